@@ -132,6 +132,15 @@ func (d *Datetime) Source() string {
 	return string(d.Data)
 }
 
+// Delimiter returns the character separating the date and the time, 'T' or ' '.
+// It returns 0 for local dates and local times.
+func (d *Datetime) Delimiter() byte {
+	if len(d.Value) > 10 && strings.IndexByte(d.Value, '-') == 4 {
+		return d.Value[10]
+	}
+	return 0
+}
+
 var timeFormats = [...]string{
 	"2006-01-02T15:04:05.999999999Z07:00",
 	"2006-01-02 15:04:05.999999999Z07:00",
@@ -139,17 +148,35 @@ var timeFormats = [...]string{
 	"2006-01-02 15:04:05.999999999",
 }
 
+// Time returns the value of d. Leap seconds cannot be represented by time.Time, so a
+// second value of 60 is returned as the first second of the following minute.
 func (d *Datetime) Time() (time.Time, error) {
+	v, leap := d.Value, false
+	sec := 6
+	if strings.IndexByte(v, '-') == 4 {
+		sec = 17
+	}
+	if len(v) >= sec+2 && v[sec:sec+2] == "60" {
+		v, leap = v[:sec]+"59"+v[sec+2:], true
+	}
+	t, err := parseTime(v)
+	if leap {
+		t = t.Add(time.Second)
+	}
+	return t, err
+}
+
+func parseTime(v string) (time.Time, error) {
 	switch {
-	case !strings.Contains(d.Value, ":"):
-		return time.Parse("2006-01-02", d.Value)
-	case !strings.Contains(d.Value, "-"):
-		return time.Parse("15:04:05.999999999", d.Value)
+	case !strings.Contains(v, ":"):
+		return time.Parse("2006-01-02", v)
+	case !strings.Contains(v, "-"):
+		return time.Parse("15:04:05.999999999", v)
 	default:
 		var t time.Time
 		var err error
 		for _, format := range timeFormats {
-			if t, err = time.Parse(format, d.Value); err == nil {
+			if t, err = time.Parse(format, v); err == nil {
 				return t, nil
 			}
 		}
@@ -203,6 +230,17 @@ type Table struct {
 	Fields   map[string]interface{}
 	Type     TableType
 	Data     []rune
+
+	// Comments contains all comments of the document in source order. It is only
+	// set on the top-level table, and only if comments were requested from the parser.
+	Comments []*Comment
+}
+
+// Comment is a comment in a TOML document.
+type Comment struct {
+	Position Position
+	Line     int
+	Text     string // text after '#'
 }
 
 func (t *Table) Pos() int {
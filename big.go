@@ -0,0 +1,137 @@
+package toml
+
+import (
+	"math/big"
+	"reflect"
+	"strings"
+
+	"github.com/naoina/toml/ast"
+)
+
+var (
+	bigIntType   = reflect.TypeOf(big.Int{})
+	bigFloatType = reflect.TypeOf(big.Float{})
+	bigRatType   = reflect.TypeOf(big.Rat{})
+)
+
+// setBig decodes integers and floats into big.Int, big.Float and big.Rat without loss
+// of precision. Strings are left to their UnmarshalText methods.
+func setBig(rv reflect.Value, val ast.Value) (bool, error) {
+	var s, kind string
+	switch v := val.(type) {
+	case *ast.Integer:
+		s, kind = v.Value, "integer"
+	case *ast.Float:
+		s, kind = v.Value, "float"
+		if strings.HasSuffix(s, "nan") {
+			kind = "nan"
+		}
+	default:
+		return false, nil
+	}
+	if !rv.CanAddr() {
+		return false, nil
+	}
+	switch rv.Type() {
+	case bigIntType:
+		if kind != "integer" {
+			return true, &UnmarshalTypeError{kind, "", rv.Type()}
+		}
+		rv.Addr().Interface().(*big.Int).SetString(s, 0)
+	case bigFloatType:
+		if kind == "nan" {
+			return true, &UnmarshalTypeError{kind, "", rv.Type()}
+		}
+		z := rv.Addr().Interface().(*big.Float)
+		if z.Prec() == 0 {
+			z.SetPrec(bigFloatPrec(s))
+		}
+		if _, _, err := z.Parse(s, 0); err != nil {
+			return true, err
+		}
+	case bigRatType:
+		z := rv.Addr().Interface().(*big.Rat)
+		if kind == "integer" {
+			i, _ := new(big.Int).SetString(s, 0)
+			z.SetInt(i)
+		} else if _, ok := z.SetString(s); !ok {
+			return true, &UnmarshalTypeError{strings.TrimLeft(s, "+-"), "", rv.Type()}
+		}
+	default:
+		return false, nil
+	}
+	return true, nil
+}
+
+// bigFloatPrec returns the precision for decoding number s into a big.Float with zero
+// precision. It holds all digits of s, but at least as many as a float64.
+func bigFloatPrec(s string) uint {
+	if prec := uint(len(s)) * 4; prec > 64 {
+		return prec
+	}
+	return 64
+}
+
+// appendBig writes values of the math/big types as TOML numbers. Rationals which have
+// no exact decimal representation are written as strings like "1/3".
+func appendBig(buf []byte, rv reflect.Value) ([]byte, bool) {
+	if rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return buf, false
+		}
+		rv = rv.Elem()
+	}
+	switch rv.Type() {
+	case bigIntType, bigFloatType, bigRatType:
+	default:
+		return buf, false
+	}
+	if !rv.CanAddr() {
+		ptr := reflect.New(rv.Type())
+		ptr.Elem().Set(rv)
+		rv = ptr.Elem()
+	}
+	switch x := rv.Addr().Interface().(type) {
+	case *big.Int:
+		return x.Append(buf, 10), true
+	case *big.Float:
+		switch {
+		case x.IsInf() && x.Sign() < 0:
+			return append(buf, "-inf"...), true
+		case x.IsInf():
+			return append(buf, "inf"...), true
+		}
+		return x.Append(buf, 'e', -1), true
+	case *big.Rat:
+		if n, ok := decimalDigits(x.Denom()); ok {
+			s := x.FloatString(n)
+			if n == 0 {
+				s += ".0"
+			}
+			return append(buf, s...), true
+		}
+		return appendQuote(buf, x.String(), false), true
+	}
+	return buf, false
+}
+
+// decimalDigits returns the number of fractional decimal digits of fractions with
+// denominator d, if they have a finite decimal representation.
+func decimalDigits(d *big.Int) (int, bool) {
+	d = new(big.Int).Set(d)
+	var twos, fives int
+	two, five, rem := big.NewInt(2), big.NewInt(5), new(big.Int)
+	for d.Cmp(two) >= 0 {
+		if q, r := new(big.Int).QuoRem(d, two, rem); r.Sign() == 0 {
+			d, twos = q, twos+1
+		} else if q, r := new(big.Int).QuoRem(d, five, rem); r.Sign() == 0 {
+			d, fives = q, fives+1
+		} else {
+			return 0, false
+		}
+	}
+	if twos > fives {
+		return twos, true
+	}
+	return fives, true
+}
@@ -0,0 +1,9 @@
+//go:build !tinygo && !toml_slim
+// +build !tinygo,!toml_slim
+
+package toml
+
+// maxPooledTokens limits the size of the token tree retained by pooled parsers. It is
+// smaller when the package is built with the toml_slim build tag, which reduces the
+// memory used by the package for TinyGo and WebAssembly targets.
+const maxPooledTokens = 1 << 16
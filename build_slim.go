@@ -0,0 +1,7 @@
+//go:build tinygo || toml_slim
+// +build tinygo toml_slim
+
+package toml
+
+// In slim builds, pooled parsers retain less memory.
+const maxPooledTokens = 1 << 10
@@ -0,0 +1,216 @@
+package toml
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/naoina/toml/ast"
+)
+
+// Canonicalize returns the canonical form of a TOML document. Documents with the same
+// content have byte-identical canonical forms, making it suitable for signing, cache keys
+// and golden tests.
+//
+// In canonical form, comments are removed, keys are sorted, numbers, strings and
+// datetimes use the notation described for Hash, inline tables are written as standard
+// tables, and arrays which contain only tables are written as array tables. Key/value
+// pairs of a table precede its sub-tables.
+func Canonicalize(data []byte) ([]byte, error) {
+	t, err := Parse(data)
+	if err != nil {
+		return nil, err
+	}
+	return CanonicalizeTable(t)
+}
+
+// CanonicalizeTable returns the canonical form of a parsed document.
+// See Canonicalize for details.
+func CanonicalizeTable(t *ast.Table) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := canonicalTable(&buf, t, nil); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// canonicalTable writes the key/value pairs and sub-tables of t below the table
+// at path.
+func canonicalTable(buf *bytes.Buffer, t *ast.Table, path []string) error {
+	var tables []string
+	for _, key := range sortedKeys(t) {
+		field := t.Fields[key]
+		if _, ok := canonicalSubTable(field); ok {
+			tables = append(tables, key)
+			continue
+		}
+		kv := field.(*ast.KeyValue)
+		buf.WriteString(canonicalKey(key))
+		buf.WriteString(" = ")
+		if err := canonicalInline(buf, kv.Value); err != nil {
+			return lineError(kv.Line, err)
+		}
+		buf.WriteByte('\n')
+	}
+	for _, key := range tables {
+		sub, _ := canonicalSubTable(t.Fields[key])
+		childPath := append(path[:len(path):len(path)], key)
+		header := canonicalPath(childPath)
+		if sub.array != nil {
+			for _, elem := range sub.array {
+				canonicalHeader(buf, "[["+header+"]]")
+				if err := canonicalTable(buf, elem, childPath); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		// The header of tables containing only sub-tables is implied by their children.
+		if len(sub.table.Fields) == 0 || hasValues(sub.table) {
+			canonicalHeader(buf, "["+header+"]")
+		}
+		if err := canonicalTable(buf, sub.table, childPath); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type canonicalSub struct {
+	table *ast.Table
+	array []*ast.Table
+}
+
+// canonicalSubTable returns the table or array of tables contained in field.
+func canonicalSubTable(field interface{}) (canonicalSub, bool) {
+	switch f := field.(type) {
+	case *ast.Table:
+		return canonicalSub{table: f}, true
+	case []*ast.Table:
+		return canonicalSub{array: f}, true
+	case *ast.KeyValue:
+		switch v := f.Value.(type) {
+		case *ast.Table:
+			return canonicalSub{table: v}, true
+		case *ast.Array:
+			if len(v.Value) == 0 {
+				return canonicalSub{}, false
+			}
+			tables := make([]*ast.Table, len(v.Value))
+			for i, elem := range v.Value {
+				t, ok := elem.(*ast.Table)
+				if !ok {
+					return canonicalSub{}, false
+				}
+				tables[i] = t
+			}
+			return canonicalSub{array: tables}, true
+		}
+	}
+	return canonicalSub{}, false
+}
+
+// hasValues reports whether t contains any fields which aren't tables.
+func hasValues(t *ast.Table) bool {
+	for _, field := range t.Fields {
+		if _, ok := canonicalSubTable(field); !ok {
+			return true
+		}
+	}
+	return false
+}
+
+func canonicalHeader(buf *bytes.Buffer, header string) {
+	if buf.Len() > 0 {
+		buf.WriteByte('\n')
+	}
+	buf.WriteString(header)
+	buf.WriteByte('\n')
+}
+
+// canonicalInline writes v as an inline value.
+func canonicalInline(buf *bytes.Buffer, v ast.Value) error {
+	switch v := v.(type) {
+	case *ast.Array:
+		buf.WriteByte('[')
+		for i, elem := range v.Value {
+			if i > 0 {
+				buf.WriteString(", ")
+			}
+			if err := canonicalInline(buf, elem); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+	case *ast.Table:
+		if len(v.Fields) == 0 {
+			buf.WriteString("{}")
+			return nil
+		}
+		buf.WriteString("{ ")
+		for i, key := range sortedKeys(v) {
+			if i > 0 {
+				buf.WriteString(", ")
+			}
+			buf.WriteString(canonicalKey(key))
+			buf.WriteString(" = ")
+			if err := canonicalInlineField(buf, v.Fields[key]); err != nil {
+				return err
+			}
+		}
+		buf.WriteString(" }")
+	default:
+		_, text, err := canonicalValue(v)
+		if err != nil {
+			return err
+		}
+		buf.WriteString(text)
+	}
+	return nil
+}
+
+// canonicalInlineField writes a table field as an inline value.
+func canonicalInlineField(buf *bytes.Buffer, field interface{}) error {
+	switch f := field.(type) {
+	case *ast.KeyValue:
+		return canonicalInline(buf, f.Value)
+	case *ast.Table:
+		return canonicalInline(buf, f)
+	case []*ast.Table:
+		buf.WriteByte('[')
+		for i, t := range f {
+			if i > 0 {
+				buf.WriteString(", ")
+			}
+			if err := canonicalInline(buf, t); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+		return nil
+	default:
+		panic(fmt.Sprintf("BUG: unhandled node type %T", field))
+	}
+}
+
+func canonicalPath(path []string) string {
+	keys := make([]string, len(path))
+	for i, key := range path {
+		keys[i] = canonicalKey(key)
+	}
+	return strings.Join(keys, ".")
+}
+
+// canonicalKey returns key as a bare key if possible, and as a basic string otherwise.
+func canonicalKey(key string) string {
+	if key == "" {
+		return `""`
+	}
+	for _, r := range key {
+		if r >= '0' && r <= '9' || r >= 'A' && r <= 'Z' || r >= 'a' && r <= 'z' || r == '-' || r == '_' {
+			continue
+		}
+		return quoteString(key)
+	}
+	return key
+}
@@ -0,0 +1,86 @@
+package toml
+
+import (
+	"testing"
+)
+
+func TestCanonicalize(t *testing.T) {
+	input := `
+# comment
+title = 'x'
+num = 0x10
+f = 1.5
+when = 1979-05-27 07:32:00-08:00
+mixed = [1, { a = 2 }]
+points = [{ y = 2, x = 1 }, { x = 3 }]
+
+[server]
+port = 8080
+host = "localhost"
+limits = { rate = 1_000 }
+
+[a.b.c]
+"quoted key" = "tab\tand \"quotes\""
+
+[[products]]
+name = "hammer"
+
+[[products]]
+name = "nail"
+[products.size]
+mm = 2
+`
+	want := `f = 1.5e+00
+mixed = [1, { a = 2 }]
+num = 16
+title = "x"
+when = 1979-05-27T15:32:00Z
+
+[a.b.c]
+"quoted key" = "tab\tand \"quotes\""
+
+[[points]]
+x = 1
+y = 2
+
+[[points]]
+x = 3
+
+[[products]]
+name = "hammer"
+
+[[products]]
+name = "nail"
+
+[products.size]
+mm = 2
+
+[server]
+host = "localhost"
+port = 8080
+
+[server.limits]
+rate = 1000
+`
+	out, err := Canonicalize([]byte(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := checkOutput(out, []byte(want)); d != "" {
+		t.Fatalf("wrong output:\n%s", d)
+	}
+
+	// The canonical form is stable.
+	again, err := Canonicalize(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := checkOutput(again, out); d != "" {
+		t.Fatalf("canonical form changed when canonicalized again:\n%s", d)
+	}
+	h1, _ := Hash([]byte(input))
+	h2, _ := Hash(out)
+	if h1 != h2 {
+		t.Error("canonical form has a different hash")
+	}
+}
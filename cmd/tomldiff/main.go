@@ -0,0 +1,76 @@
+// Command tomldiff prints the semantic differences between two TOML files.
+//
+// Usage:
+//
+//	tomldiff [-exit-code] old.toml new.toml
+//
+// Each line of output describes one changed key. Lines of changed values start with
+// "~", lines of added keys with "+" and lines of removed keys with "-", e.g.
+//
+//	~ log.level = "info" -> "debug"
+//	+ server.host = "localhost"
+//	- server.port = 80
+//
+// Formatting differences, comments and the order of keys are ignored.
+// With -exit-code, tomldiff exits with status 1 if there are differences.
+// The exit status is 2 if an error occurred.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/naoina/toml"
+	"github.com/naoina/toml/ast"
+)
+
+func main() {
+	exitCode := flag.Bool("exit-code", false, "exit with status 1 if there are differences")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: tomldiff [-exit-code] old.toml new.toml")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 2 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	old, err := parseFile(flag.Arg(0))
+	if err != nil {
+		fatal(err)
+	}
+	new, err := parseFile(flag.Arg(1))
+	if err != nil {
+		fatal(err)
+	}
+	changes, err := toml.Diff(old, new)
+	if err != nil {
+		fatal(err)
+	}
+	for _, c := range changes {
+		fmt.Println(c)
+	}
+	if *exitCode && len(changes) > 0 {
+		os.Exit(1)
+	}
+}
+
+func parseFile(name string) (*ast.Table, error) {
+	data, err := ioutil.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	t, err := toml.Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+	return t, nil
+}
+
+func fatal(err error) {
+	fmt.Fprintln(os.Stderr, "tomldiff:", err)
+	os.Exit(2)
+}
@@ -0,0 +1,79 @@
+// Command tomllint checks TOML files for problems.
+//
+// Usage:
+//
+//	tomllint [-fail-on severity] [file.toml...]
+//
+// Without file arguments, or for the file name "-", standard input is checked. Each
+// problem is printed as
+//
+//	file:line: severity: message [code]
+//
+// tomllint exits with status 1 if any problem has at least the severity given by
+// -fail-on (info, warning or error; the default is warning), and with status 2 if a
+// file cannot be read. Problems can be suppressed with comments, see toml.Lint.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/naoina/toml"
+)
+
+func main() {
+	failOn := flag.String("fail-on", "warning", "exit with status 1 for problems of at least this `severity`")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: tomllint [-fail-on severity] [file.toml...]")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	threshold, ok := parseSeverity(*failOn)
+	if !ok {
+		fmt.Fprintf(os.Stderr, "tomllint: invalid severity %q\n", *failOn)
+		os.Exit(2)
+	}
+	files := flag.Args()
+	if len(files) == 0 {
+		files = []string{"-"}
+	}
+
+	failed := false
+	for _, name := range files {
+		data, err := readFile(name)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "tomllint:", err)
+			os.Exit(2)
+		}
+		if name == "-" {
+			name = "<stdin>"
+		}
+		for _, d := range toml.Lint(data) {
+			fmt.Printf("%s:%d: %s: %s [%s]\n", name, d.Line, d.Severity, d.Message, d.Code)
+			if d.Severity >= threshold {
+				failed = true
+			}
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+func readFile(name string) ([]byte, error) {
+	if name == "-" {
+		return ioutil.ReadAll(os.Stdin)
+	}
+	return ioutil.ReadFile(name)
+}
+
+func parseSeverity(s string) (toml.Severity, bool) {
+	for _, sev := range []toml.Severity{toml.SeverityInfo, toml.SeverityWarning, toml.SeverityError} {
+		if sev.String() == s {
+			return sev, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,79 @@
+// Command tomlmerge merges TOML files.
+//
+// Usage:
+//
+//	tomlmerge [-append] [-o output.toml] base.toml override.toml...
+//
+// The files are merged in order: tables are merged recursively, all other values of
+// later files replace those of earlier files. With -append, arrays and array tables
+// are concatenated instead. The result is written in canonical form to standard
+// output or to the file given by -o.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/naoina/toml"
+	"github.com/naoina/toml/ast"
+)
+
+func main() {
+	var (
+		appendArrays = flag.Bool("append", false, "concatenate arrays and array tables instead of replacing them")
+		output       = flag.String("o", "", "write the result to `file` instead of standard output")
+	)
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: tomlmerge [-append] [-o output.toml] file.toml...")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	var merged *ast.Table
+	for _, name := range flag.Args() {
+		t, err := parseFile(name)
+		if err != nil {
+			fatal(err)
+		}
+		if merged == nil {
+			merged = t
+		} else {
+			toml.Merge(merged, t, *appendArrays)
+		}
+	}
+	out, err := toml.CanonicalizeTable(merged)
+	if err != nil {
+		fatal(err)
+	}
+	if *output == "" {
+		_, err = os.Stdout.Write(out)
+	} else {
+		err = ioutil.WriteFile(*output, out, 0644)
+	}
+	if err != nil {
+		fatal(err)
+	}
+}
+
+func parseFile(name string) (*ast.Table, error) {
+	data, err := ioutil.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	t, err := toml.Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+	return t, nil
+}
+
+func fatal(err error) {
+	fmt.Fprintln(os.Stderr, "tomlmerge:", err)
+	os.Exit(1)
+}
@@ -0,0 +1,117 @@
+// Command tomlq selects values from TOML documents.
+//
+// Usage:
+//
+//	tomlq [-r | -toml] query [file.toml...]
+//
+// The query syntax is described in the documentation of toml.Query, e.g.
+//
+//	tomlq '.servers[].ip' config.toml
+//
+// Each selected value is printed on its own line as JSON. With -r, strings are printed
+// without quotes. With -toml, values are printed in TOML notation and tables as TOML
+// documents. Without file arguments, the document is read from standard input.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/naoina/toml"
+	"github.com/naoina/toml/ast"
+)
+
+func main() {
+	var (
+		raw      = flag.Bool("r", false, "print strings without quotes")
+		tomlMode = flag.Bool("toml", false, "print results in TOML notation")
+	)
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: tomlq [-r | -toml] query [file.toml...]")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 || *raw && *tomlMode {
+		flag.Usage()
+		os.Exit(2)
+	}
+	query, files := flag.Arg(0), flag.Args()[1:]
+	if len(files) == 0 {
+		files = []string{"-"}
+	}
+
+	for _, name := range files {
+		doc, err := parseFile(name)
+		if err != nil {
+			fatal(err)
+		}
+		results, err := toml.Query(doc, query)
+		if err != nil {
+			fatal(err)
+		}
+		for _, v := range results {
+			out, err := format(v, *raw, *tomlMode)
+			if err != nil {
+				fatal(err)
+			}
+			os.Stdout.Write(out)
+		}
+	}
+}
+
+func parseFile(name string) (*ast.Table, error) {
+	var data []byte
+	var err error
+	if name == "-" {
+		data, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		data, err = ioutil.ReadFile(name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	t, err := toml.Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+	return t, nil
+}
+
+// format returns the output for a single result, including the trailing newline.
+func format(v ast.Value, raw, tomlMode bool) ([]byte, error) {
+	if t, ok := v.(*ast.Table); ok && tomlMode {
+		return toml.CanonicalizeTable(t)
+	}
+	if s, ok := v.(*ast.String); ok && raw {
+		return []byte(s.Value + "\n"), nil
+	}
+	gv, err := goValue(v)
+	if err != nil {
+		return nil, err
+	}
+	var out []byte
+	if tomlMode {
+		out, err = toml.EncodeValue(gv)
+	} else {
+		out, err = json.Marshal(gv)
+	}
+	return append(out, '\n'), err
+}
+
+// goValue converts v into plain Go values.
+func goValue(v ast.Value) (interface{}, error) {
+	var m map[string]interface{}
+	wrapper := &ast.Table{Fields: map[string]interface{}{"v": &ast.KeyValue{Key: "v", Value: v}}}
+	if err := toml.UnmarshalTable(wrapper, &m); err != nil {
+		return nil, err
+	}
+	return m["v"], nil
+}
+
+func fatal(err error) {
+	fmt.Fprintln(os.Stderr, "tomlq:", err)
+	os.Exit(1)
+}
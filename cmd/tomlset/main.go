@@ -0,0 +1,90 @@
+// Command tomlset modifies a key of a TOML file in place.
+//
+// Usage:
+//
+//	tomlset [-string] file.toml key value
+//	tomlset -delete file.toml key
+//
+// The key is a dotted TOML key like server.port or labels."app.name". The value is
+// given in TOML notation, e.g. 8080, true or '"text"'. Values which aren't valid TOML
+// are set as strings; with -string, the value is always set as a string.
+//
+// Only the modified key is rewritten; formatting and comments of the rest of the file
+// are preserved. New keys are added to the end of their table, and missing tables are
+// appended to the end of the file.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/naoina/toml"
+)
+
+func main() {
+	var (
+		del      = flag.Bool("delete", false, "remove the key instead of setting it")
+		asString = flag.Bool("string", false, "set the value as a string")
+	)
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: tomlset [-string] file.toml key value")
+		fmt.Fprintln(os.Stderr, "       tomlset -delete file.toml key")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if *del && flag.NArg() != 2 || !*del && flag.NArg() != 3 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	name, key := flag.Arg(0), flag.Arg(1)
+
+	info, err := os.Stat(name)
+	if err != nil {
+		fatal(err)
+	}
+	data, err := ioutil.ReadFile(name)
+	if err != nil {
+		fatal(err)
+	}
+	doc, err := toml.ParseDocument(data)
+	if err != nil {
+		fatal(fmt.Errorf("%s: %v", name, err))
+	}
+	path, err := toml.SplitKey(key)
+	if err != nil {
+		fatal(err)
+	}
+	if *del {
+		err = doc.Delete(path)
+	} else {
+		err = doc.Set(path, value(flag.Arg(2), *asString))
+	}
+	if err != nil {
+		fatal(err)
+	}
+	if err := ioutil.WriteFile(name, doc.Bytes(), info.Mode().Perm()); err != nil {
+		fatal(err)
+	}
+}
+
+// value returns the TOML notation of the value argument.
+func value(arg string, asString bool) string {
+	if !asString {
+		if t, err := toml.Parse([]byte("v = " + arg)); err == nil && len(t.Fields) == 1 {
+			return arg
+		}
+	}
+	out, err := toml.Marshal(struct{ V string }{arg})
+	if err != nil {
+		fatal(err)
+	}
+	return strings.TrimSpace(strings.TrimPrefix(string(out), "v ="))
+}
+
+func fatal(err error) {
+	fmt.Fprintln(os.Stderr, "tomlset:", err)
+	os.Exit(1)
+}
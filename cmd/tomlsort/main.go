@@ -0,0 +1,82 @@
+// Command tomlsort sorts the keys and tables of TOML files.
+//
+// Usage:
+//
+//	tomlsort [-w] [-schema reference.toml] file.toml...
+//
+// By default, keys are sorted alphabetically. With -schema, keys are sorted in the
+// order they appear in the reference document instead; keys which don't appear in it
+// follow alphabetically. Spacing is normalized and comments move with the key or table
+// they precede. The result is written to standard output, or back to the files with -w.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/naoina/toml"
+)
+
+func main() {
+	var (
+		write  = flag.Bool("w", false, "write the result back to the files")
+		schema = flag.String("schema", "", "sort keys in the order of the reference `file`")
+	)
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: tomlsort [-w] [-schema reference.toml] file.toml...")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	order := toml.KeyOrder(toml.AlphabeticalOrder)
+	if *schema != "" {
+		data, err := ioutil.ReadFile(*schema)
+		if err != nil {
+			fatal(err)
+		}
+		ref, err := toml.Parse(data)
+		if err != nil {
+			fatal(fmt.Errorf("%s: %v", *schema, err))
+		}
+		order = toml.DocumentOrder(ref)
+	}
+	for _, name := range flag.Args() {
+		if err := sortFile(name, order, *write); err != nil {
+			fatal(err)
+		}
+	}
+}
+
+func sortFile(name string, order toml.KeyOrder, write bool) error {
+	info, err := os.Stat(name)
+	if err != nil {
+		return err
+	}
+	data, err := ioutil.ReadFile(name)
+	if err != nil {
+		return err
+	}
+	doc, err := toml.ParseDocument(data)
+	if err != nil {
+		return fmt.Errorf("%s: %v", name, err)
+	}
+	if err := doc.Sort(order); err != nil {
+		return fmt.Errorf("%s: %v", name, err)
+	}
+	if write {
+		return ioutil.WriteFile(name, doc.Bytes(), info.Mode().Perm())
+	}
+	_, err = os.Stdout.Write(doc.Bytes())
+	return err
+}
+
+func fatal(err error) {
+	fmt.Fprintln(os.Stderr, "tomlsort:", err)
+	os.Exit(1)
+}
@@ -1,6 +1,7 @@
 package toml
 
 import (
+	"context"
 	"io"
 	"reflect"
 	"strings"
@@ -13,14 +14,14 @@ import (
 type Config struct {
 	// NormFieldName is used to match TOML keys to struct fields. The function runs for
 	// both input keys and struct field names and should return a string that makes the
-	// two match. You must set this field to use the decoder.
+	// two match. You must set this field to use the decoder unless ExactKeys is set.
 	//
 	// Example: The function in the default config removes _ and lowercases all keys. This
 	// allows a key called 'api_key' to match the struct field 'APIKey' because both are
 	// normalized to 'apikey'.
 	//
 	// Note that NormFieldName is not used for fields which define a TOML
-	// key through the struct tag.
+	// key through the struct tag, or if ExactKeys is set.
 	NormFieldName func(typ reflect.Type, keyOrField string) string
 
 	// FieldToKey determines the TOML key of a struct field when encoding.
@@ -30,10 +31,196 @@ type Config struct {
 	// key through the struct tag.
 	FieldToKey func(typ reflect.Type, field string) string
 
+	// ExactKeys makes the decoder match TOML keys to struct fields exactly and case
+	// sensitively. A key only matches a field if it is the key written by the encoder,
+	// i.e. the result of FieldToKey, or the field name if FieldToKey is nil.
+	// NormFieldName is not used and doesn't need to be set. By default, keys like
+	// "ServerName", "server_name" and "servername" all match the field ServerName.
+	ExactKeys bool
+
+	// NormMapKey, if non-nil, is applied by the decoder to the keys of tables decoded
+	// into maps. The type is the map type. Setting it to NormFieldName makes maps match
+	// keys like structs do, e.g. "LogLevel" and "log_level" are both stored as
+	// "loglevel". Keys normalizing to the same map key are reported as conflicts.
+	NormMapKey func(typ reflect.Type, key string) string
+
+	// TypeHints maps dotted key paths to the types the decoder uses for values which
+	// are stored in an interface{}, e.g. a field of type interface{} or the elements of
+	// a map[string]interface{}. Without a hint, tables are decoded as
+	// map[string]interface{}. A path element "*" matches any single key, and the
+	// elements of array tables share the path of the array:
+	//
+	//	TypeHints: map[string]reflect.Type{
+	//		"plugins.*.options": reflect.TypeOf(PluginOptions{}),
+	//	}
+	//
+	// If multiple paths match, the one with the fewest wildcards is used, and the
+	// lexically smallest among those. Keys containing dots cannot be matched.
+	TypeHints map[string]reflect.Type
+
+	// DecodeHook, if non-nil, is called by the decoder before it stores a value in a Go
+	// value of the given type, including the elements of arrays and tables. Pointers
+	// are allocated and followed first. This allows converting strings to IP addresses,
+	// enums or sizes without implementing encoding.TextUnmarshaler on every type:
+	//
+	//	DecodeHook: func(from ast.Value, to reflect.Type) (interface{}, bool, error) {
+	//		s, ok := from.(*ast.String)
+	//		if !ok || to != reflect.TypeOf(net.IP{}) {
+	//			return nil, false, nil
+	//		}
+	//		ip := net.ParseIP(s.Value)
+	//		if ip == nil {
+	//			return nil, true, fmt.Errorf("invalid IP address %q", s.Value)
+	//		}
+	//		return ip, true, nil
+	//	}
+	//
+	// Hooks run before the unmarshaler interfaces are considered. Use the DecodeHook
+	// option or ComposeDecodeHooks to chain several hooks.
+	DecodeHook DecodeHookFunc
+
+	// Parser, if non-nil, replaces the built-in parser in Unmarshal, Decoder, Load and
+	// UnmarshalProfile.
+	Parser Parser
+
+	// Version selects the TOML version of the input and output, e.g. "0.5" or "1.0".
+	// The decoder rejects documents using features of later versions, and the encoder
+	// returns an error instead of writing such features, e.g. arrays with mixed element
+	// types for versions before 1.0. By default, the features of TOML 1.0 are accepted
+	// and written; those of TOML 1.1 require selecting version 1.1. See the Version
+	// parse option for the supported versions. Decoding with a custom Parser ignores
+	// the version.
+	Version string
+
+	// RejectBOM makes the decoder reject documents starting with a UTF-8 byte order
+	// mark. By default, the byte order mark is skipped. Decoding with a custom Parser
+	// ignores this setting.
+	RejectBOM bool
+
+	// AllowBareCR makes the decoder accept carriage returns which aren't followed by a
+	// line feed as line breaks. This is invalid TOML, so such carriage returns are
+	// rejected by default. Decoding with a custom Parser ignores this setting.
+	AllowBareCR bool
+
+	// AllowLeadingZeros makes the decoder accept decimal integers and floats with
+	// leading zeros, e.g. 08080. This is invalid TOML, but some parsers for TOML 0.4
+	// accepted it. Warning is called for every such number. Decoding with a custom
+	// Parser ignores this setting.
+	AllowLeadingZeros bool
+
+	// Protobuf enables support for structs generated by protoc-gen-go. Fields with a
+	// protobuf struct tag use the field name declared in the tag as their TOML key. The
+	// decoder also accepts the JSON name of such fields. The XXX_ fields of structs
+	// generated by older versions of protoc-gen-go are skipped. Oneof fields are not
+	// supported.
+	Protobuf bool
+
+	// MaxErrors is the maximum number of errors gathered by the decoder in modes which
+	// report multiple errors at once. Decoding stops when the limit is reached, and the
+	// returned error notes that further errors were omitted. The default is
+	// DefaultMaxErrors. A negative value removes the limit.
+	MaxErrors int
+
+	// TagName is the struct tag key holding TOML key names and options.
+	// The default is "toml".
+	TagName string
+
 	// MissingField, if non-nil, is called when the decoder encounters a key for which no
 	// matching struct field exists. The default behavior is to return an error.
 	MissingField func(typ reflect.Type, key string) error
 
+	// OnUnknownField, if non-nil, is called when the decoder encounters a key for which
+	// no matching struct field exists, before MissingField. The line is the location of
+	// the key in the input. This can be used to log unknown keys which are ignored
+	// through IgnoreUnknownFields.
+	OnUnknownField func(typ reflect.Type, key string, line int)
+
+	// CollectUnknownFields instructs the decoder to continue after keys for which no
+	// matching struct field exists. The errors for all such keys in the document are
+	// returned together, in the order of their lines, up to MaxErrors. Decoding still
+	// stops at the first error of any other kind.
+	CollectUnknownFields bool
+
+	// ContinueOnError instructs the decoder to continue with the next key when the value
+	// of a key cannot be decoded, e.g. because of a type mismatch or overflow. All errors
+	// are returned together as an *ErrorList, in the order of their lines, up to
+	// MaxErrors. Fields whose value could not be decoded may be partially assigned.
+	// ContinueOnError implies CollectUnknownFields.
+	ContinueOnError bool
+
+	// WeaklyTypedInput makes the decoder convert values which don't match the type of
+	// their Go value, e.g. for hand-written configuration like
+	//
+	//	port = "8080"
+	//	enabled = "true"
+	//
+	// Strings are parsed into integer, float and boolean values, integers are converted
+	// to floats, and values other than arrays are decoded into slices as a single
+	// element.
+	//
+	// Without WeaklyTypedInput, numbers must match the type of their field exactly, e.g.
+	// a float64 field requires a float like 1.0 and rejects the integer 1.
+	WeaklyTypedInput bool
+
+	// AppendSlices instructs the decoder to append array elements to slices which
+	// already contain elements instead of replacing them. When used with Load, arrays
+	// and array tables of all sources are concatenated.
+	AppendSlices bool
+
+	// ExtendsKey, if non-empty, is the name of a top-level key which Load treats as a
+	// reference to a base document, e.g. with ExtendsKey set to "extends":
+	//
+	//	extends = "base.toml"
+	//
+	// The base document is loaded first and the current document is merged into it.
+	// Names are resolved relative to the extending document, which requires a
+	// RelativeSource. Base documents can extend other documents, but cycles are
+	// reported as errors. The key itself is removed before decoding.
+	ExtendsKey string
+
+	// UnsetField, if non-nil, is called by the decoder for every field of a struct which
+	// wasn't assigned by the corresponding table. Fields which already hold a non-empty
+	// value, e.g. a default set before decoding, are not reported. Returning an error
+	// aborts decoding.
+	//
+	// This can be used to find settings that were placed in the wrong table.
+	UnsetField func(typ reflect.Type, field string) error
+
+	// Warning, if non-nil, is called by the decoder for problems in the input which don't
+	// prevent decoding. Warnings are *LineError values. They are reported for keys of
+	// struct fields tagged as deprecated, e.g.
+	//
+	//	Port int `deprecated:"use server.port instead"`
+	//
+	// Warnings are discarded when Warning is nil.
+	Warning func(err error)
+
+	// RenamedKeys maps dotted key paths which were renamed to their new paths, e.g.
+	//
+	//	RenamedKeys: map[string]string{
+	//		"server.timeout_seconds": "server.timeout",
+	//		"listen":                 "server.listen",
+	//	}
+	//
+	// The decoder moves keys found at an old path to the new path before decoding, so
+	// documents using the old keys keep working. Setting both keys is an error. Tables
+	// left empty by moving their keys are removed. Keys in array tables and inline tables
+	// are not renamed. Every renamed key is reported to OnRenamedKey and as a warning to
+	// Warning.
+	RenamedKeys map[string]string
+
+	// OnRenamedKey, if non-nil, is called by the decoder for every key of RenamedKeys
+	// found at its old path. The line is the location of the key in the input.
+	OnRenamedKey func(oldPath, newPath string, line int)
+
+	// SkipKeys contains dotted key paths which the decoder ignores entirely. Matching
+	// keys and everything below them are never assigned to Go values, so they don't need
+	// a corresponding struct field. A path element "*" matches any single key, e.g.
+	// "plugins.*.internal". The elements of array tables share the path of the array.
+	//
+	// Keys containing dots cannot be matched.
+	SkipKeys []string
+
 	// WriteEmptyTables instructs the encoder to write all tables, even if they are empty.
 	// By default, empty tables are not written to the output. Note that empty array
 	// tables and inline tables are always written.
@@ -41,15 +228,365 @@ type Config struct {
 	// This setting mostly exists for compatibility with the toml-test tool.
 	// Don't set this unless you have a good reason for it.
 	WriteEmptyTables bool
+
+	// CommentUnset instructs the encoder to write nil pointer struct fields as
+	// commented-out key/value pairs or tables containing the zero value of the
+	// pointed-to type, e.g.
+	//
+	//	# port = 0
+	//
+	// This is useful for generating default configuration files. By default, nil
+	// pointers cannot be encoded unless the field is tagged "omitempty".
+	CommentUnset bool
+
+	// SkipNilMapValues instructs the encoder to omit map entries whose value is a nil
+	// pointer or interface. By default, such values cannot be encoded. This is useful
+	// for generic maps decoded from JSON, where null values are common.
+	SkipNilMapValues bool
+
+	// LiteralStrings instructs the encoder to write strings containing backslashes as
+	// literal strings, e.g. 'C:\Users' instead of "C:\\Users", unless they contain
+	// single quotes or control characters. The "literal" tag option writes a string
+	// field as a literal string even if it doesn't contain backslashes.
+	LiteralStrings bool
+
+	// MarshalStringers instructs the encoder to write values of types which implement
+	// fmt.Stringer, but none of the marshaler interfaces, as strings holding the result
+	// of their String method. This is useful for enum-like types from other packages.
+	// By default, such values are encoded like values of their underlying type.
+	MarshalStringers bool
+
+	// LineEnding is the line ending written by the encoder, either "\n" or "\r\n".
+	// It also applies to newlines within values, e.g. in multi-line strings returned
+	// by a Marshaler. The default is "\n".
+	LineEnding string
+
+	// MultilineInlineTables instructs the encoder to write inline tables with one
+	// key/value pair per line and a trailing comma after the last pair. It only takes
+	// effect if Version selects TOML 1.1, because earlier versions require inline
+	// tables to be written on a single line.
+	MultilineInlineTables bool
+
+	// AlignEquals instructs the encoder to pad keys with spaces so that the equals signs
+	// of all key/value pairs in a table line up.
+	AlignEquals bool
+
+	// TableOrder determines the order in which the encoder writes the sub-tables of a
+	// table. The default is TableOrderFields.
+	TableOrder TableOrder
+
+	// FilterValue, if non-nil, is called by the encoder for every key before its value
+	// is written. The path contains the keys of all enclosing tables followed by the
+	// key itself. Returning omit == true skips the key entirely. A non-nil replacement
+	// is encoded in place of the original value.
+	//
+	// This can be used to redact secrets or convert units without modifying the
+	// structs being encoded.
+	FilterValue func(path []string, v reflect.Value) (replacement interface{}, omit bool)
+
+	hintedNodes map[interface{}]reflect.Type // AST nodes matched by TypeHints
+	errs        *errorList                   // errors gathered while decoding
+	source      *docSource                   // source of the document being decoded
+	meta        *MetaData                    // records the keys decoded by UnmarshalMetaData
 }
 
+// TableOrder is the order in which sub-tables are written by the encoder.
+// The elements of an array table are always written together. Unless the order
+// separates them, array tables are placed among the normal tables like a normal
+// table with the same key.
+type TableOrder uint8
+
+const (
+	// TableOrderFields writes sub-tables in the order of struct fields.
+	// Map keys are sorted.
+	TableOrderFields TableOrder = iota
+	// TableOrderAlphabetical sorts sub-tables by their key.
+	TableOrderAlphabetical
+	// TableOrderTablesFirst writes all normal tables before array tables,
+	// keeping the field order within each group.
+	TableOrderTablesFirst
+	// TableOrderArrayTablesFirst writes all array tables before normal tables,
+	// keeping the field order within each group.
+	TableOrderArrayTablesFirst
+)
+
 // DefaultConfig contains the default options for encoding and decoding.
-// Snake case (i.e. 'foo_bar') is used for key names.
+// Snake case (i.e. 'foo_bar') is used for key names. It is used by the package-level
+// functions like Unmarshal and Marshal. Modifying it affects all users of these
+// functions; use DefaultConfig.With to derive a modified copy instead.
 var DefaultConfig = Config{
 	NormFieldName: defaultNormFieldName,
 	FieldToKey:    snakeCase,
 }
 
+// DefaultMaxErrors is the number of errors gathered by the decoder if
+// Config.MaxErrors is zero.
+const DefaultMaxErrors = 20
+
+func (cfg *Config) maxErrors() int {
+	if cfg.MaxErrors == 0 {
+		return DefaultMaxErrors
+	}
+	return cfg.MaxErrors
+}
+
+// allows11 reports whether the encoder may write features of TOML 1.1, e.g. the \e
+// and \xHH escapes in strings and non-ASCII bare keys.
+func (cfg *Config) allows11() bool {
+	return versions[cfg.Version] >= 11
+}
+
+// multilineInlineTables reports whether the encoder writes multi-line inline tables.
+func (cfg *Config) multilineInlineTables() bool {
+	return cfg.MultilineInlineTables && cfg.allows11()
+}
+
+func (cfg *Config) tagName() string {
+	if cfg.TagName == "" {
+		return fieldTagName
+	}
+	return cfg.TagName
+}
+
+// ConfigOption modifies a Config. Options are applied with Config.With.
+type ConfigOption func(*Config)
+
+// With returns a copy of cfg with the given options applied. cfg itself is not
+// modified, which makes it safe to derive configurations from a shared Config such
+// as DefaultConfig:
+//
+//	cfg := toml.DefaultConfig.With(toml.WriteEmptyTables(true))
+func (cfg *Config) With(opts ...ConfigOption) *Config {
+	c := *cfg
+	c.SkipKeys = append([]string(nil), cfg.SkipKeys...)
+	for _, opt := range opts {
+		opt(&c)
+	}
+	return &c
+}
+
+// NormFieldName sets Config.NormFieldName.
+func NormFieldName(fn func(typ reflect.Type, keyOrField string) string) ConfigOption {
+	return func(cfg *Config) { cfg.NormFieldName = fn }
+}
+
+// FieldToKey sets Config.FieldToKey.
+func FieldToKey(fn func(typ reflect.Type, field string) string) ConfigOption {
+	return func(cfg *Config) { cfg.FieldToKey = fn }
+}
+
+// ExactKeys sets Config.ExactKeys.
+func ExactKeys(enable bool) ConfigOption {
+	return func(cfg *Config) { cfg.ExactKeys = enable }
+}
+
+// NormMapKey sets Config.NormMapKey.
+func NormMapKey(fn func(typ reflect.Type, key string) string) ConfigOption {
+	return func(cfg *Config) { cfg.NormMapKey = fn }
+}
+
+// TypeHints adds entries to Config.TypeHints.
+func TypeHints(hints map[string]reflect.Type) ConfigOption {
+	return func(cfg *Config) {
+		m := make(map[string]reflect.Type, len(cfg.TypeHints)+len(hints))
+		for path, typ := range cfg.TypeHints {
+			m[path] = typ
+		}
+		for path, typ := range hints {
+			m[path] = typ
+		}
+		cfg.TypeHints = m
+	}
+}
+
+// UseParser sets Config.Parser.
+func UseParser(p Parser) ConfigOption {
+	return func(cfg *Config) { cfg.Parser = p }
+}
+
+// UseVersion sets Config.Version.
+func UseVersion(v string) ConfigOption {
+	return func(cfg *Config) { cfg.Version = v }
+}
+
+// Protobuf sets Config.Protobuf.
+func Protobuf(enable bool) ConfigOption {
+	return func(cfg *Config) { cfg.Protobuf = enable }
+}
+
+// MaxErrors sets Config.MaxErrors.
+func MaxErrors(n int) ConfigOption {
+	return func(cfg *Config) { cfg.MaxErrors = n }
+}
+
+// TagName sets Config.TagName.
+func TagName(name string) ConfigOption {
+	return func(cfg *Config) { cfg.TagName = name }
+}
+
+// CollectUnknownFields sets Config.CollectUnknownFields.
+func CollectUnknownFields(enable bool) ConfigOption {
+	return func(cfg *Config) { cfg.CollectUnknownFields = enable }
+}
+
+// ContinueOnError sets Config.ContinueOnError.
+func ContinueOnError(enable bool) ConfigOption {
+	return func(cfg *Config) { cfg.ContinueOnError = enable }
+}
+
+// IgnoreUnknownFields makes the decoder skip keys which don't correspond to a struct
+// field instead of returning an error.
+func IgnoreUnknownFields() ConfigOption {
+	return func(cfg *Config) {
+		cfg.MissingField = func(reflect.Type, string) error { return nil }
+	}
+}
+
+// Strict turns off the extensions of the TOML specification which the package
+// accepts. It makes the decoder reject keys which don't correspond to a struct field,
+// undoing IgnoreUnknownFields and MissingField options applied before it. Documents
+// starting with a byte order mark or containing bare carriage returns are rejected as
+// well, and WeaklyTypedInput is disabled if an earlier option enabled it. Integers like
+// 1 are then rejected for float fields, as they are by default. If no version is selected, Version is set to "1.0", which also checks
+// the output of the encoder.
+//
+// Some valid TOML 1.0 documents are still rejected or decoded differently, because
+// the package doesn't support these features:
+//   - dotted keys in key/value pairs, e.g. a.b = 1 and t = {a.b = 1}
+//   - literal strings as keys, e.g. 'a' = 1
+//   - local date-times, dates and times, which decode as date-times in UTC
+//
+// Strict is the configuration of toml-test-adapter.go, and the tests of package
+// tomltest run a subset of the toml-test suite with it.
+func Strict() ConfigOption {
+	return func(cfg *Config) {
+		cfg.MissingField = nil
+		cfg.RejectBOM = true
+		cfg.AllowBareCR = false
+		cfg.AllowLeadingZeros = false
+		cfg.WeaklyTypedInput = false
+		if cfg.Version == "" {
+			cfg.Version = "1.0"
+		}
+	}
+}
+
+// MissingField sets Config.MissingField.
+func MissingField(fn func(typ reflect.Type, key string) error) ConfigOption {
+	return func(cfg *Config) { cfg.MissingField = fn }
+}
+
+// OnUnknownField sets Config.OnUnknownField.
+func OnUnknownField(fn func(typ reflect.Type, key string, line int)) ConfigOption {
+	return func(cfg *Config) { cfg.OnUnknownField = fn }
+}
+
+// WeaklyTypedInput sets Config.WeaklyTypedInput.
+func WeaklyTypedInput(enable bool) ConfigOption {
+	return func(cfg *Config) { cfg.WeaklyTypedInput = enable }
+}
+
+// AppendSlices sets Config.AppendSlices.
+func AppendSlices(enable bool) ConfigOption {
+	return func(cfg *Config) { cfg.AppendSlices = enable }
+}
+
+// ExtendsKey sets Config.ExtendsKey.
+func ExtendsKey(key string) ConfigOption {
+	return func(cfg *Config) { cfg.ExtendsKey = key }
+}
+
+// UnsetField sets Config.UnsetField.
+func UnsetField(fn func(typ reflect.Type, field string) error) ConfigOption {
+	return func(cfg *Config) { cfg.UnsetField = fn }
+}
+
+// Warning sets Config.Warning.
+func Warning(fn func(err error)) ConfigOption {
+	return func(cfg *Config) { cfg.Warning = fn }
+}
+
+// RenamedKeys adds entries to Config.RenamedKeys.
+func RenamedKeys(renames map[string]string) ConfigOption {
+	return func(cfg *Config) {
+		m := make(map[string]string, len(cfg.RenamedKeys)+len(renames))
+		for old, to := range cfg.RenamedKeys {
+			m[old] = to
+		}
+		for old, to := range renames {
+			m[old] = to
+		}
+		cfg.RenamedKeys = m
+	}
+}
+
+// OnRenamedKey sets Config.OnRenamedKey.
+func OnRenamedKey(fn func(oldPath, newPath string, line int)) ConfigOption {
+	return func(cfg *Config) { cfg.OnRenamedKey = fn }
+}
+
+// SkipKeys adds key paths to Config.SkipKeys.
+func SkipKeys(paths ...string) ConfigOption {
+	return func(cfg *Config) { cfg.SkipKeys = append(cfg.SkipKeys, paths...) }
+}
+
+// WriteEmptyTables sets Config.WriteEmptyTables.
+func WriteEmptyTables(enable bool) ConfigOption {
+	return func(cfg *Config) { cfg.WriteEmptyTables = enable }
+}
+
+// CommentUnset sets Config.CommentUnset.
+func CommentUnset(enable bool) ConfigOption {
+	return func(cfg *Config) { cfg.CommentUnset = enable }
+}
+
+// SkipNilMapValues sets Config.SkipNilMapValues.
+func SkipNilMapValues(enable bool) ConfigOption {
+	return func(cfg *Config) { cfg.SkipNilMapValues = enable }
+}
+
+// LiteralStrings sets Config.LiteralStrings.
+func LiteralStrings(enable bool) ConfigOption {
+	return func(cfg *Config) { cfg.LiteralStrings = enable }
+}
+
+// MarshalStringers sets Config.MarshalStringers.
+func MarshalStringers(enable bool) ConfigOption {
+	return func(cfg *Config) { cfg.MarshalStringers = enable }
+}
+
+// LineEnding sets Config.LineEnding.
+func LineEnding(ending string) ConfigOption {
+	return func(cfg *Config) { cfg.LineEnding = ending }
+}
+
+// MultilineInlineTables sets Config.MultilineInlineTables.
+func MultilineInlineTables(enable bool) ConfigOption {
+	return func(cfg *Config) { cfg.MultilineInlineTables = enable }
+}
+
+// AlignEquals sets Config.AlignEquals.
+func AlignEquals(enable bool) ConfigOption {
+	return func(cfg *Config) { cfg.AlignEquals = enable }
+}
+
+// OrderTables sets Config.TableOrder.
+func OrderTables(order TableOrder) ConfigOption {
+	return func(cfg *Config) { cfg.TableOrder = order }
+}
+
+// FilterValue sets Config.FilterValue.
+func FilterValue(fn func(path []string, v reflect.Value) (replacement interface{}, omit bool)) ConfigOption {
+	return func(cfg *Config) { cfg.FilterValue = fn }
+}
+
+// DecodeHook appends hooks to Config.DecodeHook. The existing hook runs first.
+func DecodeHook(hooks ...DecodeHookFunc) ConfigOption {
+	return func(cfg *Config) {
+		cfg.DecodeHook = ComposeDecodeHooks(append([]DecodeHookFunc{cfg.DecodeHook}, hooks...)...)
+	}
+}
+
 func defaultNormFieldName(typ reflect.Type, s string) string {
 	return strings.Replace(strings.ToLower(s), "_", "", -1)
 }
@@ -64,18 +601,64 @@ func NewEncoder(w io.Writer) *Encoder {
 	return DefaultConfig.NewEncoder(w)
 }
 
+// NewEncoderWith returns a new Encoder that writes to w, using DefaultConfig with the
+// given options applied.
+func NewEncoderWith(w io.Writer, opts ...ConfigOption) *Encoder {
+	return DefaultConfig.With(opts...).NewEncoder(w)
+}
+
 // Marshal returns the TOML encoding of v.
 // It is shorthand for DefaultConfig.Marshal(v).
 func Marshal(v interface{}) ([]byte, error) {
 	return DefaultConfig.Marshal(v)
 }
 
+// EncodeValue returns the TOML encoding of a single value.
+// It is shorthand for DefaultConfig.EncodeValue(v).
+func EncodeValue(v interface{}) ([]byte, error) {
+	return DefaultConfig.EncodeValue(v)
+}
+
+// EncodeKeyValue returns the TOML encoding of a key/value pair.
+// It is shorthand for DefaultConfig.EncodeKeyValue(key, v).
+func EncodeKeyValue(key string, v interface{}) ([]byte, error) {
+	return DefaultConfig.EncodeKeyValue(key, v)
+}
+
+// MarshalWith is like Marshal, but uses DefaultConfig with the given options applied.
+func MarshalWith(v interface{}, opts ...ConfigOption) ([]byte, error) {
+	return DefaultConfig.With(opts...).Marshal(v)
+}
+
 // Unmarshal parses the TOML data and stores the result in the value pointed to by v.
 // It is shorthand for DefaultConfig.Unmarshal(data, v).
 func Unmarshal(data []byte, v interface{}) error {
 	return DefaultConfig.Unmarshal(data, v)
 }
 
+// MustUnmarshal is like Unmarshal but panics if an error occurs.
+// It is shorthand for DefaultConfig.MustUnmarshal(data, v).
+func MustUnmarshal(data []byte, v interface{}) {
+	DefaultConfig.MustUnmarshal(data, v)
+}
+
+// UnmarshalPositions is like Unmarshal, but also returns the positions of all keys.
+// It is shorthand for DefaultConfig.UnmarshalPositions(data, v).
+func UnmarshalPositions(data []byte, v interface{}) (Positions, error) {
+	return DefaultConfig.UnmarshalPositions(data, v)
+}
+
+// UnmarshalMetaData is like Unmarshal, but also returns the keys of data and whether
+// they were decoded. It is shorthand for DefaultConfig.UnmarshalMetaData(data, v).
+func UnmarshalMetaData(data []byte, v interface{}) (*MetaData, error) {
+	return DefaultConfig.UnmarshalMetaData(data, v)
+}
+
+// UnmarshalWith is like Unmarshal, but uses DefaultConfig with the given options applied.
+func UnmarshalWith(data []byte, v interface{}, opts ...ConfigOption) error {
+	return DefaultConfig.With(opts...).Unmarshal(data, v)
+}
+
 // UnmarshalTable applies the contents of an ast.Table to the value pointed at by v.
 // It is shorthand for DefaultConfig.UnmarshalTable(t, v).
 func UnmarshalTable(t *ast.Table, v interface{}) error {
@@ -87,3 +670,24 @@ func UnmarshalTable(t *ast.Table, v interface{}) error {
 func NewDecoder(r io.Reader) *Decoder {
 	return DefaultConfig.NewDecoder(r)
 }
+
+// NewDecoderWith returns a new Decoder that reads from r, using DefaultConfig with the
+// given options applied, e.g.
+//
+//	dec := toml.NewDecoderWith(r, toml.IgnoreUnknownFields(), toml.TagName("conf"))
+func NewDecoderWith(r io.Reader, opts ...ConfigOption) *Decoder {
+	return DefaultConfig.With(opts...).NewDecoder(r)
+}
+
+// Load reads TOML from the given sources and stores the merged result in the value
+// pointed to by v. It is shorthand for DefaultConfig.Load(ctx, v, sources...).
+func Load(ctx context.Context, v interface{}, sources ...Source) error {
+	return DefaultConfig.Load(ctx, v, sources...)
+}
+
+// UnmarshalProfile parses the TOML data, applies the named profile and stores the
+// result in the value pointed to by v.
+// It is shorthand for DefaultConfig.UnmarshalProfile(data, profile, v).
+func UnmarshalProfile(data []byte, profile string, v interface{}) error {
+	return DefaultConfig.UnmarshalProfile(data, profile, v)
+}
@@ -2,9 +2,15 @@ package toml
 
 import (
 	"bytes"
+	"errors"
+	"fmt"
 	"reflect"
+	"sort"
+	"strconv"
 	"strings"
 	"testing"
+
+	"github.com/naoina/toml/ast"
 )
 
 func TestConfigNormField(t *testing.T) {
@@ -36,6 +42,117 @@ func TestConfigNormField(t *testing.T) {
 	}
 }
 
+func TestConfigUnsetField(t *testing.T) {
+	var unset []string
+	cfg := DefaultConfig
+	cfg.UnsetField = func(rt reflect.Type, field string) error {
+		unset = append(unset, rt.Name()+"."+field)
+		return nil
+	}
+
+	type Server struct {
+		Host    string
+		Port    int
+		Timeout int
+	}
+	type Config struct {
+		Name    string
+		Server  Server
+		Backup  Server
+		Ignored int `toml:"-"`
+	}
+	x := Config{Server: Server{Timeout: 30}}
+	input := []byte(`
+[server]
+host = "localhost"
+`)
+	if err := cfg.Unmarshal(input, &x); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"Server.Port", "Config.Name", "Config.Backup"}
+	if !reflect.DeepEqual(unset, want) {
+		t.Errorf("wrong unset fields: got %q, want %q", unset, want)
+	}
+}
+
+func TestConfigWarningDeprecated(t *testing.T) {
+	var warnings []error
+	cfg := DefaultConfig
+	cfg.Warning = func(err error) { warnings = append(warnings, err) }
+
+	type testStruct struct {
+		Port int    `deprecated:"use server.port instead"`
+		Host string `deprecated:""`
+		Name string
+	}
+	var x testStruct
+	input := []byte(`
+name = "x"
+port = 80
+`)
+	if err := cfg.Unmarshal(input, &x); err != nil {
+		t.Fatal(err)
+	}
+	if x.Port != 80 {
+		t.Errorf("deprecated field not set")
+	}
+	want := []error{
+		&LineError{Line: 3, Column: 8, Path: "port", StructField: "toml.testStruct.Port", Err: &deprecatedKeyError{"port", "use server.port instead"}},
+	}
+	if !reflect.DeepEqual(warnings, want) {
+		t.Errorf("wrong warnings: got %v, want %v", warnings, want)
+	}
+}
+
+func TestConfigSkipKeys(t *testing.T) {
+	cfg := DefaultConfig
+	cfg.SkipKeys = []string{"other", "plugins.*.internal", "servers.secret"}
+
+	type plugin struct{ Name string }
+	type server struct{ Host string }
+	var x struct {
+		Plugins map[string]plugin
+		Servers []server
+	}
+	input := []byte(`
+[other]
+anything = 1
+
+[plugins.a]
+name = "a"
+[plugins.a.internal]
+x = 1
+
+[plugins.b]
+name = "b"
+internal = { y = 2 }
+
+[[servers]]
+host = "one"
+secret = "s"
+
+[[servers]]
+host = "two"
+`)
+	tbl, err := Parse(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.UnmarshalTable(tbl, &x); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(x.Plugins, map[string]plugin{"a": {"a"}, "b": {"b"}}) {
+		t.Errorf("wrong plugins: %v", x.Plugins)
+	}
+	if !reflect.DeepEqual(x.Servers, []server{{"one"}, {"two"}}) {
+		t.Errorf("wrong servers: %v", x.Servers)
+	}
+	// The AST is not modified.
+	if _, ok := tbl.Fields["other"]; !ok {
+		t.Error("skipped key removed from AST")
+	}
+}
+
 func TestConfigFieldToKey(t *testing.T) {
 	cfg := Config{FieldToKey: func(reflect.Type, string) string { return "A" }}
 
@@ -79,3 +196,737 @@ B = 2
 		t.Error("MissingField called for 'B'")
 	}
 }
+
+func TestConfigFilterValue(t *testing.T) {
+	var paths []string
+	cfg := DefaultConfig
+	cfg.FilterValue = func(path []string, v reflect.Value) (interface{}, bool) {
+		key := strings.Join(path, ".")
+		paths = append(paths, key)
+		switch key {
+		case "db.password":
+			return "<redacted>", false
+		case "db.port":
+			return nil, true
+		}
+		return nil, false
+	}
+
+	type DB struct {
+		User     string
+		Password string
+		Port     int
+	}
+	x := struct{ Db DB }{DB{"alice", "secret", 5432}}
+	enc, err := cfg.Marshal(&x)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "[db]\nuser = \"alice\"\npassword = \"<redacted>\"\n"
+	if string(enc) != want {
+		t.Errorf("wrong output: got %q, want %q", enc, want)
+	}
+	wantPaths := []string{"db", "db.user", "db.password", "db.port"}
+	if !reflect.DeepEqual(paths, wantPaths) {
+		t.Errorf("wrong paths: got %q, want %q", paths, wantPaths)
+	}
+}
+
+func TestConfigTableOrder(t *testing.T) {
+	type Item struct{ N int }
+	x := struct {
+		C     struct{ V int }
+		Items []Item
+		A     struct{ V int }
+	}{Items: []Item{{1}, {2}}}
+
+	tests := []struct {
+		order TableOrder
+		want  string
+	}{
+		{TableOrderFields, "[c]\nv = 0\n\n[[items]]\nn = 1\n\n[[items]]\nn = 2\n\n[a]\nv = 0\n"},
+		{TableOrderAlphabetical, "[a]\nv = 0\n\n[c]\nv = 0\n\n[[items]]\nn = 1\n\n[[items]]\nn = 2\n"},
+		{TableOrderTablesFirst, "[c]\nv = 0\n\n[a]\nv = 0\n\n[[items]]\nn = 1\n\n[[items]]\nn = 2\n"},
+		{TableOrderArrayTablesFirst, "[[items]]\nn = 1\n\n[[items]]\nn = 2\n\n[c]\nv = 0\n\n[a]\nv = 0\n"},
+	}
+	for _, test := range tests {
+		cfg := DefaultConfig
+		cfg.TableOrder = test.order
+		enc, err := cfg.Marshal(&x)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(enc) != test.want {
+			t.Errorf("wrong output for order %d:\n%s", test.order, checkOutput(enc, []byte(test.want)))
+		}
+	}
+}
+
+func TestConfigCommentUnset(t *testing.T) {
+	type Server struct {
+		Host string
+		Next *Server
+	}
+	x := struct {
+		Name   string
+		Port   *int `toml:",omitempty"`
+		Server *Server
+	}{Name: "app"}
+
+	cfg := DefaultConfig
+	cfg.CommentUnset = true
+	enc, err := cfg.Marshal(&x)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "name = \"app\"\n# port = 0\n\n# [server]\n# host = \"\"\n"
+	if d := checkOutput(enc, []byte(want)); d != "" {
+		t.Errorf("wrong output:\n%s", d)
+	}
+}
+
+func TestConfigAlignEquals(t *testing.T) {
+	x := map[string]interface{}{
+		"a":         1,
+		"long_name": "x",
+		"quoted \"": true,
+		"sub":       map[string]int{"bb": 1, "c": 2},
+	}
+	cfg := DefaultConfig
+	cfg.AlignEquals = true
+	enc, err := cfg.Marshal(x)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `a           = 1
+long_name   = "x"
+"quoted \"" = true
+
+[sub]
+bb = 1
+c  = 2
+`
+	if d := checkOutput(enc, []byte(want)); d != "" {
+		t.Errorf("wrong output:\n%s", d)
+	}
+
+	// Lines inside multi-line values are not key/value pairs.
+	enc, err = cfg.Marshal(map[string]interface{}{
+		"ab":   1,
+		"text": testMarshaler{`"""` + "\nlongkey = 1\n" + `"""`},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = "ab   = 1\ntext = \"\"\"\nlongkey = 1\n\"\"\"\n"
+	if d := checkOutput(enc, []byte(want)); d != "" {
+		t.Errorf("wrong output:\n%s", d)
+	}
+}
+
+func TestConfigSkipNilMapValues(t *testing.T) {
+	var nilPtr *int
+	x := map[string]interface{}{
+		"a":   1,
+		"b":   nil,
+		"c":   nilPtr,
+		"sub": map[string]interface{}{"d": nil, "e": "x"},
+	}
+	if _, err := Marshal(x); err == nil {
+		t.Fatal("expected error without SkipNilMapValues")
+	}
+	cfg := DefaultConfig
+	cfg.SkipNilMapValues = true
+	enc, err := cfg.Marshal(x)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `a = 1
+
+[sub]
+e = "x"
+`
+	if d := checkOutput(enc, []byte(want)); d != "" {
+		t.Errorf("wrong output:\n%s", d)
+	}
+}
+
+func TestConfigWith(t *testing.T) {
+	base := DefaultConfig.With(SkipKeys("a"))
+	cfg := base.With(WriteEmptyTables(true), SkipKeys("b"), AlignEquals(true))
+	if DefaultConfig.WriteEmptyTables || DefaultConfig.SkipKeys != nil {
+		t.Error("With modified DefaultConfig")
+	}
+	if !reflect.DeepEqual(base.SkipKeys, []string{"a"}) {
+		t.Errorf("With modified base config: SkipKeys = %q", base.SkipKeys)
+	}
+	if !cfg.WriteEmptyTables || !cfg.AlignEquals || !reflect.DeepEqual(cfg.SkipKeys, []string{"a", "b"}) {
+		t.Errorf("options not applied: %+v", cfg)
+	}
+	if cfg.NormFieldName == nil || cfg.FieldToKey == nil {
+		t.Error("With dropped fields of the base config")
+	}
+
+	var v struct{ A int }
+	if err := cfg.Unmarshal([]byte("a = 1\nb = 2"), &v); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestConfigOptions(t *testing.T) {
+	type config struct {
+		Name string `conf:"app_name"`
+		Port int    `conf:",omitempty"`
+	}
+	input := []byte("app_name = \"x\"\nextra = 1\n")
+
+	var v config
+	if err := UnmarshalWith(input, &v, TagName("conf")); err == nil {
+		t.Error("expected error for unknown key")
+	}
+	dec := NewDecoderWith(bytes.NewReader(input), IgnoreUnknownFields(), TagName("conf"))
+	if err := dec.Decode(&v); err != nil {
+		t.Fatal(err)
+	}
+	if v.Name != "x" {
+		t.Errorf("wrong value after Decode: %+v", v)
+	}
+	if err := UnmarshalWith(input, &v, IgnoreUnknownFields(), Strict()); err == nil {
+		t.Error("Strict didn't undo IgnoreUnknownFields")
+	}
+
+	out, err := MarshalWith(config{Name: "x"}, TagName("conf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "app_name = \"x\"\n"; string(out) != want {
+		t.Errorf("wrong output from MarshalWith: got %q, want %q", out, want)
+	}
+}
+
+func TestConfigParser(t *testing.T) {
+	var calls int
+	cfg := DefaultConfig.With(UseParser(ParserFunc(func(data []byte) (*ast.Table, error) {
+		calls++
+		return Parse(bytes.ToLower(data))
+	})))
+
+	var v struct{ A string }
+	if err := cfg.Unmarshal([]byte(`A = "X"`), &v); err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.NewDecoder(strings.NewReader(`A = "Y"`)).Decode(&v); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 2 || v.A != "y" {
+		t.Errorf("custom parser not used: %d calls, value %q", calls, v.A)
+	}
+}
+
+type testColor int
+
+func (c testColor) String() string { return [...]string{"red", "green"}[c] }
+
+type testVersion struct{ major, minor int }
+
+func (v *testVersion) String() string { return strconv.Itoa(v.major) + "." + strconv.Itoa(v.minor) }
+
+func TestConfigMarshalStringers(t *testing.T) {
+	v := struct {
+		Color   testColor
+		Version testVersion
+		Colors  []testColor
+		Ptr     *testColor
+	}{Color: 1, Version: testVersion{1, 2}, Colors: []testColor{0, 1}, Ptr: new(testColor)}
+
+	out, err := DefaultConfig.With(MarshalStringers(true)).Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "color = \"green\"\nversion = \"1.2\"\ncolors = [\"red\", \"green\"]\nptr = \"red\"\n"
+	if string(out) != want {
+		t.Errorf("wrong output:\ngot  %q\nwant %q", out, want)
+	}
+}
+
+// testProtoMessage resembles a struct generated by protoc-gen-go.
+type testProtoMessage struct {
+	state         struct{}
+	sizeCache     int32
+	unknownFields []byte
+
+	ServerName string `protobuf:"bytes,1,opt,name=server_name,json=serverName,proto3" json:"server_name,omitempty"`
+	Http2      bool   `protobuf:"varint,2,opt,name=use_http2,json=useHttp2,proto3" json:"use_http2,omitempty"`
+
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+}
+
+func TestConfigProtobuf(t *testing.T) {
+	cfg := DefaultConfig.With(Protobuf(true))
+	var v testProtoMessage
+	if err := cfg.Unmarshal([]byte("serverName = \"x\"\nuse_http2 = true\n"), &v); err != nil {
+		t.Fatal(err)
+	}
+	if v.ServerName != "x" || !v.Http2 {
+		t.Errorf("wrong value after Unmarshal: %+v", v)
+	}
+	if err := cfg.Unmarshal([]byte("XXX_unrecognized = []\n"), &v); err == nil {
+		t.Error("expected error for internal field")
+	}
+
+	out, err := cfg.Marshal(&v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "server_name = \"x\"\nuse_http2 = true\n"; string(out) != want {
+		t.Errorf("wrong output:\ngot  %q\nwant %q", out, want)
+	}
+}
+
+func TestConfigMaxErrors(t *testing.T) {
+	collect := func(cfg *Config, n int) error {
+		list := newErrorList(cfg)
+		for i := 1; i <= n; i++ {
+			if !list.add(lineError(i, errors.New("bad"))) {
+				break
+			}
+		}
+		return list.err()
+	}
+	tests := []struct {
+		cfg  *Config
+		n    int
+		want string
+	}{
+		{&DefaultConfig, 0, ""},
+		{&DefaultConfig, 1, "line 1: bad"},
+		{&DefaultConfig, 2, "line 1: bad\nline 2: bad"},
+		{DefaultConfig.With(MaxErrors(2)), 5, "line 1: bad\nline 2: bad\ntoo many errors"},
+		{DefaultConfig.With(MaxErrors(1)), 5, "line 1: bad\ntoo many errors"},
+		{DefaultConfig.With(MaxErrors(-1)), 3, "line 1: bad\nline 2: bad\nline 3: bad"},
+	}
+	for _, test := range tests {
+		err := collect(test.cfg, test.n)
+		got := ""
+		if err != nil {
+			got = err.Error()
+		}
+		if got != test.want {
+			t.Errorf("MaxErrors %d, %d errors: got %q, want %q", test.cfg.MaxErrors, test.n, got, test.want)
+		}
+	}
+	if err := collect(&DefaultConfig, 100); strings.Count(err.Error(), "\n") != DefaultMaxErrors {
+		t.Errorf("default limit not applied: %v", err)
+	}
+}
+
+func TestConfigNormMapKey(t *testing.T) {
+	cfg := DefaultConfig.With(NormMapKey(DefaultConfig.NormFieldName))
+	var v struct {
+		Levels map[string]int
+		Any    map[string]interface{}
+	}
+	input := []byte("[levels]\nLogLevel = 1\nmax_Size = 2\n[any.Sub_Table]\nKey = true\n")
+	if err := cfg.Unmarshal(input, &v); err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]int{"loglevel": 1, "maxsize": 2}; !reflect.DeepEqual(v.Levels, want) {
+		t.Errorf("got %v, want %v", v.Levels, want)
+	}
+	if want := map[string]interface{}{"subtable": map[string]interface{}{"key": true}}; !reflect.DeepEqual(v.Any, want) {
+		t.Errorf("got %v, want %v", v.Any, want)
+	}
+
+	err := cfg.Unmarshal([]byte("[levels]\nlog_level = 1\nLogLevel = 2\n"), &v)
+	want := "line 3: levels.LogLevel: key `LogLevel' is in conflict with key `log_level' in line 2 (both match key `loglevel' of map[string]int)"
+	if err == nil || err.Error() != want {
+		t.Errorf("wrong error %v, want %q", err, want)
+	}
+}
+
+func TestConfigTypeHints(t *testing.T) {
+	type options struct {
+		Level int
+	}
+	type output struct {
+		Path string
+	}
+	cfg := DefaultConfig.With(TypeHints(map[string]reflect.Type{
+		"plugins.*.options": reflect.TypeOf(options{}),
+		"plugins.log.*":     reflect.TypeOf(""),
+		"outputs":           reflect.TypeOf(&output{}),
+	}))
+	input := []byte(`
+[plugins.a.options]
+level = 1
+
+[plugins.log]
+options = { level = 2 }
+name = "log"
+
+[[outputs]]
+path = "/tmp/x"
+`)
+	var v struct {
+		Plugins map[string]map[string]interface{}
+		Outputs []interface{}
+	}
+	if err := cfg.Unmarshal(input, &v); err != nil {
+		t.Fatal(err)
+	}
+	if got := v.Plugins["a"]["options"]; got != (options{1}) {
+		t.Errorf("plugins.a.options = %#v", got)
+	}
+	if got := v.Plugins["log"]["options"]; got != (options{2}) {
+		t.Errorf("plugins.log.options = %#v", got)
+	}
+	if got := v.Plugins["log"]["name"]; got != "log" {
+		t.Errorf("plugins.log.name = %#v", got)
+	}
+	if len(v.Outputs) != 1 || !reflect.DeepEqual(v.Outputs[0], &output{"/tmp/x"}) {
+		t.Errorf("outputs = %#v", v.Outputs)
+	}
+
+	var m map[string]interface{}
+	if err := cfg.Unmarshal(input, &m); err != nil {
+		t.Fatal(err)
+	}
+	if got := m["outputs"].([]interface{})[0]; !reflect.DeepEqual(got, &output{"/tmp/x"}) {
+		t.Errorf("outputs in map = %#v", got)
+	}
+}
+
+func TestConfigVersion(t *testing.T) {
+	cfg := DefaultConfig.With(UseVersion("0.4"))
+	var v map[string]interface{}
+	err := cfg.Unmarshal([]byte("a = 0x10\n"), &v)
+	if want := "line 1: hexadecimal, octal and binary integers require TOML 0.5.0, but version 0.4 is selected"; err == nil || err.Error() != want {
+		t.Errorf("wrong decode error %v, want %q", err, want)
+	}
+	if err := cfg.NewDecoder(strings.NewReader("a = nan\n")).Decode(&v); err == nil {
+		t.Error("Decoder accepted nan for version 0.4")
+	}
+
+	mixed := map[string]interface{}{"a": []interface{}{1, "x"}}
+	_, err = DefaultConfig.With(UseVersion("0.5")).Marshal(mixed)
+	if want := "toml: cannot encode as TOML 0.5: arrays with mixed element types require TOML 1.0.0, but version 0.5 is selected"; err == nil || err.Error() != want {
+		t.Errorf("wrong encode error %v, want %q", err, want)
+	}
+	out, err := DefaultConfig.With(UseVersion("1.0")).Marshal(mixed)
+	if err != nil || string(out) != "a = [1, \"x\"]\n" {
+		t.Errorf("wrong output %q, error %v", out, err)
+	}
+	if _, err := DefaultConfig.With(UseVersion("2.0")).Marshal(mixed); err == nil {
+		t.Error("no error for unknown version")
+	}
+}
+
+func TestConfigRejectBOM(t *testing.T) {
+	input := []byte("\xef\xbb\xbfa = 1\n")
+	var v struct{ A int }
+	if err := Unmarshal(input, &v); err != nil || v.A != 1 {
+		t.Errorf("got %+v, error %v", v, err)
+	}
+	cfg := DefaultConfig.With(Strict())
+	want := "line 1: byte order mark at start of document"
+	if err := cfg.Unmarshal(input, &v); err == nil || err.Error() != want {
+		t.Errorf("wrong error %v, want %q", err, want)
+	}
+	if err := cfg.NewDecoder(bytes.NewReader(input)).Decode(&v); err == nil || err.Error() != want {
+		t.Errorf("wrong Decoder error %v, want %q", err, want)
+	}
+}
+
+func TestConfigAllowBareCR(t *testing.T) {
+	input := []byte("a = 1\rb = \"\"\"x\ry\"\"\"\r")
+	var v struct {
+		A int
+		B string
+	}
+	cfg := DefaultConfig
+	cfg.AllowBareCR = true
+	if err := cfg.Unmarshal(input, &v); err != nil || v.A != 1 || v.B != "x\ny" {
+		t.Errorf("got %+v, error %v", v, err)
+	}
+	if err := cfg.With(Strict()).Unmarshal(input, &v); err == nil {
+		t.Error("Strict accepted bare CR")
+	}
+}
+
+func TestConfigStrict(t *testing.T) {
+	cfg := DefaultConfig.With(Strict())
+	if cfg.Version != "1.0" || !cfg.RejectBOM {
+		t.Errorf("wrong strict config: version %q, RejectBOM %t", cfg.Version, cfg.RejectBOM)
+	}
+	if cfg := DefaultConfig.With(UseVersion("0.5"), Strict()); cfg.Version != "0.5" {
+		t.Errorf("Strict replaced version %q", cfg.Version)
+	}
+
+	var ratio struct{ Ratio float64 }
+	strict := DefaultConfig.With(WeaklyTypedInput(true), Strict())
+	err := strict.Unmarshal([]byte("ratio = 1"), &ratio)
+	if err == nil || err.Error() != "line 1: ratio (struct { Ratio float64 }.Ratio): cannot unmarshal TOML integer into float64" {
+		t.Errorf("wrong error for integer float %v", err)
+	}
+
+	var v map[string]interface{}
+	tests := []struct {
+		input, err string
+	}{
+		{"a = 1\na = 2\n", "line 2: key `a' is in conflict with line 1"},
+		{"a = {b = 1,}\n", "line 1: inline table cannot contain ',' after last key/value pair"},
+		{"a = 2021-02-29\n", "line 1: invalid datetime 2021-02-29 for key `a': day out of range"},
+		{"a = \"\\e\"\n", "line 1: \\e and \\x escapes require TOML 1.1.0, but version 1.0 is selected"},
+	}
+	for _, test := range tests {
+		err := cfg.Unmarshal([]byte(test.input), &v)
+		if errString(err) != test.err {
+			t.Errorf("input %q: got error %q, want %q", test.input, errString(err), test.err)
+		}
+	}
+}
+
+func TestConfigAllowLeadingZeros(t *testing.T) {
+	input := []byte("port = 08080\nmode = 0755\nneg = -00\nratio = 00.5\n")
+	var warnings []string
+	cfg := DefaultConfig.With(Warning(func(err error) { warnings = append(warnings, err.Error()) }))
+	cfg.AllowLeadingZeros = true
+	var v struct {
+		Port, Mode, Neg int
+		Ratio           float64
+	}
+	if err := cfg.Unmarshal(input, &v); err != nil {
+		t.Fatal(err)
+	}
+	if v.Port != 8080 || v.Mode != 755 || v.Neg != 0 || v.Ratio != 0.5 {
+		t.Errorf("wrong values %+v", v)
+	}
+	want := []string{
+		"line 1: number 08080 has leading zeros",
+		"line 2: number 0755 has leading zeros",
+		"line 3: number -00 has leading zeros",
+		"line 4: number 00.5 has leading zeros",
+	}
+	if !reflect.DeepEqual(warnings, want) {
+		t.Errorf("wrong warnings %q", warnings)
+	}
+	if err := cfg.With(Strict()).Unmarshal(input, &v); err == nil {
+		t.Error("Strict accepted leading zeros")
+	}
+}
+
+func TestConfigOnUnknownField(t *testing.T) {
+	input := []byte("name = \"x\"\nextra = 1\n\n[server]\nport = 80\n")
+	var unknown []string
+	cfg := DefaultConfig.With(IgnoreUnknownFields(), OnUnknownField(func(typ reflect.Type, key string, line int) {
+		unknown = append(unknown, fmt.Sprintf("%v.%s:%d", typ, key, line))
+	}))
+	var v struct {
+		Name   string
+		Server struct{ Host string }
+	}
+	if err := cfg.Unmarshal(input, &v); err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(unknown)
+	want := []string{"struct { Host string }.port:5", "struct { Name string; Server struct { Host string } }.extra:2"}
+	if !reflect.DeepEqual(unknown, want) {
+		t.Errorf("wrong unknown keys %q", unknown)
+	}
+}
+
+func TestConfigCollectUnknownFields(t *testing.T) {
+	input := []byte("name = \"x\"\nextra = 1\n\n[server]\nport = 80\nhost = \"h\"\n\n[[clients]]\nid = 1\n")
+	type config struct {
+		Name   string
+		Server struct{ Host string }
+	}
+	tests := []struct {
+		cfg  *Config
+		want string
+	}{
+		{
+			DefaultConfig.With(CollectUnknownFields(true)),
+			"line 2: extra: field corresponding to `extra' is not defined in toml.config\n" +
+				"line 5: server.port: field corresponding to `port' is not defined in struct { Host string }\n" +
+				"line 8: clients: field corresponding to `clients' is not defined in toml.config",
+		},
+		{
+			DefaultConfig.With(CollectUnknownFields(true), MaxErrors(2)),
+			"line 2: extra: field corresponding to `extra' is not defined in toml.config\n" +
+				"line 5: server.port: field corresponding to `port' is not defined in struct { Host string }\n" +
+				"too many errors",
+		},
+		{
+			DefaultConfig.With(CollectUnknownFields(true)).With(func(cfg *Config) {
+				cfg.MissingField = func(typ reflect.Type, key string) error {
+					if key == "port" {
+						return nil
+					}
+					return fmt.Errorf("unknown key %s", key)
+				}
+			}),
+			"line 2: extra: unknown key extra\nline 8: clients: unknown key clients",
+		},
+	}
+	for _, test := range tests {
+		var v config
+		err := test.cfg.Unmarshal(input, &v)
+		if err == nil {
+			t.Errorf("expected error, want %q", test.want)
+			continue
+		}
+		if err.Error() != test.want {
+			t.Errorf("wrong error:\ngot  %q\nwant %q", err.Error(), test.want)
+		}
+		if v.Name != "x" {
+			t.Errorf("known field not decoded: %+v", v)
+		}
+	}
+
+	var v struct{ Name int }
+	err := DefaultConfig.With(CollectUnknownFields(true)).Unmarshal([]byte("a = 1\nname = \"x\"\nb = 2\n"), &v)
+	want := "line 1: a: field corresponding to `a' is not defined in struct { Name int }\n" +
+		"line 2: name (struct { Name int }.Name): cannot unmarshal TOML string into int"
+	if err == nil || err.Error() != want {
+		t.Errorf("wrong error for type mismatch:\ngot  %v\nwant %s", err, want)
+	}
+}
+
+func TestConfigContinueOnError(t *testing.T) {
+	input := []byte("a = \"x\"\nb = 300\nc = 1\nextra = true\n\n[m]\nk1 = 1\nk2 = \"y\"\n")
+	var v struct {
+		A int
+		B int8
+		C int
+		M map[string]int
+	}
+	err := DefaultConfig.With(ContinueOnError(true)).Unmarshal(input, &v)
+	var list *ErrorList
+	if !errors.As(err, &list) {
+		t.Fatalf("expected *ErrorList, got %#v", err)
+	}
+	var msgs []string
+	for _, e := range list.Errors {
+		msgs = append(msgs, e.Error())
+	}
+	want := []string{
+		"line 1: a (struct { A int; B int8; C int; M map[string]int }.A): cannot unmarshal TOML string into int",
+		"line 2: b (struct { A int; B int8; C int; M map[string]int }.B): value 300 is out of range for int8",
+		"line 4: extra: field corresponding to `extra' is not defined in struct { A int; B int8; C int; M map[string]int }",
+		"line 8: m.k2: cannot unmarshal TOML string into int",
+	}
+	if !reflect.DeepEqual(msgs, want) {
+		t.Errorf("wrong errors:\ngot  %q\nwant %q", msgs, want)
+	}
+	if list.Limited {
+		t.Error("list should not be limited")
+	}
+	if v.C != 1 || v.M["k1"] != 1 {
+		t.Errorf("valid keys not decoded: %+v", v)
+	}
+}
+
+func TestConfigRenamedKeys(t *testing.T) {
+	input := []byte("listen = \":80\"\n\n[server]\ntimeout_seconds = 5\n")
+	var renamed, warnings []string
+	cfg := DefaultConfig.With(
+		RenamedKeys(map[string]string{
+			"listen":                 "server.listen",
+			"server.timeout_seconds": "server.timeout",
+		}),
+		OnRenamedKey(func(oldPath, newPath string, line int) {
+			renamed = append(renamed, fmt.Sprintf("%s->%s:%d", oldPath, newPath, line))
+		}),
+		Warning(func(err error) { warnings = append(warnings, err.Error()) }),
+	)
+	type config struct {
+		Server struct {
+			Listen  string
+			Timeout int
+		}
+	}
+	var v config
+	if err := cfg.Unmarshal(input, &v); err != nil {
+		t.Fatal(err)
+	}
+	if v.Server.Listen != ":80" || v.Server.Timeout != 5 {
+		t.Errorf("wrong value %+v", v)
+	}
+	wantRenamed := []string{"listen->server.listen:1", "server.timeout_seconds->server.timeout:4"}
+	if !reflect.DeepEqual(renamed, wantRenamed) {
+		t.Errorf("wrong renamed keys %q", renamed)
+	}
+	wantWarnings := []string{
+		"line 1: listen: key `listen' is deprecated, use `server.listen' instead",
+		"line 4: server.timeout_seconds: key `server.timeout_seconds' is deprecated, use `server.timeout' instead",
+	}
+	if !reflect.DeepEqual(warnings, wantWarnings) {
+		t.Errorf("wrong warnings %q", warnings)
+	}
+
+	// The AST passed to UnmarshalTable is not modified.
+	table, err := Parse([]byte("[server]\ntimeout_seconds = 5\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.UnmarshalTable(table, new(config)); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := table.Fields["server"].(*ast.Table).Fields["timeout_seconds"]; !ok {
+		t.Error("UnmarshalTable modified the AST")
+	}
+
+	err = cfg.Unmarshal([]byte("[server]\ntimeout = 1\ntimeout_seconds = 5\n"), &v)
+	if err == nil || err.Error() != "line 3: key `server.timeout_seconds' is in conflict with `server.timeout' in line 2, which replaces it" {
+		t.Errorf("wrong error %v", err)
+	}
+
+	// Tables left empty by renaming their keys are removed.
+	cfg = DefaultConfig.With(RenamedKeys(map[string]string{"old.sub.k": "new.k"}))
+	var moved struct{ New struct{ K int } }
+	if err := cfg.Unmarshal([]byte("[old.sub]\nk = 1\n"), &moved); err != nil {
+		t.Fatal(err)
+	}
+	if moved.New.K != 1 {
+		t.Errorf("wrong value %+v", moved)
+	}
+	var kept struct {
+		Old struct {
+			Sub struct{}
+			X   int
+		}
+		New struct{ K int }
+	}
+	if err := cfg.Unmarshal([]byte("[old]\nx = 2\n[old.sub]\nk = 1\n"), &kept); err != nil {
+		t.Fatal(err)
+	}
+	if kept.Old.X != 2 || kept.New.K != 1 {
+		t.Errorf("wrong value %+v", kept)
+	}
+}
+
+func TestConfigExactKeys(t *testing.T) {
+	type config struct {
+		Name       string
+		ServerName string
+		Na_me      string
+	}
+	cfg := DefaultConfig.With(ExactKeys(true))
+	var v config
+	if err := cfg.Unmarshal([]byte("name = \"a\"\nserver_name = \"b\"\nna_me = \"c\"\n"), &v); err != nil {
+		t.Fatal(err)
+	}
+	if v != (config{"a", "b", "c"}) {
+		t.Errorf("wrong value %+v", v)
+	}
+	for _, input := range []string{"Name = \"a\"", "servername = \"b\""} {
+		if err := cfg.Unmarshal([]byte(input), &v); err == nil {
+			t.Errorf("%q: expected error for non-exact key", input)
+		}
+	}
+	if err := Unmarshal([]byte(`name = "a"`), &v); err == nil {
+		t.Error("expected conflict between Name and Na_me without ExactKeys")
+	}
+}
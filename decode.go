@@ -4,12 +4,15 @@
 package toml
 
 import (
+	"bytes"
 	"encoding"
 	"fmt"
 	"io"
-	"io/ioutil"
+	"math/big"
 	"reflect"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/naoina/toml/ast"
@@ -29,8 +32,13 @@ var timeType = reflect.TypeOf(time.Time{})
 //	TOML arrays to any type of slice
 //	TOML tables to struct or map
 //	TOML array tables to slice of struct or map
+//
+// Arrays with elements of different types, as allowed by TOML 1.0, can be decoded into
+// []interface{}. Tables can also be decoded into []KeyValue, which keeps the keys in
+// document order. Integers decoded into interface{} are int64, or uint64 or *big.Int if
+// they don't fit.
 func (cfg *Config) Unmarshal(data []byte, v interface{}) error {
-	table, err := Parse(data)
+	table, err := cfg.parseBytes(data)
 	if err != nil {
 		return err
 	}
@@ -40,26 +48,90 @@ func (cfg *Config) Unmarshal(data []byte, v interface{}) error {
 	return nil
 }
 
+// MustUnmarshal is like Unmarshal but panics if an error occurs. The panic message
+// includes the offending source line.
+func (cfg *Config) MustUnmarshal(data []byte, v interface{}) {
+	if err := cfg.Unmarshal(data, v); err != nil {
+		mustPanic("MustUnmarshal", data, err)
+	}
+}
+
 // A Decoder reads and decodes TOML from an input stream.
 type Decoder struct {
-	r   io.Reader
-	cfg *Config
+	r         io.Reader
+	cfg       *Config
+	offset    int64
+	progress  func(offset int64)
+	positions Positions
+
+	incremental bool
+	tokens      *tokenReader
 }
 
 // NewDecoder returns a new Decoder that reads from r.
-// Note that it reads all from r before parsing it.
+// Note that it reads all from r before parsing it, unless Incremental is called.
 func (cfg *Config) NewDecoder(r io.Reader) *Decoder {
-	return &Decoder{r, cfg}
+	return &Decoder{r: r, cfg: cfg}
+}
+
+// InputOffset returns the number of bytes read from the input stream so far.
+// It is safe to call InputOffset from the progress function.
+func (d *Decoder) InputOffset() int64 {
+	return d.offset
+}
+
+// Progress sets a function that is called with the input offset whenever
+// Decode has read from the input stream.
+func (d *Decoder) Progress(fn func(offset int64)) {
+	d.progress = fn
+}
+
+// RecordPositions makes Decode add the positions of all keys of the decoded documents
+// to p.
+func (d *Decoder) RecordPositions(p Positions) {
+	d.positions = p
+}
+
+// Incremental makes Decode parse the input in parts while reading it, as with the
+// Incremental parse option. This bounds the memory used for large documents, but the
+// source text isn't available: values implementing Unmarshaler receive the canonical
+// form of their value, and positions of values and decode errors have no column.
+// It has no effect if Config.Parser is set.
+func (d *Decoder) Incremental() {
+	d.incremental = true
 }
 
 // Decode parses the TOML data from its input and stores it in the value pointed to by v.
 // See the documentation for Unmarshal for details about the conversion of TOML into a Go value.
 func (d *Decoder) Decode(v interface{}) error {
-	b, err := ioutil.ReadAll(d.r)
+	var opts []ParseOption
+	if d.incremental {
+		opts = append(opts, Incremental())
+	}
+	table, err := d.cfg.parseReader(decoderReader{d}, opts...)
 	if err != nil {
 		return err
 	}
-	return d.cfg.Unmarshal(b, v)
+	if d.positions != nil {
+		d.positions.record(table)
+	}
+	return d.cfg.UnmarshalTable(table, v)
+}
+
+// decoderReader tracks the input offset of a Decoder.
+type decoderReader struct {
+	d *Decoder
+}
+
+func (r decoderReader) Read(buf []byte) (int, error) {
+	n, err := r.d.r.Read(buf)
+	if n > 0 {
+		r.d.offset += int64(n)
+		if r.d.progress != nil {
+			r.d.progress(r.d.offset)
+		}
+	}
+	return n, err
 }
 
 // UnmarshalerRec may be implemented by types to customize their behavior when being
@@ -82,6 +154,14 @@ type Unmarshaler interface {
 	UnmarshalTOML(input []byte) error
 }
 
+// Validator may be implemented by types to check their value after it has been decoded
+// from a table. ValidateTOML is called once all keys of the table are set, including
+// those of nested tables, whose values are validated first. Errors are returned with the
+// line of the table.
+type Validator interface {
+	ValidateTOML() error
+}
+
 // UnmarshalTable applies the contents of an ast.Table to the value pointed at by v.
 //
 // UnmarshalTable will mapped to v that according to following rules:
@@ -100,11 +180,254 @@ func (cfg *Config) UnmarshalTable(t *ast.Table, v interface{}) error {
 	if (!toplevelMap && rv.Kind() != reflect.Ptr) || rv.IsNil() {
 		return &invalidUnmarshalError{reflect.TypeOf(v)}
 	}
-	return unmarshalTable(cfg, rv, t, toplevelMap)
+	if len(cfg.RenamedKeys) > 0 {
+		var err error
+		if t, err = renameKeys(cfg, t); err != nil {
+			return err
+		}
+	}
+	if len(cfg.SkipKeys) > 0 {
+		t = skipKeys(t, nil, splitKeyPaths(cfg.SkipKeys))
+	}
+	if len(cfg.TypeHints) > 0 {
+		c := *cfg
+		c.hintedNodes = make(map[interface{}]reflect.Type)
+		findTypeHints(c.hintedNodes, t, nil, newTypeHints(cfg.TypeHints))
+		cfg = &c
+	}
+	if isDocument(t) || cfg.CollectUnknownFields || cfg.ContinueOnError {
+		c := *cfg
+		if isDocument(t) {
+			// The source of a document contains all values, so it is used for the
+			// columns of errors.
+			c.source = &docSource{data: t.Data}
+		}
+		if cfg.CollectUnknownFields || cfg.ContinueOnError {
+			c.errs = newErrorList(cfg)
+		}
+		cfg = &c
+	}
+	err := unmarshalTable(cfg, rv, t, nil, toplevelMap)
+	if cfg.errs != nil {
+		return cfg.errs.finish(err)
+	}
+	return err
+}
+
+// isDocument reports whether t is the top-level table of a document returned by the
+// parser, which holds the entire source.
+func isDocument(t *ast.Table) bool {
+	return t.Data != nil && t.Name == "" && t.Type == ast.TableTypeNormal && t.Position.Begin == 0 && t.Position.End == len(t.Data)
+}
+
+type typeHint struct {
+	pattern []string
+	typ     reflect.Type
+}
+
+// newTypeHints returns the entries of Config.TypeHints, most specific patterns first.
+func newTypeHints(m map[string]reflect.Type) []typeHint {
+	hints := make([]typeHint, 0, len(m))
+	for path, typ := range m {
+		hints = append(hints, typeHint{strings.Split(path, "."), typ})
+	}
+	wildcards := func(h typeHint) (n int) {
+		for _, elem := range h.pattern {
+			if elem == "*" {
+				n++
+			}
+		}
+		return n
+	}
+	sort.Slice(hints, func(i, j int) bool {
+		wi, wj := wildcards(hints[i]), wildcards(hints[j])
+		if wi != wj {
+			return wi < wj
+		}
+		return strings.Join(hints[i].pattern, ".") < strings.Join(hints[j].pattern, ".")
+	})
+	return hints
+}
+
+// hintedType returns the type that Config.TypeHints assigns to an AST node.
+func hintedType(cfg *Config, fieldAst interface{}) reflect.Type {
+	switch fieldAst.(type) {
+	case *ast.KeyValue, *ast.Table:
+		if typ := cfg.hintedNodes[fieldAst]; typ != nil && typ.Kind() != reflect.Interface {
+			return typ
+		}
+	}
+	return nil
+}
+
+// findTypeHints records the hinted type of all fields of t matched by hints in nodes.
+func findTypeHints(nodes map[interface{}]reflect.Type, t *ast.Table, path []string, hints []typeHint) {
+	for key, field := range t.Fields {
+		fieldPath := append(path[:len(path):len(path)], key)
+		var typ reflect.Type
+		for _, h := range hints {
+			if matchKeyPath([][]string{h.pattern}, fieldPath) {
+				typ = h.typ
+				break
+			}
+		}
+		switch f := field.(type) {
+		case *ast.Table:
+			if typ != nil {
+				nodes[f] = typ
+			}
+			findTypeHints(nodes, f, fieldPath, hints)
+		case []*ast.Table:
+			for _, elem := range f {
+				if typ != nil {
+					nodes[elem] = typ
+				}
+				findTypeHints(nodes, elem, fieldPath, hints)
+			}
+		case *ast.KeyValue:
+			if typ != nil {
+				nodes[f] = typ
+			}
+			if inline, ok := f.Value.(*ast.Table); ok {
+				findTypeHints(nodes, inline, fieldPath, hints)
+			}
+		}
+	}
+}
+
+func splitKeyPaths(paths []string) [][]string {
+	split := make([][]string, len(paths))
+	for i, p := range paths {
+		split[i] = strings.Split(p, ".")
+	}
+	return split
+}
+
+// matchKeyPath reports whether path is matched by any of the patterns.
+func matchKeyPath(patterns [][]string, path []string) bool {
+outer:
+	for _, pattern := range patterns {
+		if len(pattern) != len(path) {
+			continue
+		}
+		for i, elem := range pattern {
+			if elem != "*" && elem != path[i] {
+				continue outer
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// skipKeys returns t without the fields matched by patterns. The table is only
+// copied if any of its fields are removed, the original AST is never modified.
+func skipKeys(t *ast.Table, path []string, patterns [][]string) *ast.Table {
+	var fields map[string]interface{}
+	for key, field := range t.Fields {
+		fieldPath := append(path[:len(path):len(path)], key)
+		newField, changed := skipKeysField(field, fieldPath, patterns)
+		if !changed {
+			continue
+		}
+		if fields == nil {
+			fields = make(map[string]interface{}, len(t.Fields))
+			for k, v := range t.Fields {
+				fields[k] = v
+			}
+		}
+		if newField == nil {
+			delete(fields, key)
+		} else {
+			fields[key] = newField
+		}
+	}
+	if fields == nil {
+		return t
+	}
+	nt := *t
+	nt.Fields = fields
+	return &nt
+}
+
+// skipKeysField applies skipKeys to a table field. It returns nil if the field
+// should be removed.
+func skipKeysField(field interface{}, path []string, patterns [][]string) (interface{}, bool) {
+	if matchKeyPath(patterns, path) {
+		return nil, true
+	}
+	switch f := field.(type) {
+	case *ast.Table:
+		if nt := skipKeys(f, path, patterns); nt != f {
+			return nt, true
+		}
+	case []*ast.Table:
+		var tables []*ast.Table
+		for i, t := range f {
+			nt := skipKeys(t, path, patterns)
+			if nt != t && tables == nil {
+				tables = append([]*ast.Table(nil), f...)
+			}
+			if tables != nil {
+				tables[i] = nt
+			}
+		}
+		if tables != nil {
+			return tables, true
+		}
+	case *ast.KeyValue:
+		if t, ok := f.Value.(*ast.Table); ok {
+			if nt := skipKeys(t, path, patterns); nt != t {
+				kv := *f
+				kv.Value = nt
+				return &kv, true
+			}
+		}
+	}
+	return field, false
+}
+
+// checkRequired reports the fields of struct type rt tagged "required" which weren't set
+// by table t. Fields holding a struct whose table is missing are checked too, with t as
+// the location of the error. Pointers to structs make their table optional, so they are
+// not checked.
+func checkRequired(cfg *Config, rt reflect.Type, fc fieldCache, t *ast.Table, path *keyPath, setBy map[string]string) error {
+	for _, info := range fc.fields() {
+		if _, ok := setBy[info.name]; ok {
+			continue
+		}
+		key := info.key
+		switch {
+		case key != "":
+		case cfg.FieldToKey != nil:
+			key = cfg.FieldToKey(rt, info.name)
+		default:
+			key = info.name
+		}
+		if info.opts.has(tagRequired) {
+			structField := rt.String() + "." + info.name
+			if err := cfg.collect(cfg.valueError(path, t, structField, &requiredKeyError{key}), false); err != nil {
+				return err
+			}
+			continue
+		}
+		ft := rt.FieldByIndex(info.index).Type
+		if ft.Kind() != reflect.Struct || ft == timeType || hasUnmarshaler(ft) {
+			continue
+		}
+		sub, err := makeFieldCache(cfg, ft)
+		if err != nil {
+			continue // the table is missing, so the conflict doesn't matter
+		}
+		if err := checkRequired(cfg, ft, sub, t, path.child(key), nil); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 // used for UnmarshalerRec.
-func unmarshalTableOrValue(cfg *Config, rv reflect.Value, av interface{}) error {
+func unmarshalTableOrValue(cfg *Config, rv reflect.Value, av interface{}, path *keyPath) error {
 	if (rv.Kind() != reflect.Ptr && rv.Kind() != reflect.Map) || rv.IsNil() {
 		return &invalidUnmarshalError{rv.Type()}
 	}
@@ -112,12 +435,12 @@ func unmarshalTableOrValue(cfg *Config, rv reflect.Value, av interface{}) error
 
 	switch av := av.(type) {
 	case *ast.KeyValue, *ast.Table, []*ast.Table:
-		if err := unmarshalField(cfg, rv, av); err != nil {
-			return lineError(fieldLineNumber(av), err)
+		if err := unmarshalField(cfg, rv, av, path); err != nil {
+			return cfg.valueError(path, av, "", err)
 		}
 		return nil
 	case ast.Value:
-		return setValue(cfg, rv, av)
+		return setValue(cfg, rv, av, path)
 	default:
 		panic(fmt.Sprintf("BUG: unhandled AST node type %T", av))
 	}
@@ -127,23 +450,36 @@ func unmarshalTableOrValue(cfg *Config, rv reflect.Value, av interface{}) error
 //
 // toplevelMap is true when rv is an (unadressable) map given to UnmarshalTable. In this
 // (special) case, the map is used as-is instead of creating a new map.
-func unmarshalTable(cfg *Config, rv reflect.Value, t *ast.Table, toplevelMap bool) error {
+func unmarshalTable(cfg *Config, rv reflect.Value, t *ast.Table, path *keyPath, toplevelMap bool) error {
 	rv = indirect(rv)
-	if handled, err := setUnmarshaler(cfg, rv, t); handled {
-		return lineError(t.Line, err)
+	if handled, err := setUnmarshaler(cfg, rv, t, path); handled {
+		return cfg.valueError(path, t, "", err)
 	}
 
 	switch {
+	case rv.Type() == keyValuesType:
+		cfg.markDecoded(path, true)
+		return unmarshalKeyValues(cfg, rv, t, path)
 	case rv.Kind() == reflect.Struct:
-		fc := makeFieldCache(cfg, rv.Type())
-		for key, fieldAst := range t.Fields {
-			fv, fieldName, err := fc.findField(cfg, rv, key)
-			if err != nil {
-				return lineError(fieldLineNumber(fieldAst), err)
-			}
-			if fv.IsValid() {
-				if err := unmarshalField(cfg, fv, fieldAst); err != nil {
-					return lineErrorField(fieldLineNumber(fieldAst), rv.Type().String()+"."+fieldName, err)
+		fc, err := makeFieldCache(cfg, rv.Type())
+		if err != nil {
+			return cfg.valueError(path, t, "", err)
+		}
+		setBy := make(map[string]string) // field name -> key
+		if err := unmarshalStructFields(cfg, rv, fc, t, path, "", setBy); err != nil {
+			return err
+		}
+		if err := checkRequired(cfg, rv.Type(), fc, t, path, setBy); err != nil {
+			return err
+		}
+		if cfg.UnsetField != nil {
+			for _, info := range fc.fields() {
+				fv := fieldByIndex(rv, info.index, false)
+				if _, ok := setBy[info.name]; ok || fv.IsValid() && !isEmptyValue(fv) {
+					continue
+				}
+				if err := cfg.UnsetField(rv.Type(), info.name); err != nil {
+					return cfg.valueError(path, t, "", err)
 				}
 			}
 		}
@@ -157,26 +493,260 @@ func unmarshalTable(cfg *Config, rv reflect.Value, t *ast.Table, toplevelMap boo
 			}
 		}
 		elemtyp := m.Type().Elem()
-		for key, fieldAst := range t.Fields {
-			kv, err := unmarshalMapKey(m.Type().Key(), key)
-			if err != nil {
-				return lineError(fieldLineNumber(fieldAst), err)
+		setBy := make(map[string]string) // normalized key -> key
+		for _, key := range tableKeys(cfg, t) {
+			fieldAst, fieldPath := t.Fields[key], path.child(key)
+			mapKey := key
+			if cfg.NormMapKey != nil {
+				mapKey = cfg.NormMapKey(m.Type(), key)
+				if other, ok := setBy[mapKey]; ok {
+					if err := cfg.collect(keyConflictError(cfg, key, other, fmt.Sprintf("key `%s' of %v", mapKey, m.Type()), t, path), false); err != nil {
+						return err
+					}
+					continue
+				}
+				setBy[mapKey] = key
 			}
-			fv := reflect.New(elemtyp).Elem()
-			if err := unmarshalField(cfg, fv, fieldAst); err != nil {
-				return lineError(fieldLineNumber(fieldAst), err)
+			kv, err := unmarshalMapKey(m.Type().Key(), mapKey)
+			if err == nil {
+				fv := reflect.New(elemtyp).Elem()
+				if err = unmarshalField(cfg, fv, fieldAst, fieldPath); err == nil {
+					m.SetMapIndex(kv, fv)
+					continue
+				}
+			}
+			if err := cfg.collect(cfg.valueError(fieldPath, fieldAst, "", err), false); err != nil {
+				return err
 			}
-			m.SetMapIndex(kv, fv)
 		}
 		if !toplevelMap {
 			rv.Set(m)
 		}
 	default:
-		return lineError(t.Line, &unmarshalTypeError{"table", "struct or map", rv.Type()})
+		return cfg.valueError(path, t, "", &UnmarshalTypeError{"table", "struct or map", rv.Type()})
+	}
+	return cfg.collect(validate(cfg, rv, t, path), false)
+}
+
+// validate calls the ValidateTOML method of rv, which was decoded from table t.
+func validate(cfg *Config, rv reflect.Value, t *ast.Table, path *keyPath) error {
+	if rv.CanAddr() {
+		rv = rv.Addr()
+	}
+	v, ok := rv.Interface().(Validator)
+	if !ok {
+		return nil
+	}
+	return cfg.valueError(path, t, "", v.ValidateTOML())
+}
+
+// unmarshalStructFields sets the fields of struct rv from the keys of t. The prefix is
+// the dotted key of t in the tags of rv's fields, and setBy tracks the keys of fields
+// which were already set.
+func unmarshalStructFields(cfg *Config, rv reflect.Value, fc fieldCache, t *ast.Table, path *keyPath, prefix string, setBy map[string]string) error {
+	for _, key := range tableKeys(cfg, t) {
+		fieldAst, fieldPath := t.Fields[key], path.child(key)
+		if sub, ok := fieldTable(fieldAst); ok && fc.tables[prefix+key] != "" {
+			cfg.markDecoded(fieldPath, false)
+			if err := unmarshalStructFields(cfg, rv, fc, sub, fieldPath, prefix+key+".", setBy); err != nil {
+				return err
+			}
+			continue
+		}
+		fv, info, err := fc.findField(cfg, rv, prefix+key, fieldLineNumber(fieldAst))
+		if err != nil {
+			if err := cfg.collect(cfg.valueError(fieldPath, fieldAst, "", err), true); err != nil {
+				return err
+			}
+			continue
+		}
+		if fv.IsValid() {
+			structField := rv.Type().String() + "." + info.name
+			if other, ok := setBy[info.name]; ok {
+				if err := cfg.collect(keyConflictError(cfg, key, other, fmt.Sprintf("field %s", structField), t, path), false); err != nil {
+					return err
+				}
+				continue
+			}
+			setBy[info.name] = key
+			if info.deprecated && cfg.Warning != nil {
+				cfg.Warning(cfg.valueError(fieldPath, fieldAst, structField, &deprecatedKeyError{key, info.deprecatedMsg}))
+			}
+			if err := unmarshalStructField(cfg, fv, info, fieldAst, fieldPath); err != nil {
+				if err := cfg.collect(cfg.valueError(fieldPath, fieldAst, structField, err), false); err != nil {
+					return err
+				}
+			} else {
+				cfg.markSet(fv)
+			}
+		}
 	}
 	return nil
 }
 
+// fieldTable returns the table of a table field or of a key/value pair holding an inline
+// table.
+func fieldTable(fieldAst interface{}) (*ast.Table, bool) {
+	switch f := fieldAst.(type) {
+	case *ast.Table:
+		return f, true
+	case *ast.KeyValue:
+		t, ok := f.Value.(*ast.Table)
+		return t, ok
+	}
+	return nil, false
+}
+
+// tableKeys returns the keys of t. When errors are gathered, the keys are in document
+// order so that the same errors are reported if decoding stops at Config.MaxErrors.
+func tableKeys(cfg *Config, t *ast.Table) []string {
+	keys := make([]string, 0, len(t.Fields))
+	for key := range t.Fields {
+		keys = append(keys, key)
+	}
+	if cfg.errs != nil {
+		sort.Slice(keys, func(i, j int) bool { return fieldPos(t.Fields[keys[i]]) < fieldPos(t.Fields[keys[j]]) })
+	}
+	return keys
+}
+
+// unmarshalKeyValues stores the fields of t in a []KeyValue, in the order they appear in
+// the document. Sub-tables are also decoded as []KeyValue, array tables as []interface{}
+// containing []KeyValue.
+func unmarshalKeyValues(cfg *Config, rv reflect.Value, t *ast.Table, path *keyPath) error {
+	keys := make([]string, 0, len(t.Fields))
+	for key := range t.Fields {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return fieldPos(t.Fields[keys[i]]) < fieldPos(t.Fields[keys[j]]) })
+	kvs := make([]KeyValue, len(keys))
+	for i, key := range keys {
+		value, err := orderedValue(cfg, t.Fields[key], path.child(key))
+		if err != nil {
+			return cfg.valueError(path.child(key), t.Fields[key], "", err)
+		}
+		kvs[i] = KeyValue{key, value}
+	}
+	rv.Set(reflect.ValueOf(kvs))
+	return nil
+}
+
+func orderedValue(cfg *Config, fieldAst interface{}, path *keyPath) (interface{}, error) {
+	switch av := fieldAst.(type) {
+	case *ast.Table:
+		var kvs []KeyValue
+		err := unmarshalKeyValues(cfg, reflect.ValueOf(&kvs).Elem(), av, path)
+		return kvs, err
+	case []*ast.Table:
+		list := make([]interface{}, len(av))
+		for i, t := range av {
+			var err error
+			if list[i], err = orderedValue(cfg, t, path.elem(i)); err != nil {
+				return nil, err
+			}
+		}
+		return list, nil
+	case *ast.KeyValue:
+		if t, ok := av.Value.(*ast.Table); ok {
+			return orderedValue(cfg, t, path)
+		}
+		var v interface{}
+		err := setValue(cfg, reflect.ValueOf(&v).Elem(), av.Value, path)
+		return v, err
+	default:
+		panic(fmt.Sprintf("BUG: unhandled AST node type %T", av))
+	}
+}
+
+// keyConflictError reports that two keys of t, which is at the given path, match the same
+// struct field.
+func keyConflictError(cfg *Config, key1, key2, target string, t *ast.Table, path *keyPath) error {
+	line1, line2 := fieldLineNumber(t.Fields[key1]), fieldLineNumber(t.Fields[key2])
+	if line1 < line2 || (line1 == line2 && key1 < key2) {
+		key1, key2 = key2, key1
+		line1, line2 = line2, line1
+	}
+	err := fmt.Errorf("key `%s' is in conflict with key `%s' in line %d (both match %s)", key1, key2, line2, target)
+	return cfg.valueError(path.child(key1), t.Fields[key1], "", err)
+}
+
+// valueError returns err as a *LineError for the value at path, which is defined by the
+// AST node av. Errors which already have a location are returned unchanged.
+func (cfg *Config) valueError(path *keyPath, av interface{}, structField string, err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := err.(*LineError); ok {
+		return err
+	}
+	pos := Position{Line: fieldLineNumber(av)}
+	if offset := nodePos(av); offset >= 0 {
+		pos = cfg.source.position(pos.Line, offset)
+	}
+	return &LineError{Line: pos.Line, Column: pos.Column, Path: path.String(), StructField: structField, Err: err}
+}
+
+// nodePos returns the offset of the value defined by av, or -1 for implicitly created tables.
+func nodePos(av interface{}) int {
+	switch av := av.(type) {
+	case *ast.KeyValue:
+		return av.Value.Pos()
+	case *ast.Table:
+		if av.Position == (ast.Position{}) {
+			return -1
+		}
+		return av.Position.Begin
+	case []*ast.Table:
+		return nodePos(av[0])
+	default:
+		panic(fmt.Sprintf("BUG: unhandled node type %T", av))
+	}
+}
+
+// keyPath is the key path of a value being decoded. It is a linked list from the value up
+// to the document, which is represented by a nil *keyPath.
+type keyPath struct {
+	parent *keyPath
+	key    string
+	index  int // array index, or -1 for keys
+}
+
+func (p *keyPath) child(key string) *keyPath {
+	return &keyPath{parent: p, key: key, index: -1}
+}
+
+func (p *keyPath) elem(i int) *keyPath {
+	return &keyPath{parent: p, index: i}
+}
+
+// keys returns the keys of p, leaving out array indices.
+func (p *keyPath) keys() []string {
+	if p == nil {
+		return nil
+	}
+	keys := p.parent.keys()
+	if p.index < 0 {
+		keys = append(keys, p.key)
+	}
+	return keys
+}
+
+// String returns the path in the form used by Positions.
+func (p *keyPath) String() string {
+	if p == nil {
+		return ""
+	}
+	parent := p.parent.String()
+	switch {
+	case p.index >= 0:
+		return parent + "[" + strconv.Itoa(p.index) + "]"
+	case parent == "":
+		return canonicalKey(p.key)
+	default:
+		return parent + "." + canonicalKey(p.key)
+	}
+}
+
 func fieldLineNumber(fieldAst interface{}) int {
 	switch av := fieldAst.(type) {
 	case *ast.KeyValue:
@@ -190,17 +760,57 @@ func fieldLineNumber(fieldAst interface{}) int {
 	}
 }
 
+// unmarshalStructField is like unmarshalField, but applies the options given in the
+// struct tag of the field.
+func unmarshalStructField(cfg *Config, rv reflect.Value, info fieldInfo, fieldAst interface{}, path *keyPath) error {
+	if kv, ok := fieldAst.(*ast.KeyValue); ok {
+		if layout, ok := info.opts.lookup(tagLayout); ok {
+			if handled, err := setTimeLayout(rv, kv.Value, layout); handled {
+				return err
+			}
+		}
+		if str, ok := kv.Value.(*ast.String); ok && info.opts.has(tagString) {
+			if handled, err := setQuoted(rv, str); handled {
+				return err
+			}
+		}
+	}
+	return unmarshalField(cfg, rv, fieldAst, path)
+}
+
 // unmarshalField is called for struct fields and map entries.
-// rv is the value that should be set.
-func unmarshalField(cfg *Config, rv reflect.Value, fieldAst interface{}) error {
+// rv is the value that should be set, path is the key path of fieldAst.
+func unmarshalField(cfg *Config, rv reflect.Value, fieldAst interface{}, path *keyPath) error {
+	if cfg.hintedNodes != nil && isEface(rv) {
+		if typ := hintedType(cfg, fieldAst); typ != nil {
+			v := reflect.New(typ).Elem()
+			if err := unmarshalField(cfg, v, fieldAst, path); err != nil {
+				return err
+			}
+			rv.Set(v)
+			return nil
+		}
+	}
+	if setPrimitive(cfg, rv, fieldAst, path) {
+		cfg.markDecoded(path, true)
+		return nil
+	}
+	if handled, err := setRawMessage(cfg, rv, fieldAst); handled {
+		cfg.markDecoded(path, true)
+		return err
+	}
+	cfg.markDecoded(path, false)
 	switch av := fieldAst.(type) {
 	case *ast.KeyValue:
-		return setValue(cfg, rv, av.Value)
+		return setValue(cfg, rv, av.Value, path)
 	case *ast.Table:
-		return unmarshalTable(cfg, rv, av, false)
+		if handled, err := setDecodeHook(cfg, indirect(rv), av); handled {
+			return err
+		}
+		return unmarshalTable(cfg, rv, av, path, false)
 	case []*ast.Table:
 		rv = indirect(rv)
-		if handled, err := setUnmarshaler(cfg, rv, fieldAst); handled {
+		if handled, err := setUnmarshaler(cfg, rv, fieldAst, path); handled {
 			return err
 		}
 		var slice reflect.Value
@@ -210,16 +820,14 @@ func unmarshalField(cfg *Config, rv reflect.Value, fieldAst interface{}) error {
 		case isEface(rv):
 			slice = reflect.ValueOf(make([]interface{}, len(av)))
 		default:
-			return &unmarshalTypeError{"array table", "slice", rv.Type()}
+			return &UnmarshalTypeError{"array table", "slice", rv.Type()}
 		}
 		for i, tbl := range av {
-			vv := reflect.New(slice.Type().Elem()).Elem()
-			if err := unmarshalTable(cfg, vv, tbl, false); err != nil {
+			if err := unmarshalField(cfg, slice.Index(i), tbl, path.elem(i)); err != nil {
 				return err
 			}
-			slice.Index(i).Set(vv)
 		}
-		rv.Set(slice)
+		setSlice(cfg, rv, slice)
 	default:
 		panic(fmt.Sprintf("BUG: unhandled AST node type %T", av))
 	}
@@ -252,14 +860,25 @@ func unmarshalMapKey(typ reflect.Type, key string) (reflect.Value, error) {
 	return rv, nil
 }
 
-func setValue(cfg *Config, lhs reflect.Value, val ast.Value) error {
+func setValue(cfg *Config, lhs reflect.Value, val ast.Value, path *keyPath) error {
 	lhs = indirect(lhs)
-	if handled, err := setUnmarshaler(cfg, lhs, val); handled {
+	if handled, err := setDecodeHook(cfg, lhs, val); handled {
+		return err
+	}
+	if handled, err := setUnmarshaler(cfg, lhs, val, path); handled {
+		return err
+	}
+	if handled, err := setBig(lhs, val); handled {
 		return err
 	}
 	if handled, err := setTextUnmarshaler(lhs, val); handled {
 		return err
 	}
+	if cfg.WeaklyTypedInput {
+		if handled, err := setWeak(cfg, lhs, val, path); handled {
+			return err
+		}
+	}
 	switch v := val.(type) {
 	case *ast.Integer:
 		return setInt(lhs, v)
@@ -272,9 +891,9 @@ func setValue(cfg *Config, lhs reflect.Value, val ast.Value) error {
 	case *ast.Datetime:
 		return setDatetime(lhs, v)
 	case *ast.Array:
-		return setArray(cfg, lhs, v)
+		return setArray(cfg, lhs, v, path)
 	case *ast.Table:
-		return unmarshalTable(cfg, lhs, v, false)
+		return unmarshalTable(cfg, lhs, v, path, false)
 	default:
 		panic(fmt.Sprintf("BUG: unhandled node type %T", v))
 	}
@@ -290,37 +909,127 @@ func indirect(rv reflect.Value) reflect.Value {
 	return rv
 }
 
-func setUnmarshaler(cfg *Config, lhs reflect.Value, av interface{}) (bool, error) {
-	if lhs.CanAddr() {
-		if u, ok := lhs.Addr().Interface().(UnmarshalerRec); ok {
-			err := u.UnmarshalTOML(func(v interface{}) error {
-				return unmarshalTableOrValue(cfg, reflect.ValueOf(v), av)
-			})
-			return true, err
+var (
+	unmarshalerRecType = reflect.TypeOf((*UnmarshalerRec)(nil)).Elem()
+	unmarshalerType    = reflect.TypeOf((*Unmarshaler)(nil)).Elem()
+)
+
+func setUnmarshaler(cfg *Config, lhs reflect.Value, av interface{}, path *keyPath) (bool, error) {
+	switch {
+	case lhs.CanAddr() && lhs.Kind() != reflect.Interface:
+		return callUnmarshaler(cfg, lhs.Addr(), av, path)
+	case lhs.Kind() == reflect.Interface && !lhs.IsNil():
+		// The interface holds a value which may implement the unmarshaler interfaces.
+		held := lhs.Elem()
+		if held.Kind() == reflect.Ptr {
+			if held.IsNil() {
+				return false, nil
+			}
+			return callUnmarshaler(cfg, held, av, path)
+		}
+		if !hasUnmarshaler(held.Type()) || !lhs.CanSet() {
+			return false, nil
 		}
-		if u, ok := lhs.Addr().Interface().(Unmarshaler); ok {
-			return true, u.UnmarshalTOML(unmarshalerSource(av))
+		tmp := reflect.New(held.Type())
+		tmp.Elem().Set(held)
+		handled, err := callUnmarshaler(cfg, tmp, av, path)
+		lhs.Set(tmp.Elem())
+		return handled, err
+	case !lhs.CanAddr() && isReference(lhs.Kind()) && hasUnmarshaler(lhs.Type()):
+		// The value can't be stored back, but the unmarshaler can still modify what
+		// a reference type like a map refers to.
+		tmp := reflect.New(lhs.Type())
+		tmp.Elem().Set(lhs)
+		return callUnmarshaler(cfg, tmp, av, path)
+	}
+	return false, nil
+}
+
+// isReference reports whether values of kind k refer to data which is shared by copies.
+func isReference(k reflect.Kind) bool {
+	return k == reflect.Map || k == reflect.Ptr || k == reflect.Slice
+}
+
+// hasUnmarshaler reports whether *typ implements UnmarshalerRec or Unmarshaler.
+func hasUnmarshaler(typ reflect.Type) bool {
+	ptr := reflect.PtrTo(typ)
+	return ptr.Implements(unmarshalerRecType) || ptr.Implements(unmarshalerType)
+}
+
+// callUnmarshaler invokes the unmarshaler methods of ptr.
+func callUnmarshaler(cfg *Config, ptr reflect.Value, av interface{}, path *keyPath) (bool, error) {
+	if u, ok := ptr.Interface().(UnmarshalerRec); ok {
+		cfg.markDecoded(path, true)
+		err := u.UnmarshalTOML(func(v interface{}) error {
+			return unmarshalTableOrValue(cfg, reflect.ValueOf(v), av, path)
+		})
+		return true, err
+	}
+	if u, ok := ptr.Interface().(Unmarshaler); ok {
+		cfg.markDecoded(path, true)
+		source, err := unmarshalerSource(av, path)
+		if err != nil {
+			return true, err
 		}
+		return true, u.UnmarshalTOML(source)
 	}
 	return false, nil
 }
 
-func unmarshalerSource(av interface{}) []byte {
-	var source []byte
+// unmarshalerSource returns the source text of av for Unmarshaler. If the source was
+// discarded, the canonical form is returned instead, with headers for the elements of
+// array tables.
+func unmarshalerSource(av interface{}, path *keyPath) ([]byte, error) {
 	switch av := av.(type) {
 	case []*ast.Table:
+		if len(av) > 0 && av[0].Data == nil {
+			var buf bytes.Buffer
+			keys := path.keys()
+			for _, tab := range av {
+				canonicalHeader(&buf, "[["+canonicalPath(keys)+"]]")
+				if err := canonicalTable(&buf, tab, keys); err != nil {
+					return nil, err
+				}
+			}
+			return buf.Bytes(), nil
+		}
+		var source []byte
 		for i, tab := range av {
 			source = append(source, tab.Source()...)
 			if i != len(av)-1 {
 				source = append(source, '\n')
 			}
 		}
+		return source, nil
+	case *ast.Table:
+		return tableSource(av)
 	case ast.Value:
-		source = []byte(av.Source())
+		return []byte(valueSource(av)), nil
 	default:
 		panic(fmt.Sprintf("BUG: unhandled node type %T", av))
 	}
-	return source
+}
+
+// tableSource returns the source text of t. If the source was discarded, the
+// canonical form of its contents is returned instead.
+func tableSource(t *ast.Table) ([]byte, error) {
+	if t.Data != nil || t.Type == ast.TableTypeInline {
+		return []byte(valueSource(t)), nil
+	}
+	return CanonicalizeTable(t)
+}
+
+// valueSource returns the source text of v. If the source was discarded, the
+// canonical form of v is returned instead.
+func valueSource(v ast.Value) string {
+	if source := v.Source(); source != "" {
+		return source
+	}
+	var buf bytes.Buffer
+	if canonicalInline(&buf, v) != nil {
+		return ""
+	}
+	return buf.String()
 }
 
 func setTextUnmarshaler(lhs reflect.Value, val ast.Value) (bool, error) {
@@ -334,9 +1043,9 @@ func setTextUnmarshaler(lhs reflect.Value, val ast.Value) (bool, error) {
 	var data string
 	switch val := val.(type) {
 	case *ast.Array:
-		return true, &unmarshalTypeError{"array", "", lhs.Type()}
+		return true, &UnmarshalTypeError{"array", "", lhs.Type()}
 	case *ast.Table:
-		return true, &unmarshalTypeError{"table", "", lhs.Type()}
+		return true, &UnmarshalTypeError{"table", "", lhs.Type()}
 	case *ast.String:
 		data = val.Value
 	case *ast.Integer:
@@ -344,7 +1053,7 @@ func setTextUnmarshaler(lhs reflect.Value, val ast.Value) (bool, error) {
 	case *ast.Float:
 		data = val.Value
 	default:
-		data = val.Source()
+		data = valueSource(val)
 	}
 	return true, u.UnmarshalText([]byte(data))
 }
@@ -360,7 +1069,7 @@ func setInt(fv reflect.Value, v *ast.Integer) error {
 		fv.SetInt(i)
 	case k >= reflect.Uint && k <= reflect.Uintptr:
 		if v.Sign() < 0 {
-			return &unmarshalTypeError{"integer < 0", "", fv.Type()}
+			return &UnmarshalTypeError{"integer < 0", "", fv.Type()}
 		}
 		i, err := strconv.ParseUint(v.Value, 0, int(fv.Type().Size()*8))
 		if err != nil {
@@ -368,17 +1077,28 @@ func setInt(fv reflect.Value, v *ast.Integer) error {
 		}
 		fv.SetUint(i)
 	case isEface(fv):
-		i, err := strconv.ParseInt(v.Value, 0, 64)
-		if err != nil {
-			return convertNumError(reflect.Int64, err)
-		}
-		fv.Set(reflect.ValueOf(i))
+		fv.Set(reflect.ValueOf(ifaceInt(v)))
 	default:
-		return &unmarshalTypeError{"integer", "", fv.Type()}
+		return &UnmarshalTypeError{"integer", "", fv.Type()}
 	}
 	return nil
 }
 
+// ifaceInt returns the value of v for interface{} targets. Integers are int64 if they
+// fit, otherwise uint64 or *big.Int.
+func ifaceInt(v *ast.Integer) interface{} {
+	if i, err := strconv.ParseInt(v.Value, 0, 64); err == nil {
+		return i
+	}
+	if v.Sign() >= 0 {
+		if u, err := strconv.ParseUint(strings.TrimPrefix(v.Value, "+"), 0, 64); err == nil {
+			return u
+		}
+	}
+	i, _ := new(big.Int).SetString(v.Value, 0)
+	return i
+}
+
 func setFloat(fv reflect.Value, v *ast.Float) error {
 	f, err := v.Float()
 	if err != nil {
@@ -387,13 +1107,13 @@ func setFloat(fv reflect.Value, v *ast.Float) error {
 	switch {
 	case fv.Kind() == reflect.Float32 || fv.Kind() == reflect.Float64:
 		if fv.OverflowFloat(f) {
-			return &overflowError{fv.Kind(), v.Value}
+			return &OverflowError{fv.Kind(), v.Value}
 		}
 		fv.SetFloat(f)
 	case isEface(fv):
 		fv.Set(reflect.ValueOf(f))
 	default:
-		return &unmarshalTypeError{"float", "", fv.Type()}
+		return &UnmarshalTypeError{"float", "", fv.Type()}
 	}
 	return nil
 }
@@ -405,7 +1125,7 @@ func setString(fv reflect.Value, v *ast.String) error {
 	case isEface(fv):
 		fv.Set(reflect.ValueOf(v.Value))
 	default:
-		return &unmarshalTypeError{"string", "", fv.Type()}
+		return &UnmarshalTypeError{"string", "", fv.Type()}
 	}
 	return nil
 }
@@ -418,7 +1138,7 @@ func setBoolean(fv reflect.Value, v *ast.Boolean) error {
 	case isEface(fv):
 		fv.Set(reflect.ValueOf(b))
 	default:
-		return &unmarshalTypeError{"boolean", "", fv.Type()}
+		return &UnmarshalTypeError{"boolean", "", fv.Type()}
 	}
 	return nil
 }
@@ -429,13 +1149,66 @@ func setDatetime(rv reflect.Value, v *ast.Datetime) error {
 		return err
 	}
 	if !timeType.AssignableTo(rv.Type()) {
-		return &unmarshalTypeError{"datetime", "", rv.Type()}
+		return &UnmarshalTypeError{"datetime", "", rv.Type()}
 	}
 	rv.Set(reflect.ValueOf(t))
 	return nil
 }
 
-func setArray(cfg *Config, rv reflect.Value, v *ast.Array) error {
+// setTimeLayout parses a string or datetime into a time.Time field according to layout.
+func setTimeLayout(rv reflect.Value, v ast.Value, layout string) (bool, error) {
+	var text string
+	switch v := v.(type) {
+	case *ast.String:
+		text = v.Value
+	case *ast.Datetime:
+		text = v.Value
+	default:
+		return false, nil
+	}
+	typ := rv.Type()
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if !timeType.AssignableTo(typ) {
+		return false, nil
+	}
+	t, err := time.Parse(layout, text)
+	if err != nil {
+		return true, err
+	}
+	indirect(rv).Set(reflect.ValueOf(t))
+	return true, nil
+}
+
+// setQuoted sets a numeric or boolean field from a quoted value.
+func setQuoted(rv reflect.Value, v *ast.String) (bool, error) {
+	typ := rv.Type()
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	text := strings.TrimSpace(v.Value)
+	switch k := typ.Kind(); {
+	case k >= reflect.Int && k <= reflect.Uintptr:
+		if _, err := strconv.ParseInt(text, 0, 64); err != nil && err.(*strconv.NumError).Err != strconv.ErrRange {
+			return true, &UnmarshalTypeError{"string", "quoted integer", typ}
+		}
+		return true, setInt(indirect(rv), &ast.Integer{Position: v.Position, Value: text})
+	case k == reflect.Float32 || k == reflect.Float64:
+		if _, err := strconv.ParseFloat(text, 64); err != nil {
+			return true, &UnmarshalTypeError{"string", "quoted float", typ}
+		}
+		return true, setFloat(indirect(rv), &ast.Float{Position: v.Position, Value: text})
+	case k == reflect.Bool:
+		if text != "true" && text != "false" {
+			return true, &UnmarshalTypeError{"string", "quoted boolean", typ}
+		}
+		return true, setBoolean(indirect(rv), &ast.Boolean{Position: v.Position, Value: text})
+	}
+	return false, nil
+}
+
+func setArray(cfg *Config, rv reflect.Value, v *ast.Array, path *keyPath) error {
 	var slicetyp reflect.Type
 	switch {
 	case rv.Kind() == reflect.Slice:
@@ -443,28 +1216,35 @@ func setArray(cfg *Config, rv reflect.Value, v *ast.Array) error {
 	case isEface(rv):
 		slicetyp = reflect.SliceOf(rv.Type())
 	default:
-		return &unmarshalTypeError{"array", "slice", rv.Type()}
+		return &UnmarshalTypeError{"array", "slice", rv.Type()}
 	}
 
 	if len(v.Value) == 0 {
 		// Ensure defined slices are always set to a non-nil value.
-		rv.Set(reflect.MakeSlice(slicetyp, 0, 0))
+		setSlice(cfg, rv, reflect.MakeSlice(slicetyp, 0, 0))
 		return nil
 	}
 
 	slice := reflect.MakeSlice(slicetyp, len(v.Value), len(v.Value))
-	typ := slicetyp.Elem()
 	for i, vv := range v.Value {
-		tmp := reflect.New(typ).Elem()
-		if err := setValue(cfg, tmp, vv); err != nil {
+		if err := setValue(cfg, slice.Index(i), vv, path.elem(i)); err != nil {
 			return err
 		}
-		slice.Index(i).Set(tmp)
 	}
-	rv.Set(slice)
+	setSlice(cfg, rv, slice)
 	return nil
 }
 
+// setSlice assigns slice to rv. If cfg.AppendSlices is set, the elements are
+// appended to the existing content of rv instead.
+func setSlice(cfg *Config, rv, slice reflect.Value) {
+	if cfg.AppendSlices && rv.Kind() == reflect.Slice && !rv.IsNil() {
+		rv.Set(reflect.AppendSlice(rv, slice))
+		return
+	}
+	rv.Set(slice)
+}
+
 func isEface(rv reflect.Value) bool {
 	return rv.Kind() == reflect.Interface && rv.Type().NumMethod() == 0
 }
@@ -1,6 +1,8 @@
 package toml
 
 import (
+	"bytes"
+	"fmt"
 	"testing"
 )
 
@@ -16,3 +18,20 @@ func BenchmarkUnmarshal(b *testing.B) {
 		}
 	}
 }
+
+func BenchmarkParseArrayTables(b *testing.B) {
+	var buf bytes.Buffer
+	for i := 0; i < 1000; i++ {
+		fmt.Fprintf(&buf, "[[servers]]\nname = \"server\"\nhost = \"10.0.0.%d\"\nenabled = true\n\n", i%256)
+	}
+	data := buf.Bytes()
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.SetBytes(int64(len(data)))
+
+	for i := 0; i < b.N; i++ {
+		if _, err := Parse(data); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -3,15 +3,21 @@ package toml
 import (
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"math"
 	"math/big"
 	"path/filepath"
 	"reflect"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
+	"testing/iotest"
 	"time"
 
 	"github.com/kylelemons/godebug/pretty"
+	"github.com/naoina/toml/ast"
 )
 
 func loadTestData(file string) []byte {
@@ -30,6 +36,11 @@ func mustTime(tm time.Time, err error) time.Time {
 	return tm
 }
 
+func bigInt(s string) *big.Int {
+	i, _ := new(big.Int).SetString(s, 10)
+	return i
+}
+
 type Name struct {
 	First string
 	Last  string
@@ -316,7 +327,7 @@ func testUnmarshal(t *testing.T, testcases []testcase) {
 		}
 
 		err := Unmarshal([]byte(test.data), val)
-		if !reflect.DeepEqual(err, test.err) {
+		if !reflect.DeepEqual(withoutLocation(err), test.err) {
 			t.Errorf("Error mismatch for input:\n%s\ngot:  %+v\nwant: %+v", test.data, err, test.err)
 		}
 		if err == nil && !reflect.DeepEqual(val, test.expect) {
@@ -325,6 +336,21 @@ func testUnmarshal(t *testing.T, testcases []testcase) {
 	}
 }
 
+// withoutLocation returns a copy of err without the column, key path and the source line
+// and expected tokens of syntax errors, which are checked by TestErrorLocation and
+// TestSyntaxErrorSource.
+func withoutLocation(err error) error {
+	if lerr, ok := err.(*LineError); ok {
+		c := *lerr
+		c.Column, c.Path = 0, ""
+		if serr, ok := c.Err.(*SyntaxError); ok {
+			c.Err = &SyntaxError{Msg: serr.Msg}
+		}
+		return &c
+	}
+	return err
+}
+
 func TestUnmarshal_WithString(t *testing.T) {
 	type testStruct struct {
 		Str      string
@@ -427,35 +453,35 @@ func TestUnmarshal_WithInteger(t *testing.T) {
 		{`intval = 0b01100110`, nil, &testStruct{102}},
 		{`intval = 0b011_00110`, nil, &testStruct{102}},
 		// invalid _
-		{`intval = _1_000`, lineError(1, errParse), &testStruct{}},
-		{`intval = 1_000_`, lineError(1, errParse), &testStruct{}},
-		{`intval = 0x_01`, lineError(1, errParse), &testStruct{}},
-		{`intval = 0x01_`, lineError(1, errParse), &testStruct{}},
-		{`intval = 0o_01`, lineError(1, errParse), &testStruct{}},
-		{`intval = 0o01_`, lineError(1, errParse), &testStruct{}},
-		{`intval = 0b_01`, lineError(1, errParse), &testStruct{}},
-		{`intval = 0b01_`, lineError(1, errParse), &testStruct{}},
+		{`intval = _1_000`, lineError(1, ErrSyntax), &testStruct{}},
+		{`intval = 1_000_`, lineError(1, ErrSyntax), &testStruct{}},
+		{`intval = 0x_01`, lineError(1, ErrSyntax), &testStruct{}},
+		{`intval = 0x01_`, lineError(1, ErrSyntax), &testStruct{}},
+		{`intval = 0o_01`, lineError(1, ErrSyntax), &testStruct{}},
+		{`intval = 0o01_`, lineError(1, ErrSyntax), &testStruct{}},
+		{`intval = 0b_01`, lineError(1, ErrSyntax), &testStruct{}},
+		{`intval = 0b01_`, lineError(1, ErrSyntax), &testStruct{}},
 		// sign unsupported for non-decimal ints
-		{`intval = +0x01`, lineError(1, errParse), &testStruct{}},
-		{`intval = +0o01`, lineError(1, errParse), &testStruct{}},
-		{`intval = +0b01`, lineError(1, errParse), &testStruct{}},
-		{`intval = -0x01`, lineError(1, errParse), &testStruct{}},
-		{`intval = -0o0`, lineError(1, errParse), &testStruct{}},
-		{`intval = -0b011_00110`, lineError(1, errParse), &testStruct{}},
+		{`intval = +0x01`, lineError(1, ErrSyntax), &testStruct{}},
+		{`intval = +0o01`, lineError(1, ErrSyntax), &testStruct{}},
+		{`intval = +0b01`, lineError(1, ErrSyntax), &testStruct{}},
+		{`intval = -0x01`, lineError(1, ErrSyntax), &testStruct{}},
+		{`intval = -0o0`, lineError(1, ErrSyntax), &testStruct{}},
+		{`intval = -0b011_00110`, lineError(1, ErrSyntax), &testStruct{}},
 		// overflow
 		{
 			data:   `intval = 9223372036854775808`,
-			err:    lineErrorField(1, "toml.testStruct.Intval", &overflowError{reflect.Int64, "9223372036854775808"}),
+			err:    lineErrorField(1, "toml.testStruct.Intval", &OverflowError{reflect.Int64, "9223372036854775808"}),
 			expect: &testStruct{},
 		},
 		{
 			data:   `intval = +9223372036854775808`,
-			err:    lineErrorField(1, "toml.testStruct.Intval", &overflowError{reflect.Int64, "+9223372036854775808"}),
+			err:    lineErrorField(1, "toml.testStruct.Intval", &OverflowError{reflect.Int64, "+9223372036854775808"}),
 			expect: &testStruct{},
 		},
 		{
 			data:   `intval = -9223372036854775809`,
-			err:    lineErrorField(1, "toml.testStruct.Intval", &overflowError{reflect.Int64, "-9223372036854775809"}),
+			err:    lineErrorField(1, "toml.testStruct.Intval", &OverflowError{reflect.Int64, "-9223372036854775809"}),
 			expect: &testStruct{},
 		},
 	})
@@ -476,13 +502,13 @@ func TestUnmarshal_WithUint(t *testing.T) {
 		// error when negative
 		{
 			data:   `u64 = -12`,
-			err:    lineErrorField(1, "toml.testStruct.U64", &unmarshalTypeError{"integer < 0", "", reflect.TypeOf(uint64(0))}),
+			err:    lineErrorField(1, "toml.testStruct.U64", &UnmarshalTypeError{"integer < 0", "", reflect.TypeOf(uint64(0))}),
 			expect: &testStruct{},
 		},
 		// overflow
 		{
 			data:   `u8 = 256`,
-			err:    lineErrorField(1, "toml.testStruct.U8", &overflowError{reflect.Uint8, "256"}),
+			err:    lineErrorField(1, "toml.testStruct.U8", &OverflowError{reflect.Uint8, "256"}),
 			expect: &testStruct{},
 		},
 	})
@@ -528,16 +554,16 @@ func TestUnmarshal_WithFloat(t *testing.T) {
 		{`floatval = 1e1_00`, nil, &testStruct{1e100}},
 		{`floatval = 1e02`, nil, &testStruct{1e2}},
 		// invalid _
-		{`floatval = _1e1_00`, lineError(1, errParse), &testStruct{}},
-		{`floatval = 1e1_00_`, lineError(1, errParse), &testStruct{}},
+		{`floatval = _1e1_00`, lineError(1, ErrSyntax), &testStruct{}},
+		{`floatval = 1e1_00_`, lineError(1, ErrSyntax), &testStruct{}},
 		// invalid encodings from spec
-		{`floatval = .7`, lineError(1, errParse), &testStruct{}},
-		{`floatval = 7.`, lineError(1, errParse), &testStruct{}},
-		{`floatval = 3.e+20`, lineError(1, errParse), &testStruct{}},
+		{`floatval = .7`, lineError(1, ErrSyntax), &testStruct{}},
+		{`floatval = 7.`, lineError(1, ErrSyntax), &testStruct{}},
+		{`floatval = 3.e+20`, lineError(1, ErrSyntax), &testStruct{}},
 		// non-decimal base unsupported
-		{`floatval = 0xff.0`, lineError(1, errParse), &testStruct{}},
-		{`floatval = 0o71.0`, lineError(1, errParse), &testStruct{}},
-		{`floatval = 0b01.0`, lineError(1, errParse), &testStruct{}},
+		{`floatval = 0xff.0`, lineError(1, ErrSyntax), &testStruct{}},
+		{`floatval = 0o71.0`, lineError(1, ErrSyntax), &testStruct{}},
+		{`floatval = 0b01.0`, lineError(1, ErrSyntax), &testStruct{}},
 	})
 }
 
@@ -691,10 +717,10 @@ func TestUnmarshal_WithArray(t *testing.T) {
 		{string(loadTestData("unmarshal-array-5.toml")), nil, &arrays{Ints: []int{1, 2, 3}}},
 		{string(loadTestData("unmarshal-array-6.toml")), nil, &arrays{Ints: []int{1, 2, 3}}},
 		// parse errors
-		{`ints = [ , ]`, lineError(1, errParse), &arrays{}},
-		{`ints = [ , 1 ]`, lineError(1, errParse), &arrays{}},
-		{`ints = [ 1 2 ]`, lineError(1, errParse), &arrays{}},
-		{`ints = [ 1 , , 2 ]`, lineError(1, errParse), &arrays{}},
+		{`ints = [ , ]`, lineError(1, ErrSyntax), &arrays{}},
+		{`ints = [ , 1 ]`, lineError(1, ErrSyntax), &arrays{}},
+		{`ints = [ 1 2 ]`, lineError(1, ErrSyntax), &arrays{}},
+		{`ints = [ 1 , , 2 ]`, lineError(1, ErrSyntax), &arrays{}},
 	})
 }
 
@@ -905,12 +931,57 @@ d = 2`, nil,
 			err:    lineError(3, errInlineTableCommaRequired),
 			expect: &testStruct{},
 		},
-		{`[]`, lineError(1, errParse), &testStruct{}},
-		{`[a.]`, lineError(1, errParse), &testStruct{}},
-		{`[a..b]`, lineError(1, errParse), &testStruct{}},
-		{`[.b]`, lineError(1, errParse), &testStruct{}},
-		{`[.]`, lineError(1, errParse), &testStruct{}},
-		{` = "no key name" # not allowed`, lineError(1, errParse), &testStruct{}},
+		{
+			data:   "a = 2021-02-30T00:00:00Z",
+			err:    lineError(1, fmt.Errorf("invalid datetime 2021-02-30T00:00:00Z for key `a': day out of range")),
+			expect: &testStruct{},
+		},
+		{
+			data:   "\n[table]\na = [2021-13-01]",
+			err:    lineError(3, fmt.Errorf("invalid datetime 2021-13-01 for key `a': month out of range")),
+			expect: &testStruct{},
+		},
+		{
+			data:   "a = 24:00:00",
+			err:    lineError(1, fmt.Errorf("invalid datetime 24:00:00 for key `a': hour out of range")),
+			expect: &testStruct{},
+		},
+		{
+			data:   "a = 1979-05-27T07:32:00+25:00",
+			err:    lineError(1, fmt.Errorf("invalid datetime 1979-05-27T07:32:00+25:00 for key `a': time zone offset out of range")),
+			expect: &testStruct{},
+		},
+		{
+			data:   "a = 1\nb = \"\xe2\x28\xa1\"",
+			err:    lineError(2, fmt.Errorf("invalid UTF-8 at byte offset 11")),
+			expect: &testStruct{},
+		},
+		{
+			data:   "a = 1 # \x01",
+			err:    lineError(1, &rawControlError{'\x01'}),
+			expect: &testStruct{},
+		},
+		{
+			data:   "a = 1\n\x7f",
+			err:    lineError(2, &rawControlError{'\x7f'}),
+			expect: &testStruct{},
+		},
+		{
+			data:   "a = 1\nb = \"x\\uD800\"",
+			err:    lineError(2, fmt.Errorf("invalid escape \\uD800: not a Unicode scalar value")),
+			expect: &testStruct{},
+		},
+		{
+			data:   "a = \"\"\"\n\\U00110000\"\"\"",
+			err:    lineError(2, fmt.Errorf("invalid escape \\U00110000: not a Unicode scalar value")),
+			expect: &testStruct{},
+		},
+		{`[]`, lineError(1, ErrSyntax), &testStruct{}},
+		{`[a.]`, lineError(1, ErrSyntax), &testStruct{}},
+		{`[a..b]`, lineError(1, ErrSyntax), &testStruct{}},
+		{`[.b]`, lineError(1, ErrSyntax), &testStruct{}},
+		{`[.]`, lineError(1, ErrSyntax), &testStruct{}},
+		{` = "no key name" # not allowed`, lineError(1, ErrSyntax), &testStruct{}},
 		{
 			data:   `ignored = "value"`,
 			err:    lineError(1, fmt.Errorf("field corresponding to `ignored' in toml.testIgnoredFieldStruct cannot be set through TOML")),
@@ -942,7 +1013,35 @@ func TestUnmarshal_WithEmbeddedStruct(t *testing.T) {
 	type TestEmbStructA struct {
 		A string
 	}
+	type TestEmbStructB struct {
+		B int
+		C int
+	}
+	type TestEmbStructC struct {
+		C int
+	}
+	type TestEmbStructTagged struct {
+		Name string `toml:"name"`
+	}
 	testUnmarshal(t, []testcase{
+		{
+			data: `name = "x"`,
+			expect: &struct {
+				TestEmbStructTagged
+				Name string
+			}{
+				Name: "x",
+			},
+		},
+		{
+			data: `a = "x"`,
+			expect: &struct {
+				TestEmbStructA
+				Name string `toml:"a"`
+			}{
+				Name: "x",
+			},
+		},
 		{
 			data: `a = "value"`,
 			expect: &struct {
@@ -961,6 +1060,60 @@ func TestUnmarshal_WithEmbeddedStruct(t *testing.T) {
 				A: "value",
 			},
 		},
+		{
+			data: "a = \"value\"\nb = 1\nc = 2",
+			expect: &struct {
+				TestEmbStructA
+				*TestEmbStructB
+				C int
+			}{
+				TestEmbStructA: TestEmbStructA{A: "value"},
+				TestEmbStructB: &TestEmbStructB{B: 1},
+				C:              2,
+			},
+		},
+		{
+			data: "b = 1",
+			expect: &struct {
+				TestEmbStructB
+				Other struct{ B int }
+			}{
+				TestEmbStructB: TestEmbStructB{B: 1},
+			},
+		},
+		{
+			data: "[test_emb_struct_a]\na = \"value\"",
+			expect: &struct {
+				TestEmbStructA
+			}{
+				TestEmbStructA: TestEmbStructA{A: "value"},
+			},
+		},
+		{
+			data: "name = \"a\"\nextra = \"b\"",
+			expect: &struct {
+				embeddedBase
+				Extra string
+			}{
+				embeddedBase: embeddedBase{Name: "a"},
+				Extra:        "b",
+			},
+		},
+		{
+			data: "embeddedbase = 1",
+			err:  lineError(1, fmt.Errorf("field corresponding to `embeddedbase' is not defined in struct { toml.embeddedBase }")),
+			expect: &struct {
+				embeddedBase
+			}{},
+		},
+		{
+			data: "c = 1",
+			err:  lineError(1, fmt.Errorf("field corresponding to `c' is not defined in struct { toml.TestEmbStructB; toml.TestEmbStructC }")),
+			expect: &struct {
+				TestEmbStructB
+				TestEmbStructC
+			}{},
+		},
 	})
 }
 
@@ -1134,6 +1287,36 @@ func TestUnmarshal_WithUnmarshaler(t *testing.T) {
 	}
 }
 
+func TestUnmarshal_WithUnmarshalerDiscardedSource(t *testing.T) {
+	type testStruct struct {
+		Title, MaxConn, Ports, Servers testUnmarshalerString
+		Table                          testUnmarshalerString
+		Arraytable                     testUnmarshalerString
+		InlineTable                    testUnmarshalerString
+		ArrayOfStruct                  testUnmarshalerString
+	}
+	data := loadTestData("unmarshal-unmarshaler.toml")
+	tbl, err := ParseReader(strings.NewReader(string(data)), DiscardSource())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var v testStruct
+	if err := UnmarshalTable(tbl, &v); err != nil {
+		t.Fatal(err)
+	}
+	v = testStruct{Table: v.Table, Arraytable: v.Arraytable}
+	expect := testStruct{
+		Table:      "Unmarshaled: name = \"alice\"\n",
+		Arraytable: "Unmarshaled: [[arraytable]]\nname = \"alice\"\n\n[[arraytable]]\nname = \"bob\"\n",
+	}
+	if !reflect.DeepEqual(v, expect) {
+		t.Error("diff:", pretty.Compare(v, expect))
+	}
+	if _, err := Parse([]byte(strings.TrimPrefix(string(v.Arraytable), "Unmarshaled: "))); err != nil {
+		t.Errorf("array table source doesn't parse: %v", err)
+	}
+}
+
 func TestUnmarshal_WithUnmarshalerForTopLevelStruct(t *testing.T) {
 	data := `title = "Alice's Adventures in Wonderland"
 author = "Lewis Carroll"
@@ -1261,6 +1444,55 @@ func TestUnmarshal_WithUnmarshalerRec(t *testing.T) {
 	}
 }
 
+func TestUnmarshal_WithUnmarshalerRecInInterface(t *testing.T) {
+	var v struct {
+		Value   interface{}
+		Pointer interface{}
+		Struct  interface{}
+	}
+	v.Value = testUnmarshalerRecString("")
+	v.Pointer = new(testUnmarshalerRecString)
+	v.Struct = testUnmarshalerRecStruct{}
+	input := `
+value = "str1"
+pointer = "str2"
+struct = {a = 1, b = 2}
+`
+	if err := Unmarshal([]byte(input), &v); err != nil {
+		t.Fatal("Unexpected error:", err)
+	}
+	if v.Value != testUnmarshalerRecString("Unmarshaled: str1") {
+		t.Errorf("wrong value for Value: %#v", v.Value)
+	}
+	if p, ok := v.Pointer.(*testUnmarshalerRecString); !ok || *p != "Unmarshaled: str2" {
+		t.Errorf("wrong value for Pointer: %#v", v.Pointer)
+	}
+	if v.Struct != (testUnmarshalerRecStruct{a: 1, b: 2}) {
+		t.Errorf("wrong value for Struct: %#v", v.Struct)
+	}
+}
+
+type testUnmarshalerMap map[string]string
+
+func (m *testUnmarshalerMap) UnmarshalTOML(data []byte) error {
+	(*m)["source"] = string(data)
+	return nil
+}
+
+func TestUnmarshal_WithUnmarshalerMapValue(t *testing.T) {
+	m := testUnmarshalerMap{}
+	tbl, err := Parse([]byte("a = 1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := UnmarshalTable(tbl, m); err != nil {
+		t.Fatal(err)
+	}
+	if m["source"] != "a = 1" {
+		t.Errorf("wrong value %#v", m)
+	}
+}
+
 func TestUnmarshal_WithMultibyteString(t *testing.T) {
 	type testStruct struct {
 		Name    string
@@ -1348,6 +1580,10 @@ foo = 1
 `,
 			expect: map[string]interface{}{"name": "evan", "foo": int64(1)},
 		},
+		{
+			data:   "a = 18446744073709551615\nb = -9223372036854775809\nc = 0xFFFF_FFFF_FFFF_FFFF_F",
+			expect: map[string]interface{}{"a": uint64(18446744073709551615), "b": bigInt("-9223372036854775809"), "c": bigInt("295147905179352825855")},
+		},
 		{
 			data: `[""]
 a = 1
@@ -1396,7 +1632,7 @@ bar = 2
 -129 = 2
 `,
 			expect: map[int8]int{1: 1},
-			err:    lineError(2, &overflowError{reflect.Int8, "-129"}),
+			err:    lineError(2, &OverflowError{reflect.Int8, "-129"}),
 		},
 	})
 }
@@ -1415,7 +1651,7 @@ func TestUnmarshal_WithQuotedKeyValue(t *testing.T) {
 		{data: `"\u2222" = 1`, expect: map[string]int{"\u2222": 1}},
 		{data: `"\"" = 1`, expect: map[string]int{"\"": 1}},
 		{data: `"" = 1`, expect: map[string]int{"": 1}},
-		{data: `'a' = 1`, expect: map[string]int{}, err: lineError(1, errParse)},
+		{data: `'a' = 1`, expect: map[string]int{}, err: lineError(1, ErrSyntax)},
 		// Inline tables:
 		{
 			data: `
@@ -1484,13 +1720,13 @@ func TestUnmarshal_WithInterface(t *testing.T) {
 	testUnmarshal(t, []testcase{
 		{data, nil, &exp},
 		// can't unmarshal into non-empty interface{}
-		{`v = "string"`, lineError(1, &unmarshalTypeError{"string", "", nonemptyIfType}), map[string]nonemptyIf{}},
-		{`v = 1`, lineError(1, &unmarshalTypeError{"integer", "", nonemptyIfType}), map[string]nonemptyIf{}},
-		{`v = 1.0`, lineError(1, &unmarshalTypeError{"float", "", nonemptyIfType}), map[string]nonemptyIf{}},
-		{`v = true`, lineError(1, &unmarshalTypeError{"boolean", "", nonemptyIfType}), map[string]nonemptyIf{}},
-		{`v = [1, 2]`, lineError(1, &unmarshalTypeError{"array", "slice", nonemptyIfType}), map[string]nonemptyIf{}},
-		{`[v]`, lineError(1, &unmarshalTypeError{"table", "struct or map", nonemptyIfType}), map[string]nonemptyIf{}},
-		{`[[v]]`, lineError(1, &unmarshalTypeError{"array table", "slice", nonemptyIfType}), map[string]nonemptyIf{}},
+		{`v = "string"`, lineError(1, &UnmarshalTypeError{"string", "", nonemptyIfType}), map[string]nonemptyIf{}},
+		{`v = 1`, lineError(1, &UnmarshalTypeError{"integer", "", nonemptyIfType}), map[string]nonemptyIf{}},
+		{`v = 1.0`, lineError(1, &UnmarshalTypeError{"float", "", nonemptyIfType}), map[string]nonemptyIf{}},
+		{`v = true`, lineError(1, &UnmarshalTypeError{"boolean", "", nonemptyIfType}), map[string]nonemptyIf{}},
+		{`v = [1, 2]`, lineError(1, &UnmarshalTypeError{"array", "slice", nonemptyIfType}), map[string]nonemptyIf{}},
+		{`[v]`, lineError(1, &UnmarshalTypeError{"table", "struct or map", nonemptyIfType}), map[string]nonemptyIf{}},
+		{`[[v]]`, lineError(1, &UnmarshalTypeError{"array table", "slice", nonemptyIfType}), map[string]nonemptyIf{}},
 	})
 }
 
@@ -1510,3 +1746,1183 @@ func TestUnmarshal_ErrorLine(t *testing.T) {
 		},
 	})
 }
+
+func TestUnmarshal_NormalizedKeyConflict(t *testing.T) {
+	type testStruct struct {
+		MaxConns int
+	}
+	testUnmarshal(t, []testcase{
+		{
+			data: `
+max_conns = 1
+maxConns = 2
+`,
+			err:    lineError(3, fmt.Errorf("key `maxConns' is in conflict with key `max_conns' in line 2 (both match field toml.testStruct.MaxConns)")),
+			expect: &testStruct{},
+		},
+		{
+			data:   `max_conns = 1`,
+			expect: &testStruct{MaxConns: 1},
+		},
+	})
+}
+
+func TestUnmarshal_FieldConflict(t *testing.T) {
+	type testStruct struct {
+		UserID int
+		UserId int
+	}
+	type testTagStruct struct {
+		A int `toml:"a"`
+		B int `toml:"a"`
+	}
+	type testIgnoredStruct struct {
+		Name  string `toml:"-"`
+		NAME_ string
+	}
+	testUnmarshal(t, []testcase{
+		{
+			data:   `user_id = 1`,
+			err:    lineError(1, &fieldConflictError{reflect.TypeOf(testStruct{}), "UserID", "UserId", "userid"}),
+			expect: &testStruct{},
+		},
+		{
+			data:   `a = 1`,
+			err:    lineError(1, &fieldConflictError{reflect.TypeOf(testTagStruct{}), "A", "B", "a"}),
+			expect: &testTagStruct{},
+		},
+		{
+			data:   `name = "x"`,
+			expect: &testIgnoredStruct{NAME_: "x"},
+		},
+	})
+}
+
+func TestUnmarshal_TimeLayout(t *testing.T) {
+	type testStruct struct {
+		Start time.Time  `toml:"start,layout=2006-01-02"`
+		End   *time.Time `toml:"end,layout=Jan 2 2006"`
+	}
+	end := time.Date(2021, 3, 4, 0, 0, 0, 0, time.UTC)
+	testUnmarshal(t, []testcase{
+		{
+			data: "start = 2020-01-02\nend = \"Mar 4 2021\"",
+			expect: &testStruct{
+				Start: time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC),
+				End:   &end,
+			},
+		},
+		{
+			data:   `end = "2021-03-04"`,
+			err:    lineErrorField(1, "toml.testStruct.End", &time.ParseError{Layout: "Jan 2 2006", Value: "2021-03-04", LayoutElem: "Jan", ValueElem: "2021-03-04", Message: ""}),
+			expect: &testStruct{},
+		},
+	})
+}
+
+func TestUnmarshal_QuotedValue(t *testing.T) {
+	type testStruct struct {
+		Int   int     `toml:",string"`
+		Uint  *uint8  `toml:",string"`
+		Float float64 `toml:",string"`
+		Bool  bool    `toml:",string"`
+		Str   string  `toml:",string"`
+		Plain int     `toml:",string"`
+	}
+	u := uint8(200)
+	testUnmarshal(t, []testcase{
+		{
+			data:   `int = "-12"` + "\n" + `uint = "200"` + "\n" + `float = "1.5"` + "\n" + `bool = "true"` + "\n" + `str = "x"` + "\n" + `plain = 3`,
+			expect: &testStruct{Int: -12, Uint: &u, Float: 1.5, Bool: true, Str: "x", Plain: 3},
+		},
+		{
+			data:   `uint = "300"`,
+			err:    lineErrorField(1, "toml.testStruct.Uint", &OverflowError{reflect.Uint8, "300"}),
+			expect: &testStruct{Uint: new(uint8)},
+		},
+		{
+			data:   `int = "twelve"`,
+			err:    lineErrorField(1, "toml.testStruct.Int", &UnmarshalTypeError{"string", "quoted integer", reflect.TypeOf(0)}),
+			expect: &testStruct{},
+		},
+		{
+			data:   `bool = "yes"`,
+			err:    lineErrorField(1, "toml.testStruct.Bool", &UnmarshalTypeError{"string", "quoted boolean", reflect.TypeOf(false)}),
+			expect: &testStruct{},
+		},
+	})
+}
+
+func TestParseConcurrent(t *testing.T) {
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				input := fmt.Sprintf("[t%d]\nkey = %d\n", i, j)
+				tbl, err := Parse([]byte(input))
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				sub := tbl.Fields[fmt.Sprintf("t%d", i)].(*ast.Table)
+				kv := sub.Fields["key"].(*ast.KeyValue)
+				if v := kv.Value.(*ast.Integer).Value; v != strconv.Itoa(j) {
+					t.Errorf("wrong value %s, want %d", v, j)
+				}
+				if string(tbl.Data) != input {
+					t.Errorf("wrong document source %q", string(tbl.Data))
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestParseReader(t *testing.T) {
+	input := "# head\na = [[1], [2]] # arrays\n[t]\nb = true\n"
+
+	tbl, err := ParseReader(strings.NewReader(input), Comments(), DiscardSource())
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantComments := []*ast.Comment{
+		{Position: ast.Position{Begin: 0, End: 6}, Line: 1, Text: " head"},
+		{Position: ast.Position{Begin: 22, End: 30}, Line: 2, Text: " arrays"},
+	}
+	if !reflect.DeepEqual(tbl.Comments, wantComments) {
+		t.Errorf("wrong comments: %s", pretty.Sprint(tbl.Comments))
+	}
+	if tbl.Data != nil || tbl.Fields["a"].(*ast.KeyValue).Value.(*ast.Array).Data != nil {
+		t.Error("source not discarded")
+	}
+
+	tests := []struct {
+		input string
+		opts  []ParseOption
+		err   string
+	}{
+		{input, []ParseOption{MaxSize(10)}, "toml: input exceeds the maximum size of 10 bytes"},
+		{input, []ParseOption{MaxDepth(2)}, "line 2: nesting depth exceeds the maximum of 2"},
+		{input, []ParseOption{MaxDepth(3)}, ""},
+		{"[a.b.c]", []ParseOption{MaxDepth(2)}, "line 1: nesting depth exceeds the maximum of 2"},
+		{"a = {b = {c = 1}}", []ParseOption{MaxDepth(2)}, "line 1: nesting depth exceeds the maximum of 2"},
+		{"a = {b = {c = 1}}", []ParseOption{MaxDepth(3)}, ""},
+		{"[[a]]\nb = [1]", []ParseOption{MaxDepth(2)}, "line 2: nesting depth exceeds the maximum of 2"},
+		{"[[a]]\nb = [{c = 1}]", []ParseOption{MaxDepth(4)}, ""},
+		{"a = []", []ParseOption{MaxDepth(1)}, ""},
+		{input, []ParseOption{Version("0.3")}, `toml: unsupported TOML version "0.3"`},
+		{"a = 0x10", []ParseOption{Version("0.4.0")}, "line 1: hexadecimal, octal and binary integers require TOML 0.5.0, but version 0.4.0 is selected"},
+		{"a = 0x10", []ParseOption{Version("0.5.0")}, ""},
+		{"a = inf", []ParseOption{Version("0.4")}, "line 1: inf and nan require TOML 0.5.0, but version 0.4 is selected"},
+		{"a = 1979-05-27", []ParseOption{Version("0.4")}, "line 1: local dates and times require TOML 0.5.0, but version 0.4 is selected"},
+		{"a = 1979-05-27T07:32:00Z", []ParseOption{Version("0.4")}, ""},
+		{"a = 1979-05-27 07:32:00Z", []ParseOption{Version("0.4")}, "line 1: spaces as date/time delimiters require TOML 0.5.0, but version 0.4 is selected"},
+		{"a = [1, 'x']", []ParseOption{Version("0.5")}, "line 1: arrays with mixed element types require TOML 1.0.0, but version 0.5 is selected"},
+		{"a = [1, 'x']", []ParseOption{Version("1.0")}, ""},
+		{`a = "\e"`, []ParseOption{Version("1.0")}, `line 1: \e and \x escapes require TOML 1.1.0, but version 1.0 is selected`},
+		{`a = "\x41"`, nil, `line 1: \e and \x escapes require TOML 1.1.0, which must be selected explicitly`},
+		{`a = "\\e"`, nil, ""},
+		{`a = "\\uD800"`, nil, ""},
+		{"\xef\xbb\xbfa = 1", nil, ""},
+		{"\xef\xbb\xbfa = 1", []ParseOption{RejectBOM()}, "line 1: byte order mark at start of document"},
+		{"a = 1\xef\xbb\xbf", nil, "line 1: invalid TOML syntax, expected end of line\n\ta = 1\ufeff\n\t     ^"},
+		{"schlüssel = 1", nil, "line 1: non-ASCII bare keys require TOML 1.1.0, which must be selected explicitly"},
+		{"[tbl.ключ]", []ParseOption{Version("1.0")}, "line 1: non-ASCII bare keys require TOML 1.1.0, but version 1.0 is selected"},
+		{"schlüssel = 1\n[ключ.×]", []ParseOption{Version("1.1")}, "line 2: invalid TOML syntax, expected a key\n\t[ключ.×]\n\t      ^"},
+		{"schlüssel = 1\n[ключ.キー]", []ParseOption{Version("1.1")}, ""},
+		{`"schlüssel" = 1`, nil, ""},
+		{"a = 1\rb = 2", nil, "line 1: carriage return without line feed"},
+		{"a = 08080", nil, "line 1: number 08080 has leading zeros"},
+		{"a = -01.5", nil, "line 1: number -01.5 has leading zeros"},
+		{"a = [0, 0.5, 0e1, 1e05, 0x0F]", nil, ""},
+		{"a = 0_1", []ParseOption{AllowLeadingZeros(nil)}, ""},
+		{"a = 1\r\nb = 2\r\n", nil, ""},
+		{"a = 1\rb = 2\r", []ParseOption{AllowBareCR()}, ""},
+		{"a = 1\rb = 2\r[", []ParseOption{AllowBareCR()}, "line 3: invalid TOML syntax, expected a key or end of line\n\t[\n\t^"},
+		{`a = "\x41"`, []ParseOption{Version("1.1")}, ""},
+		{"a = {b = 1,}", nil, "line 1: inline table cannot contain ',' after last key/value pair"},
+		{"a = {b = 1,}", []ParseOption{Version("1.1")}, ""},
+		{"a = {\n  b = 1, # comment\n  c = 2,\n}", []ParseOption{Version("1.1")}, ""},
+		{"a = {\n  b = 1\n}", []ParseOption{Version("1.0")}, "line 1: newlines in inline tables require TOML 1.1.0, but version 1.0 is selected"},
+		{"a = {b = 1 # comment\n}", nil, "line 1: newlines in inline tables require TOML 1.1.0, which must be selected explicitly"},
+		{"a = {b = [\n  1,\n]}", nil, ""},
+		{"a = {b = \"\"\"\nx\"\"\"}", nil, ""},
+		{"a = {,}", []ParseOption{Version("1.1")}, "line 1: invalid TOML syntax, expected a value\n\ta = {,}\n\t    ^"},
+		{"a = 12:30", []ParseOption{Version("1.0")}, "line 1: times without seconds require TOML 1.1.0, but version 1.0 is selected"},
+		{"a = 1979-05-27T07:32Z", nil, "line 1: times without seconds require TOML 1.1.0, which must be selected explicitly"},
+		{"a = 12:30", []ParseOption{Version("1.1")}, ""},
+		{"a = 12:3", []ParseOption{Version("1.1")}, "line 1: invalid TOML syntax\n\ta = 12:3\n\t        ^"},
+	}
+	for _, test := range tests {
+		_, err := ParseReader(strings.NewReader(test.input), test.opts...)
+		if errString(err) != test.err {
+			t.Errorf("input %q: got error %q, want %q", test.input, errString(err), test.err)
+		}
+	}
+}
+
+func errString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+func TestParseIncremental(t *testing.T) {
+	defer func(size int) { minPartSize = size }(minPartSize)
+	minPartSize = 1
+
+	inputs := []string{
+		"a = 1\n[t]\nb = '''\n[not.a.table]\n'''\n[[at]]\nc = \"\"\"\n[x]\\\"\"\"\n\"\"\"\n",
+		"a = [\n[1],\n  [2], # ]\n]\n[t] # comment\nb = \"[\"\n[u]\nc = '\"'\n",
+		"[t]\na = 1\n[t]\nb = 2\n",
+		"[t]\na = 1\n[u]\nb = \"\\q\"\n",
+		"[t]\na = 1\n[u]\nb = 1 c = 2\n",
+		"[t]\n\ta = 1\n[u]\n b = 1979-05-27T25:00:00Z\n",
+		"[t]\na = 1\n[u]\nb = \"\x01\"\n",
+	}
+	files, err := filepath.Glob(filepath.Join("testdata", "*.toml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range files {
+		inputs = append(inputs, string(loadTestData(filepath.Base(f))))
+	}
+	for _, input := range inputs {
+		want, wantErr := ParseReader(strings.NewReader(input), DiscardSource(), Comments())
+		got, err := ParseReader(iotest.OneByteReader(strings.NewReader(input)), Incremental(), Comments())
+		if !reflect.DeepEqual(err, wantErr) {
+			t.Errorf("wrong error for %q:\ngot  %#v\nwant %#v", input, err, wantErr)
+		} else if diff := pretty.Compare(got, want); err == nil && diff != "" {
+			t.Errorf("wrong AST for %q:\n%s", input, diff)
+		}
+	}
+
+	dec := NewDecoder(strings.NewReader("a = 1\n[t]\nb = 2\n"))
+	dec.Incremental()
+	var v struct {
+		A int
+		T struct{ B int }
+	}
+	if err := dec.Decode(&v); err != nil {
+		t.Fatal(err)
+	}
+	if v.A != 1 || v.T.B != 2 {
+		t.Errorf("wrong value %+v", v)
+	}
+
+	dec = NewDecoder(strings.NewReader("[t]\n[[t.at]]\na = 1\n[[t.at]]\na = 2\n"))
+	dec.Incremental()
+	var u struct {
+		T struct{ At testUnmarshalerString }
+	}
+	if err := dec.Decode(&u); err != nil {
+		t.Fatal(err)
+	}
+	if want := "Unmarshaled: [[t.at]]\na = 1\n\n[[t.at]]\na = 2\n"; string(u.T.At) != want {
+		t.Errorf("wrong Unmarshaler source %q, want %q", u.T.At, want)
+	}
+}
+
+// countingReader counts the bytes read from r.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (cr *countingReader) Read(p []byte) (int, error) {
+	n, err := cr.r.Read(p)
+	cr.n += int64(n)
+	return n, err
+}
+
+func TestParseIncrementalMaxSize(t *testing.T) {
+	cr := &countingReader{r: strings.NewReader(strings.Repeat("a = 1 # no tables here\n", 1<<16))}
+	_, err := ParseReader(cr, Incremental(), MaxSize(1024))
+	if want := "toml: input exceeds the maximum size of 1024 bytes"; errString(err) != want {
+		t.Errorf("wrong error %q, want %q", errString(err), want)
+	}
+	if cr.n > 1025 {
+		t.Errorf("read %d bytes, want at most 1025", cr.n)
+	}
+}
+
+func TestDecoderProgress(t *testing.T) {
+	input := strings.Repeat("# padding\n", 1000) + "a = 1\n"
+	dec := NewDecoder(iotest.OneByteReader(strings.NewReader(input)))
+	var calls int
+	var last int64
+	dec.Progress(func(offset int64) {
+		calls++
+		if offset <= last {
+			t.Errorf("offset %d is not greater than previous offset %d", offset, last)
+		}
+		last = offset
+	})
+	var v struct{ A int }
+	if err := dec.Decode(&v); err != nil {
+		t.Fatal(err)
+	}
+	if v.A != 1 {
+		t.Errorf("wrong value %d", v.A)
+	}
+	if dec.InputOffset() != int64(len(input)) || last != int64(len(input)) {
+		t.Errorf("wrong offset %d, want %d", dec.InputOffset(), len(input))
+	}
+	if calls != len(input) {
+		t.Errorf("progress called %d times, want %d", calls, len(input))
+	}
+}
+
+func TestMustUnmarshal(t *testing.T) {
+	var v struct{ A int }
+	MustUnmarshal([]byte("a = 1"), &v)
+	if v.A != 1 {
+		t.Errorf("wrong value: %d", v.A)
+	}
+
+	tests := []struct {
+		fn   func()
+		want string
+	}{
+		{
+			func() { MustUnmarshal([]byte("\na = \"x\"\n"), &v) },
+			"toml: MustUnmarshal: line 2: a (struct { A int }.A): cannot unmarshal TOML string into int\n\ta = \"x\"",
+		},
+		{
+			func() { MustParse([]byte("a = 1\nb = \r\n")) },
+			"toml: MustParse: line 2: invalid TOML syntax, expected a value\n\tb = \n\t    ^",
+		},
+	}
+	for _, test := range tests {
+		func() {
+			defer func() {
+				if r := recover(); r != test.want {
+					t.Errorf("wrong panic:\n got %q\nwant %q", r, test.want)
+				}
+			}()
+			test.fn()
+		}()
+	}
+}
+
+func TestUnmarshalKeyValues(t *testing.T) {
+	input := []byte(`zeta = 1
+alpha = "a"
+
+[env]
+PATH = "/bin"
+HOME = "/root"
+inline = { b = 2, a = 1 }
+
+[[hooks]]
+name = "second"
+[[hooks]]
+name = "first"
+`)
+	var v struct {
+		Zeta, Alpha interface{}
+		Env         []KeyValue
+		Hooks       []struct{ Name string }
+	}
+	if err := Unmarshal(input, &v); err != nil {
+		t.Fatal(err)
+	}
+	want := []KeyValue{
+		{"PATH", "/bin"},
+		{"HOME", "/root"},
+		{"inline", []KeyValue{{"b", int64(2)}, {"a", int64(1)}}},
+	}
+	if !reflect.DeepEqual(v.Env, want) {
+		t.Errorf("got %#v\nwant %#v", v.Env, want)
+	}
+
+	var doc []KeyValue
+	if err := Unmarshal(input, &doc); err != nil {
+		t.Fatal(err)
+	}
+	keys := make([]string, len(doc))
+	for i, kv := range doc {
+		keys[i] = kv.Key
+	}
+	if want := []string{"zeta", "alpha", "env", "hooks"}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("got keys %q, want %q", keys, want)
+	}
+	hooks := []interface{}{[]KeyValue{{"name", "second"}}, []KeyValue{{"name", "first"}}}
+	if !reflect.DeepEqual(doc[3].Value, hooks) {
+		t.Errorf("got hooks %#v, want %#v", doc[3].Value, hooks)
+	}
+
+	out, err := Marshal(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantOut := "zeta = 1\nalpha = \"a\"\n\n[env]\nPATH = \"/bin\"\nHOME = \"/root\"\n\n[env.inline]\nb = 2\na = 1\n\n[[hooks]]\nname = \"second\"\n\n[[hooks]]\nname = \"first\"\n"
+	if string(out) != wantOut {
+		t.Errorf("wrong output:\n%s\nwant:\n%s", out, wantOut)
+	}
+}
+
+func TestMixedArrays(t *testing.T) {
+	input := []byte("a = [1, \"two\", 3.5, [true], { x = 1 }]\n")
+	var v struct{ A []interface{} }
+	if err := Unmarshal(input, &v); err != nil {
+		t.Fatal(err)
+	}
+	want := []interface{}{int64(1), "two", 3.5, []interface{}{true}, map[string]interface{}{"x": int64(1)}}
+	if !reflect.DeepEqual(v.A, want) {
+		t.Errorf("got %#v, want %#v", v.A, want)
+	}
+	out, err := Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "a = [1, \"two\", 3.5e+00, [true], {x = 1}]\n"; string(out) != want {
+		t.Errorf("wrong output %q, want %q", out, want)
+	}
+
+	var ints struct{ A []int }
+	err = Unmarshal(input, &ints)
+	if want := "line 1: a (struct { A []int }.A): cannot unmarshal TOML string into int"; err == nil || err.Error() != want {
+		t.Errorf("wrong error %v, want %q", err, want)
+	}
+}
+
+func TestDatetimeDelimiter(t *testing.T) {
+	tbl, err := Parse([]byte("a = 1979-05-27 07:32:00Z\nb = 1979-05-27t07:32:00\nc = 1979-05-27\nd = 07:32:00\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		key, source string
+		delim       byte
+	}{
+		{"a", "1979-05-27 07:32:00Z", ' '},
+		{"b", "1979-05-27t07:32:00", 'T'},
+		{"c", "1979-05-27", 0},
+		{"d", "07:32:00", 0},
+	}
+	for _, test := range tests {
+		v := tbl.Fields[test.key].(*ast.KeyValue).Value.(*ast.Datetime)
+		if v.Source() != test.source || v.Delimiter() != test.delim {
+			t.Errorf("%s: got source %q, delimiter %q", test.key, v.Source(), v.Delimiter())
+		}
+	}
+
+	var got struct{ A time.Time }
+	if err := Unmarshal([]byte("a = 1979-05-27 07:32:00Z"), &got); err != nil {
+		t.Fatal(err)
+	}
+	if want := time.Date(1979, 5, 27, 7, 32, 0, 0, time.UTC); !got.A.Equal(want) {
+		t.Errorf("got %v, want %v", got.A, want)
+	}
+}
+
+func TestLeapSeconds(t *testing.T) {
+	var v struct {
+		A, B time.Time
+	}
+	input := []byte("a = 2016-12-31T23:59:60Z\nb = 1998-12-31T15:59:60.5-08:00\n")
+	if err := Unmarshal(input, &v); err != nil {
+		t.Fatal(err)
+	}
+	if want := time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC); !v.A.Equal(want) {
+		t.Errorf("got %v, want %v", v.A, want)
+	}
+	if want := time.Date(1999, 1, 1, 0, 0, 0, 5e8, time.UTC); !v.B.Equal(want) {
+		t.Errorf("got %v, want %v", v.B, want)
+	}
+
+	if err := Unmarshal([]byte("a = 2016-12-31T23:59:61Z"), &v); err == nil {
+		t.Error("no error for second 61")
+	}
+}
+
+func TestOptionalSeconds(t *testing.T) {
+	cfg := DefaultConfig.With(UseVersion("1.1"))
+	var v struct {
+		A, B, C, D time.Time
+	}
+	input := []byte("a = 12:30\nb = 1979-05-27T07:32\nc = 1979-05-27 07:32Z\nd = 1979-05-27T07:32-08:00\n")
+	if err := cfg.Unmarshal(input, &v); err != nil {
+		t.Fatal(err)
+	}
+	want := []time.Time{
+		time.Date(0, 1, 1, 12, 30, 0, 0, time.UTC),
+		time.Date(1979, 5, 27, 7, 32, 0, 0, time.UTC),
+		time.Date(1979, 5, 27, 7, 32, 0, 0, time.UTC),
+		time.Date(1979, 5, 27, 7, 32, 0, 0, time.FixedZone("", -8*60*60)),
+	}
+	for i, got := range []time.Time{v.A, v.B, v.C, v.D} {
+		if !got.Equal(want[i]) {
+			t.Errorf("value %d: got %v, want %v", i, got, want[i])
+		}
+	}
+}
+
+func TestEscapes11(t *testing.T) {
+	cfg := DefaultConfig.With(UseVersion("1.1"))
+	var v struct{ A, B, C string }
+	input := []byte("a = \"\\e[0m\"\nb = \"\\x41\\xe9\"\nc = \"\"\"\n\\x42\\\\x\"\"\"\n")
+	if err := cfg.Unmarshal(input, &v); err != nil {
+		t.Fatal(err)
+	}
+	if v.A != "\x1b[0m" || v.B != "A\u00e9" || v.C != "B\\x" {
+		t.Errorf("wrong values %q", v)
+	}
+
+	v.A, v.B, v.C = "\x1b\x01", "\a\v", "\x7f"
+	out, err := cfg.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "a = \"\\e\\x01\"\nb = \"\\u0007\\u000b\"\nc = \"\\x7f\"\n"; string(out) != want {
+		t.Errorf("wrong 1.1 output:\n%s\nwant:\n%s", out, want)
+	}
+	out, err = Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "a = \"\\u001b\\u0001\"\nb = \"\\u0007\\u000b\"\nc = \"\\u007f\"\n"; string(out) != want {
+		t.Errorf("wrong output:\n%s\nwant:\n%s", out, want)
+	}
+	var got struct{ A, B, C string }
+	if err := Unmarshal(out, &got); err != nil || got != v {
+		t.Errorf("round trip: got %q, error %v", got, err)
+	}
+}
+
+func TestErrorTypes(t *testing.T) {
+	var v struct {
+		A int8
+		B string
+	}
+	var syntaxErr *SyntaxError
+	err := Unmarshal([]byte("a = = 1\n"), &v)
+	if !errors.As(err, &syntaxErr) || !errors.Is(err, ErrSyntax) {
+		t.Errorf("expected *SyntaxError, got %#v", err)
+	}
+	err = Unmarshal([]byte("a = 1\nb = 2\n"), &v)
+	var typeErr *UnmarshalTypeError
+	if !errors.As(err, &typeErr) || typeErr.Value != "integer" || typeErr.Type != reflect.TypeOf("") {
+		t.Errorf("expected *UnmarshalTypeError, got %#v", err)
+	}
+	err = Unmarshal([]byte("a = 1000\n"), &v)
+	var overflowErr *OverflowError
+	if !errors.As(err, &overflowErr) || overflowErr.Kind != reflect.Int8 || overflowErr.Value != "1000" {
+		t.Errorf("expected *OverflowError, got %#v", err)
+	}
+	var lineErr *LineError
+	if !errors.As(err, &lineErr) || lineErr.Line != 1 {
+		t.Errorf("expected *LineError, got %#v", err)
+	}
+	if errors.As(err, &syntaxErr) {
+		t.Errorf("overflow reported as syntax error")
+	}
+}
+
+func TestErrorLocation(t *testing.T) {
+	type config struct {
+		Server struct {
+			Hosts []struct{ Name string }
+		}
+		Servers []struct {
+			Name string
+			Port int
+		}
+		Limits map[string]int
+	}
+	tests := []struct {
+		input string
+		pos   Position
+		path  string
+	}{
+		{"[[servers]]\nname = \"a\"\n\n[[servers]]\nport = \"x\"\n", Position{5, 8}, "servers[1].port"},
+		{"[server]\n  extra = 1\n", Position{2, 11}, "server.extra"},
+		{"[server]\nhosts = [{name = 1}]\n", Position{2, 18}, "server.hosts[0].name"},
+		{"[limits]\n\"a.b\" = \"x\"\n", Position{2, 9}, `limits."a.b"`},
+		{"servers = 1\n", Position{1, 11}, "servers"},
+		{"[server.hosts]\n", Position{1, 1}, "server.hosts"},
+		{"[servers]\n", Position{1, 1}, "servers"},
+		{"a = = 1\n", Position{1, 5}, ""},
+		{"servers = 1\nservers = 2\n", Position{2, 1}, ""},
+		{"[server]\nx = {a = 1, a = 2}\n", Position{2, 13}, ""},
+		{"x = 1\n[x]\n", Position{2, 2}, ""},
+		{"a = 1979-13-27\n", Position{1, 5}, ""},
+		{"a = 1\n  b = 08\n", Position{2, 7}, ""},
+		{"a = {b = \"\\uD800\"}\n", Position{1, 6}, ""},
+	}
+	for _, test := range tests {
+		var v config
+		err := Unmarshal([]byte(test.input), &v)
+		var lerr LocatedError
+		if !errors.As(err, &lerr) {
+			t.Errorf("%q: expected LocatedError, got %#v", test.input, err)
+			continue
+		}
+		if lerr.Position() != test.pos || lerr.KeyPath() != test.path {
+			t.Errorf("%q: wrong location %v %q, want %v %q (error: %v)", test.input, lerr.Position(), lerr.KeyPath(), test.pos, test.path, err)
+		}
+	}
+
+	var v config
+	err := DefaultConfig.With(ContinueOnError(true)).Unmarshal([]byte("[server]\nx = 1\ny = 2\n"), &v)
+	var list *ErrorList
+	if !errors.As(err, &list) || len(list.Errors) != 2 {
+		t.Fatalf("expected *ErrorList with two errors, got %#v", err)
+	}
+	for i, want := range []string{"server.x", "server.y"} {
+		if lerr, ok := list.Errors[i].(LocatedError); !ok || lerr.KeyPath() != want {
+			t.Errorf("error %d: wrong key path, want %q: %#v", i, want, list.Errors[i])
+		}
+	}
+}
+
+func TestSyntaxErrorExpected(t *testing.T) {
+	tests := []struct {
+		input string
+		want  []string
+	}{
+		{"a b = 1", []string{"'='"}},
+		{"a = [1 2]", []string{"','", "']'", "end of line"}},
+		{"a = {b = 1, c}", []string{"'='"}},
+		{"[a b]", []string{"'.'", "']'"}},
+		{"= 1", []string{"a key", "end of line"}},
+	}
+	for _, test := range tests {
+		_, err := Parse([]byte(test.input))
+		var serr *SyntaxError
+		if !errors.As(err, &serr) {
+			t.Errorf("input %q: expected *SyntaxError, got %v", test.input, err)
+			continue
+		}
+		if !reflect.DeepEqual(serr.Expected, test.want) {
+			t.Errorf("input %q: got expected tokens %q, want %q", test.input, serr.Expected, test.want)
+		}
+	}
+}
+
+func TestSyntaxErrorSource(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"a = 1\nb = = 2\n", "line 2: invalid TOML syntax, expected a value\n\tb = = 2\n\t    ^"},
+		{"[a]\n\tb = [1, ?]\n", "line 2: invalid TOML syntax, expected ']', a value or end of line\n\t\tb = [1, ?]\n\t\t        ^"},
+		{"ключ = ?\r\n", "line 1: invalid TOML syntax, expected a value\n\tключ = ?\n\t       ^"},
+	}
+	for _, test := range tests {
+		_, err := Parse([]byte(test.input))
+		if errString(err) != test.want {
+			t.Errorf("input %q: got error %q, want %q", test.input, errString(err), test.want)
+		}
+		if !errors.Is(err, ErrSyntax) {
+			t.Errorf("input %q: error is not ErrSyntax", test.input)
+		}
+	}
+}
+
+func TestPrimitiveDecode(t *testing.T) {
+	input := `
+[[plugin]]
+type = "http"
+config = { port = 80 }
+
+[[plugin]]
+type = "file"
+[plugin.config]
+path = "/tmp"
+mode = "x"
+`
+	var v struct {
+		Plugin []struct {
+			Type   string
+			Config Primitive
+		}
+		Missing Primitive
+	}
+	if err := Unmarshal([]byte(input), &v); err != nil {
+		t.Fatal(err)
+	}
+	var http struct{ Port int }
+	if err := PrimitiveDecode(v.Plugin[0].Config, &http); err != nil {
+		t.Fatal(err)
+	}
+	if http.Port != 80 {
+		t.Errorf("wrong port %d", http.Port)
+	}
+	var file struct {
+		Path string
+		Mode int
+	}
+	err := PrimitiveDecode(v.Plugin[1].Config, &file)
+	want := "line 10: plugin[1].config.mode (struct { Path string; Mode int }.Mode): cannot unmarshal TOML string into int"
+	if err == nil || err.Error() != want {
+		t.Errorf("wrong error %v, want %s", err, want)
+	}
+	// Decoding stops at the first error, so whether Path is set above depends on
+	// the order of the keys.
+	var fileOK struct{ Path, Mode string }
+	if err := PrimitiveDecode(v.Plugin[1].Config, &fileOK); err != nil {
+		t.Fatal(err)
+	}
+	if fileOK.Path != "/tmp" || fileOK.Mode != "x" {
+		t.Errorf("wrong value %+v", fileOK)
+	}
+	if err := PrimitiveDecode(v.Missing, &file); err != nil {
+		t.Errorf("unexpected error for undefined Primitive: %v", err)
+	}
+}
+
+func TestRawMessage(t *testing.T) {
+	input := `name = "app"
+ports = [ 80, 443 ] # raw value
+
+[plugin]
+# keep this comment
+path = "/tmp"
+mode = 0o755 # octal
+
+[inline]
+t = { a = 1 }
+
+[nested]
+a = 1
+[nested.sub]
+b = 2
+`
+	var v struct {
+		Name   string
+		Ports  RawMessage
+		Plugin RawMessage
+		Inline struct{ T RawMessage }
+		Nested RawMessage
+	}
+	if err := Unmarshal([]byte(input), &v); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"ports":    "[ 80, 443 ]",
+		"plugin":   "# keep this comment\npath = \"/tmp\"\nmode = 0o755 # octal\n",
+		"inline.t": "a = 1\n",
+		"nested":   "a = 1\n\n[sub]\nb = 2\n",
+	}
+	got := map[string]string{
+		"ports":    string(v.Ports),
+		"plugin":   string(v.Plugin),
+		"inline.t": string(v.Inline.T),
+		"nested":   string(v.Nested),
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("wrong messages:\n%s", pretty.Compare(got, want))
+	}
+
+	out, err := Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantOut := `name = "app"
+ports = [ 80, 443 ]
+
+[plugin]
+# keep this comment
+path = "/tmp"
+mode = 0o755 # octal
+
+[inline.t]
+a = 1
+
+[nested]
+a = 1
+
+[nested.sub]
+b = 2
+`
+	if string(out) != wantOut {
+		t.Errorf("wrong output:\n%s", out)
+	}
+}
+
+func TestBigNumbers(t *testing.T) {
+	input := `int = 123456789012345678901234567890
+hex = 0xFFFF_FFFF_FFFF_FFFF_FFFF
+float = 3.14159265358979323846264338327950288
+inf = -inf
+rat = 0.1
+ratint = 0o17
+third = "1/3"
+`
+	var v struct {
+		Int    *big.Int
+		Hex    big.Int
+		Float  *big.Float
+		Inf    *big.Float
+		Rat    *big.Rat
+		Ratint *big.Rat
+		Third  *big.Rat
+	}
+	if err := Unmarshal([]byte(input), &v); err != nil {
+		t.Fatal(err)
+	}
+	if v.Int.String() != "123456789012345678901234567890" || v.Hex.Text(16) != "ffffffffffffffffffff" {
+		t.Errorf("wrong integers %v, %v", v.Int, &v.Hex)
+	}
+	if s := v.Float.Text('f', 35); s != "3.14159265358979323846264338327950288" {
+		t.Errorf("wrong float %s", s)
+	}
+	if !v.Inf.IsInf() || v.Inf.Sign() > 0 {
+		t.Errorf("wrong inf %v", v.Inf)
+	}
+	if v.Rat.Cmp(big.NewRat(1, 10)) != 0 || v.Ratint.Cmp(big.NewRat(15, 1)) != 0 || v.Third.Cmp(big.NewRat(1, 3)) != 0 {
+		t.Errorf("wrong rationals %v, %v, %v", v.Rat, v.Ratint, v.Third)
+	}
+
+	out, err := Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `int = 123456789012345678901234567890
+hex = 1208925819614629174706175
+float = 3.14159265358979323846264338327950288e+00
+inf = -inf
+rat = 0.1
+ratint = 15.0
+third = "1/3"
+`
+	if string(out) != want {
+		t.Errorf("wrong output:\n%s", out)
+	}
+
+	var errv struct{ I *big.Int }
+	err = Unmarshal([]byte("i = 1.5"), &errv)
+	if err == nil || err.Error() != "line 1: i (struct { I *big.Int }.I): cannot unmarshal TOML float into big.Int" {
+		t.Errorf("wrong error %v", err)
+	}
+}
+
+func TestDecodeHook(t *testing.T) {
+	type level int
+	levels := func(from ast.Value, to reflect.Type) (interface{}, bool, error) {
+		s, ok := from.(*ast.String)
+		if !ok || to != reflect.TypeOf(level(0)) {
+			return nil, false, nil
+		}
+		switch s.Value {
+		case "debug":
+			return 1, true, nil
+		case "info":
+			return 2, true, nil
+		}
+		return nil, true, fmt.Errorf("unknown level %q", s.Value)
+	}
+	type point struct{ X, Y int }
+	points := func(from ast.Value, to reflect.Type) (interface{}, bool, error) {
+		s, ok := from.(*ast.String)
+		if !ok || to != reflect.TypeOf(point{}) {
+			return nil, false, nil
+		}
+		var p point
+		_, err := fmt.Sscanf(s.Value, "%d,%d", &p.X, &p.Y)
+		return p, true, err
+	}
+	cfg := DefaultConfig.With(DecodeHook(levels), DecodeHook(points))
+
+	input := `level = "debug"
+levels = ["info", "debug"]
+origin = "1,2"
+ptr = "3,4"
+
+[table]
+x = 5
+`
+	var v struct {
+		Level  level
+		Levels []level
+		Origin point
+		Ptr    *point
+		Table  point
+	}
+	if err := cfg.Unmarshal([]byte(input), &v); err != nil {
+		t.Fatal(err)
+	}
+	if v.Level != 1 || !reflect.DeepEqual(v.Levels, []level{2, 1}) {
+		t.Errorf("wrong levels %v, %v", v.Level, v.Levels)
+	}
+	if v.Origin != (point{1, 2}) || v.Ptr == nil || *v.Ptr != (point{3, 4}) || v.Table != (point{X: 5}) {
+		t.Errorf("wrong points %v, %v, %v", v.Origin, v.Ptr, v.Table)
+	}
+
+	var errv struct{ Level level }
+	err := cfg.Unmarshal([]byte(`level = "trace"`), &errv)
+	if err == nil || err.Error() != `line 1: level (struct { Level toml.level }.Level): unknown level "trace"` {
+		t.Errorf("wrong error %v", err)
+	}
+
+	wrong := DefaultConfig.With(DecodeHook(func(from ast.Value, to reflect.Type) (interface{}, bool, error) {
+		return "x", to.Kind() == reflect.Int, nil
+	}))
+	var iv struct{ I int }
+	err = wrong.Unmarshal([]byte(`i = 1`), &iv)
+	if err == nil || err.Error() != "line 1: i (struct { I int }.I): decode hook returned string, which cannot be assigned to int" {
+		t.Errorf("wrong error %v", err)
+	}
+}
+
+func TestWeaklyTypedInput(t *testing.T) {
+	input := `port = "8080"
+enabled = "true"
+ratio = 1
+big = 0x10
+hosts = "a"
+ports = [80, "443"]
+`
+	var v struct {
+		Port    uint16
+		Enabled bool
+		Ratio   float64
+		Big     float32
+		Hosts   []string
+		Ports   []int
+	}
+	cfg := DefaultConfig.With(WeaklyTypedInput(true))
+	if err := cfg.Unmarshal([]byte(input), &v); err != nil {
+		t.Fatal(err)
+	}
+	if v.Port != 8080 || !v.Enabled || v.Ratio != 1 || v.Big != 16 {
+		t.Errorf("wrong scalars %+v", v)
+	}
+	if !reflect.DeepEqual(v.Hosts, []string{"a"}) || !reflect.DeepEqual(v.Ports, []int{80, 443}) {
+		t.Errorf("wrong slices %v, %v", v.Hosts, v.Ports)
+	}
+
+	var errv struct{ Port int }
+	err := cfg.Unmarshal([]byte(`port = "http"`), &errv)
+	if err == nil || err.Error() != "line 1: port (struct { Port int }.Port): cannot unmarshal TOML string into int (need quoted integer)" {
+		t.Errorf("wrong error %v", err)
+	}
+	if err := Unmarshal([]byte(`port = "8080"`), &errv); err == nil {
+		t.Error("expected error without WeaklyTypedInput")
+	}
+}
+
+func TestDottedTagKeys(t *testing.T) {
+	type config struct {
+		Name string
+		Host string `toml:"server.host"`
+		Port int    `toml:"server.port"`
+		Cert string `toml:"server.tls.cert"`
+		Mode string `toml:"log.mode"`
+	}
+	input := `name = "app"
+log = {mode = "json"}
+
+[server]
+host = "localhost"
+port = 80
+
+[server.tls]
+cert = "a.pem"
+`
+	var v config
+	if err := Unmarshal([]byte(input), &v); err != nil {
+		t.Fatal(err)
+	}
+	want := config{Name: "app", Host: "localhost", Port: 80, Cert: "a.pem", Mode: "json"}
+	if v != want {
+		t.Errorf("wrong value %+v", v)
+	}
+
+	out, err := Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantOut := `name = "app"
+
+[server]
+host = "localhost"
+port = 80
+
+[server.tls]
+cert = "a.pem"
+
+[log]
+mode = "json"
+`
+	if string(out) != wantOut {
+		t.Errorf("wrong output:\n%s", out)
+	}
+
+	err = Unmarshal([]byte("[server]\nname = 1"), &v)
+	if err == nil || err.Error() != "line 2: server.name: field corresponding to `server.name' is not defined in toml.config" {
+		t.Errorf("wrong error %v", err)
+	}
+	var conflict struct {
+		Server struct{ Host string }
+		Host   string `toml:"server.host"`
+	}
+	err = Unmarshal([]byte("[server]\nhost = \"a\""), &conflict)
+	if err == nil || !strings.Contains(err.Error(), "fields Server and Host of struct") {
+		t.Errorf("wrong conflict error %v", err)
+	}
+	if _, err := Marshal(conflict); err == nil || !strings.Contains(err.Error(), "key `server' is already used by another field") {
+		t.Errorf("wrong encoder conflict error %v", err)
+	}
+}
+
+func TestAliasTag(t *testing.T) {
+	type config struct {
+		Timeout int `toml:"timeout,alias=timeout_seconds,alias=timeoutSecs"`
+		Retries int `toml:",alias=max_retries"`
+	}
+	tests := []struct {
+		input string
+		want  config
+		err   string
+	}{
+		{input: "timeout = 1\nretries = 2", want: config{1, 2}},
+		{input: "timeout_seconds = 1\nmax_retries = 2", want: config{1, 2}},
+		{input: "timeoutSecs = 1", want: config{Timeout: 1}},
+		{input: "timeout = 1\ntimeout_seconds = 2", err: "line 2: timeout_seconds: key `timeout_seconds' is in conflict with key `timeout' in line 1 (both match field toml.config.Timeout)"},
+	}
+	for _, test := range tests {
+		var v config
+		err := Unmarshal([]byte(test.input), &v)
+		if test.err != "" {
+			if err == nil || err.Error() != test.err {
+				t.Errorf("%q: wrong error %v", test.input, err)
+			}
+			continue
+		}
+		if err != nil || v != test.want {
+			t.Errorf("%q: got %+v, %v", test.input, v, err)
+		}
+	}
+
+	var conflict struct {
+		Timeout int `toml:",alias=retries"`
+		Retries int
+	}
+	err := Unmarshal([]byte("timeout = 1"), &conflict)
+	if err == nil || !strings.Contains(err.Error(), "fields Retries and Timeout of struct") {
+		t.Errorf("wrong conflict error %v", err)
+	}
+}
+
+func TestRequiredTag(t *testing.T) {
+	type server struct {
+		Host string `toml:",required"`
+		Port int    `toml:"listen_port,required"`
+	}
+	type config struct {
+		Name    string `toml:",required"`
+		Servers []server
+		Cert    string `toml:"tls.cert,required"`
+	}
+	tests := []struct {
+		input, err string
+	}{
+		{input: "name = \"a\"\ntls = {cert = \"c\"}\n\n[[servers]]\nhost = \"h\"\nlisten_port = 1\n"},
+		{
+			input: "name = \"a\"\ntls = {cert = \"c\"}\n\n[[servers]]\nhost = \"h\"\n",
+			err:   "line 4: servers[0] (toml.server.Port): required key `listen_port' is missing",
+		},
+		{
+			input: "tls = {cert = \"c\"}\n",
+			err:   "line 1: (toml.config.Name) required key `name' is missing",
+		},
+		{
+			input: "name = \"a\"\n",
+			err:   "line 1: (toml.config.Cert) required key `tls.cert' is missing",
+		},
+	}
+	for _, test := range tests {
+		var v config
+		err := Unmarshal([]byte(test.input), &v)
+		if test.err == "" && err != nil || test.err != "" && (err == nil || err.Error() != test.err) {
+			t.Errorf("%q: wrong error %v", test.input, err)
+		}
+	}
+}
+
+func TestRequiredTagMissingTable(t *testing.T) {
+	type tls struct {
+		Cert string `toml:",required"`
+	}
+	type database struct {
+		User string `toml:",required"`
+		TLS  tls
+	}
+	type config struct {
+		Name     string
+		Database database
+		Cache    *database
+	}
+	tests := []struct {
+		input, err string
+	}{
+		{input: "[database]\nuser = \"u\"\ntls = {cert = \"c\"}\n"},
+		{
+			input: "name = \"a\"\n",
+			err:   "line 1: database (toml.database.User): required key `user' is missing",
+		},
+		{
+			input: "name = \"a\"\n\n[database]\nuser = \"u\"\n",
+			err:   "line 3: database.tls (toml.tls.Cert): required key `cert' is missing",
+		},
+	}
+	for _, test := range tests {
+		var v config
+		err := Unmarshal([]byte(test.input), &v)
+		if test.err == "" && err != nil || test.err != "" && (err == nil || err.Error() != test.err) {
+			t.Errorf("%q: wrong error %v", test.input, err)
+		}
+	}
+}
+
+type validatedServer struct {
+	Port int
+}
+
+func (s *validatedServer) ValidateTOML() error {
+	if s.Port > 65535 {
+		return errors.New("port out of range")
+	}
+	return nil
+}
+
+type validatedConfig struct {
+	Name    string
+	Servers map[string]validatedServer
+}
+
+func (c validatedConfig) ValidateTOML() error {
+	if c.Name == "" {
+		return errors.New("name is empty")
+	}
+	return nil
+}
+
+func TestValidator(t *testing.T) {
+	tests := []struct {
+		input, err string
+	}{
+		{input: "name = \"a\"\n\n[servers.a]\nport = 80\n"},
+		{input: "name = \"a\"\n\n[servers.a]\nport = 80\n\n[servers.b]\nport = 80000\n", err: "line 6: servers.b: port out of range"},
+		{input: "[servers.a]\nport = 80\n", err: "line 1: name is empty"},
+	}
+	for _, test := range tests {
+		var v validatedConfig
+		err := Unmarshal([]byte(test.input), &v)
+		if test.err == "" && err != nil || test.err != "" && (err == nil || err.Error() != test.err) {
+			t.Errorf("%q: wrong error %v", test.input, err)
+		}
+	}
+
+	var list []validatedServer
+	err := Unmarshal([]byte("list = [{port = 1}, {port = 70000}]"), &struct{ List *[]validatedServer }{&list})
+	if err == nil || err.Error() != "line 1: list[1]: port out of range" {
+		t.Errorf("wrong error for inline table %v", err)
+	}
+}
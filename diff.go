@@ -0,0 +1,110 @@
+package toml
+
+import (
+	"bytes"
+	"sort"
+
+	"github.com/naoina/toml/ast"
+)
+
+// ChangeKind is the kind of a Change.
+type ChangeKind uint8
+
+const (
+	// Added means the key exists only in the new document.
+	Added ChangeKind = iota + 1
+	// Removed means the key exists only in the old document.
+	Removed
+	// Changed means the key exists in both documents, with different values.
+	Changed
+)
+
+// Change is a difference between two TOML documents.
+type Change struct {
+	Kind ChangeKind
+	Path []string // key path of the changed value
+	Key  string   // Path as a dotted TOML key
+	Old  string   // old value in canonical inline notation, empty if Kind is Added
+	New  string   // new value in canonical inline notation, empty if Kind is Removed
+}
+
+func (c Change) String() string {
+	switch c.Kind {
+	case Added:
+		return "+ " + c.Key + " = " + c.New
+	case Removed:
+		return "- " + c.Key + " = " + c.Old
+	default:
+		return "~ " + c.Key + " = " + c.Old + " -> " + c.New
+	}
+}
+
+// Diff returns the semantic differences between two documents, sorted by key path.
+// Tables are compared key by key. All other values, including arrays and array tables,
+// are compared as a whole using their canonical form (see Canonicalize). Formatting
+// differences are not reported.
+func Diff(old, new *ast.Table) ([]Change, error) {
+	var changes []Change
+	err := diffTables(&changes, nil, old, new)
+	return changes, err
+}
+
+func diffTables(changes *[]Change, path []string, old, new *ast.Table) error {
+	keys := make(map[string]bool)
+	for key := range old.Fields {
+		keys[key] = true
+	}
+	for key := range new.Fields {
+		keys[key] = true
+	}
+	sorted := make([]string, 0, len(keys))
+	for key := range keys {
+		sorted = append(sorted, key)
+	}
+	sort.Strings(sorted)
+
+	for _, key := range sorted {
+		keyPath := append(path[:len(path):len(path)], key)
+		of, inOld := old.Fields[key]
+		nf, inNew := new.Fields[key]
+		ot, nt := mergeableTable(of), mergeableTable(nf)
+		if ot != nil && nt != nil {
+			if err := diffTables(changes, keyPath, ot, nt); err != nil {
+				return err
+			}
+			continue
+		}
+		c := Change{Path: keyPath, Key: canonicalPath(keyPath)}
+		var err error
+		if inOld {
+			if c.Old, err = inlineText(of); err != nil {
+				return err
+			}
+		}
+		if inNew {
+			if c.New, err = inlineText(nf); err != nil {
+				return err
+			}
+		}
+		switch {
+		case !inOld:
+			c.Kind = Added
+		case !inNew:
+			c.Kind = Removed
+		case c.Old != c.New:
+			c.Kind = Changed
+		default:
+			continue
+		}
+		*changes = append(*changes, c)
+	}
+	return nil
+}
+
+func inlineText(field interface{}) (string, error) {
+	var buf bytes.Buffer
+	if err := canonicalInlineField(&buf, field); err != nil {
+		return "", lineError(fieldLineNumber(field), err)
+	}
+	return buf.String(), nil
+}
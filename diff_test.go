@@ -0,0 +1,53 @@
+package toml
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	old, err := Parse([]byte(`
+name = "app"
+removed = 1
+ports = [1, 2]
+
+[server]
+host = "localhost"
+port = 80
+
+[[rule]]
+x = 1
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	new, err := Parse([]byte(`
+# formatting and order don't matter
+ports = [ 1, 0x2 ]
+name = 'app'
+added = { a = true }
+server = { host = "localhost", port = 8080 }
+
+[[rule]]
+x = 2
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	changes, err := Diff(old, new)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Change{
+		{Kind: Added, Path: []string{"added"}, Key: "added", New: "{ a = true }"},
+		{Kind: Removed, Path: []string{"removed"}, Key: "removed", Old: "1"},
+		{Kind: Changed, Path: []string{"rule"}, Key: "rule", Old: "[{ x = 1 }]", New: "[{ x = 2 }]"},
+		{Kind: Changed, Path: []string{"server", "port"}, Key: "server.port", Old: "80", New: "8080"},
+	}
+	if !reflect.DeepEqual(changes, want) {
+		t.Errorf("wrong changes:\ngot  %v\nwant %v", changes, want)
+	}
+	if s := want[3].String(); s != "~ server.port = 80 -> 8080" {
+		t.Errorf("wrong string %q", s)
+	}
+}
@@ -0,0 +1,386 @@
+package toml
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/naoina/toml/ast"
+)
+
+// Document is a TOML document that can be modified without losing its formatting and
+// comments. Edits only touch the text of the modified keys.
+type Document struct {
+	src   []rune
+	table *ast.Table
+}
+
+// ParseDocument parses a TOML document for editing.
+func ParseDocument(data []byte) (*Document, error) {
+	d := &Document{src: []rune(string(data))}
+	if err := d.reparse(); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// Bytes returns the current text of the document.
+func (d *Document) Bytes() []byte {
+	return []byte(string(d.src))
+}
+
+// Table returns the AST of the current text. The comments of the document are
+// recorded in its Comments field. It must not be modified.
+func (d *Document) Table() *ast.Table {
+	return d.table
+}
+
+// Set assigns a value to the key at path. The value is given in TOML notation, e.g.
+// `"text"`, `42` or `[1, 2]`, and written in canonical form. Existing values are
+// replaced in place. New keys are added after the last key/value pair of their table;
+// missing tables are appended to the end of the document. Keys inside inline tables are
+// set by rewriting the inline table.
+func (d *Document) Set(path []string, value string) error {
+	if len(path) == 0 {
+		return errors.New("toml: empty key path")
+	}
+	v, value, err := parseValue(value)
+	if err != nil {
+		return err
+	}
+	parent, inline, err := d.lookupParent(path)
+	if err != nil {
+		return err
+	}
+	key := path[len(path)-1]
+	if inline != nil {
+		inlineTableAt(inline.table, inline.path).Fields[key] = &ast.KeyValue{Key: key, Value: v}
+		return d.rewriteInline(inline.kv)
+	}
+
+	var text string
+	if parent == nil {
+		// The table doesn't exist yet.
+		text = d.newline() + "\n[" + canonicalPath(path[:len(path)-1]) + "]\n" + canonicalKey(key) + " = " + value + "\n"
+		return d.edit(len(d.src), len(d.src), text)
+	}
+	kv := ""
+	switch f := parent.Fields[key].(type) {
+	case nil:
+		kv = canonicalKey(key) + " = " + value
+	case *ast.KeyValue:
+		return d.edit(f.Value.Pos(), f.Value.End(), value)
+	default:
+		return fmt.Errorf("toml: key `%s' is a table", strings.Join(path, "."))
+	}
+	pos, ok := lastValueEnd(parent)
+	switch {
+	case ok:
+		return d.edit(d.lineEnd(pos), d.lineEnd(pos), "\n"+kv)
+	case parent == d.table:
+		return d.edit(0, 0, kv+"\n")
+	case parent.Position != (ast.Position{}):
+		pos = d.lineEnd(parent.Position.Begin)
+		return d.edit(pos, pos, "\n"+kv)
+	default:
+		// Implicitly created table, define it.
+		text = d.newline() + "\n[" + canonicalPath(path[:len(path)-1]) + "]\n" + kv + "\n"
+		return d.edit(len(d.src), len(d.src), text)
+	}
+}
+
+// Delete removes the key at path. If the key refers to a table, the table and all of
+// its sub-tables are removed.
+func (d *Document) Delete(path []string) error {
+	if len(path) == 0 {
+		return errors.New("toml: empty key path")
+	}
+	parent, inline, err := d.lookupParent(path)
+	if err != nil {
+		return err
+	}
+	key := path[len(path)-1]
+	if inline != nil {
+		t := inlineTableAt(inline.table, inline.path)
+		if _, ok := t.Fields[key]; !ok {
+			return fmt.Errorf("toml: key `%s' is not defined", strings.Join(path, "."))
+		}
+		delete(t.Fields, key)
+		return d.rewriteInline(inline.kv)
+	}
+	if parent == nil || parent.Fields[key] == nil {
+		return fmt.Errorf("toml: key `%s' is not defined", strings.Join(path, "."))
+	}
+
+	var ranges []ast.Position
+	switch f := parent.Fields[key].(type) {
+	case *ast.KeyValue:
+		end := d.lineEnd(f.Value.End())
+		if end < len(d.src) {
+			end++ // include newline
+		}
+		ranges = append(ranges, ast.Position{Begin: d.lineStart(f.Value.Pos()), End: end})
+	default:
+		ranges = d.sections(f, d.headers(), ranges)
+	}
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i].Begin > ranges[j].Begin })
+	src := append([]rune(nil), d.src...)
+	for _, r := range ranges {
+		src = append(src[:r.Begin], src[r.End:]...)
+	}
+	return d.replace(src)
+}
+
+type inlineRef struct {
+	kv    *ast.KeyValue // key/value pair holding the outermost inline table
+	table *ast.Table    // value of kv
+	path  []string      // path of the parent table within the inline table
+}
+
+// lookupParent finds the table containing the last element of path. It returns a
+// nil table if the table doesn't exist. If the table is inside an inline table,
+// inline is set instead.
+func (d *Document) lookupParent(path []string) (parent *ast.Table, inline *inlineRef, err error) {
+	t := d.table
+	for i, key := range path[:len(path)-1] {
+		switch f := t.Fields[key].(type) {
+		case nil:
+			return nil, nil, nil
+		case *ast.Table:
+			t = f
+		case []*ast.Table:
+			return nil, nil, fmt.Errorf("toml: key `%s' is an array table", strings.Join(path[:i+1], "."))
+		case *ast.KeyValue:
+			it, ok := f.Value.(*ast.Table)
+			if !ok {
+				return nil, nil, fmt.Errorf("toml: key `%s' is not a table", strings.Join(path[:i+1], "."))
+			}
+			ref := &inlineRef{kv: f, table: it, path: path[i+1 : len(path)-1]}
+			if err := checkInlinePath(it, ref.path); err != nil {
+				return nil, nil, err
+			}
+			return nil, ref, nil
+		}
+	}
+	return t, nil, nil
+}
+
+func checkInlinePath(t *ast.Table, path []string) error {
+	for _, key := range path {
+		kv, ok := t.Fields[key].(*ast.KeyValue)
+		if !ok {
+			return fmt.Errorf("toml: key `%s' is not defined in inline table", key)
+		}
+		if t, ok = kv.Value.(*ast.Table); !ok {
+			return fmt.Errorf("toml: key `%s' is not a table", key)
+		}
+	}
+	return nil
+}
+
+func inlineTableAt(t *ast.Table, path []string) *ast.Table {
+	for _, key := range path {
+		t = t.Fields[key].(*ast.KeyValue).Value.(*ast.Table)
+	}
+	return t
+}
+
+// rewriteInline replaces the text of an inline table with its modified AST.
+func (d *Document) rewriteInline(kv *ast.KeyValue) error {
+	var buf bytes.Buffer
+	if err := canonicalInline(&buf, kv.Value); err != nil {
+		return err
+	}
+	return d.edit(kv.Value.Pos(), kv.Value.End(), buf.String())
+}
+
+// sections returns the source ranges of all tables defined by field. A section extends
+// from its header to the header of the next table, excluding comments directly above
+// that header.
+func (d *Document) sections(field interface{}, headers []int, ranges []ast.Position) []ast.Position {
+	var tables []*ast.Table
+	switch f := field.(type) {
+	case *ast.Table:
+		tables = []*ast.Table{f}
+	case []*ast.Table:
+		tables = f
+	}
+	for _, t := range tables {
+		if t.Position != (ast.Position{}) {
+			begin := d.lineStart(t.Position.Begin)
+			end := len(d.src)
+			if i := sort.SearchInts(headers, begin+1); i < len(headers) {
+				end = headers[i]
+				for end > 0 {
+					prev := d.lineStart(end - 1)
+					if !strings.HasPrefix(strings.TrimSpace(string(d.src[prev:end])), "#") {
+						break
+					}
+					end = prev
+				}
+			}
+			ranges = append(ranges, ast.Position{Begin: begin, End: end})
+		}
+		for _, child := range t.Fields {
+			ranges = d.sections(child, headers, ranges)
+		}
+	}
+	return ranges
+}
+
+// headers returns the sorted line offsets of all table headers.
+func (d *Document) headers() []int {
+	var headers []int
+	var walk func(field interface{})
+	walk = func(field interface{}) {
+		var tables []*ast.Table
+		switch f := field.(type) {
+		case *ast.Table:
+			tables = []*ast.Table{f}
+		case []*ast.Table:
+			tables = f
+		}
+		for _, t := range tables {
+			if t != d.table && t.Position != (ast.Position{}) {
+				headers = append(headers, d.lineStart(t.Position.Begin))
+			}
+			for _, child := range t.Fields {
+				walk(child)
+			}
+		}
+	}
+	walk(d.table)
+	sort.Ints(headers)
+	return headers
+}
+
+// lastValueEnd returns the end of the last key/value pair defined in table t.
+func lastValueEnd(t *ast.Table) (int, bool) {
+	end, ok := 0, false
+	for _, field := range t.Fields {
+		if kv, isKV := field.(*ast.KeyValue); isKV && kv.Value.End() > end {
+			end, ok = kv.Value.End(), true
+		}
+	}
+	return end, ok
+}
+
+func (d *Document) lineStart(pos int) int {
+	for pos > 0 && d.src[pos-1] != '\n' {
+		pos--
+	}
+	return pos
+}
+
+func (d *Document) lineEnd(pos int) int {
+	for pos < len(d.src) && d.src[pos] != '\n' {
+		pos++
+	}
+	return pos
+}
+
+// newline returns the text needed to terminate the last line of the document.
+func (d *Document) newline() string {
+	if len(d.src) > 0 && d.src[len(d.src)-1] != '\n' {
+		return "\n"
+	}
+	return ""
+}
+
+// edit replaces the text between begin and end.
+func (d *Document) edit(begin, end int, text string) error {
+	src := make([]rune, 0, len(d.src)+len(text))
+	src = append(src, d.src[:begin]...)
+	src = append(src, []rune(text)...)
+	src = append(src, d.src[end:]...)
+	return d.replace(src)
+}
+
+func (d *Document) replace(src []rune) error {
+	old := d.src
+	d.src = src
+	if err := d.reparse(); err != nil {
+		d.src = old
+		return fmt.Errorf("toml: edit produces invalid document: %v", err)
+	}
+	return nil
+}
+
+func (d *Document) reparse() error {
+	t, err := parse([]byte(string(d.src)), &parseOptions{comments: true})
+	if err != nil {
+		return err
+	}
+	d.table = t
+	return nil
+}
+
+// parseValue parses a value in TOML notation. It returns the value and its canonical
+// form, which is written to the document.
+func parseValue(text string) (ast.Value, string, error) {
+	t, err := Parse([]byte("v = " + text))
+	if err != nil || len(t.Fields) != 1 {
+		return nil, "", fmt.Errorf("toml: invalid value %s", text)
+	}
+	v := t.Fields["v"].(*ast.KeyValue).Value
+	var buf bytes.Buffer
+	if err := canonicalInline(&buf, v); err != nil {
+		return nil, "", fmt.Errorf("toml: invalid value %s: %v", text, err)
+	}
+	return v, buf.String(), nil
+}
+
+// SplitKey splits a dotted TOML key like `a."b.c".d` into its components.
+func SplitKey(key string) ([]string, error) {
+	var path []string
+	for rest := strings.TrimSpace(key); ; {
+		var elem string
+		switch {
+		case rest == "":
+			return nil, fmt.Errorf("toml: invalid key %q", key)
+		case rest[0] == '"':
+			end := 1
+			for end < len(rest) && rest[end] != '"' {
+				if rest[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(rest) {
+				return nil, fmt.Errorf("toml: invalid key %q", key)
+			}
+			var err error
+			if elem, err = strconv.Unquote(rest[:end+1]); err != nil {
+				return nil, fmt.Errorf("toml: invalid key %q", key)
+			}
+			rest = rest[end+1:]
+		case rest[0] == '\'':
+			end := strings.IndexByte(rest[1:], '\'')
+			if end < 0 {
+				return nil, fmt.Errorf("toml: invalid key %q", key)
+			}
+			elem, rest = rest[1:end+1], rest[end+2:]
+		default:
+			end := strings.IndexAny(rest, ". \t")
+			if end < 0 {
+				end = len(rest)
+			}
+			elem, rest = rest[:end], rest[end:]
+			if canonicalKey(elem) != elem {
+				return nil, fmt.Errorf("toml: invalid key %q", key)
+			}
+		}
+		path = append(path, elem)
+		rest = strings.TrimSpace(rest)
+		if rest == "" {
+			return path, nil
+		}
+		if rest[0] != '.' {
+			return nil, fmt.Errorf("toml: invalid key %q", key)
+		}
+		rest = strings.TrimSpace(rest[1:])
+	}
+}
@@ -0,0 +1,213 @@
+package toml
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testDocument = `# Service configuration.
+name = "svc" # the name
+
+[server]
+# Listen address.
+host = "localhost"
+port = 80
+limits = { rate = 10, burst = 5 }
+
+[server.tls]
+cert = "a.pem"
+
+[[rule]]
+x = 1
+`
+
+func TestDocumentSet(t *testing.T) {
+	tests := []struct {
+		key, value string
+		want       string
+	}{
+		{"name", `"app"`, `# Service configuration.
+name = "app" # the name
+
+[server]
+# Listen address.
+host = "localhost"
+port = 80
+limits = { rate = 10, burst = 5 }
+
+[server.tls]
+cert = "a.pem"
+
+[[rule]]
+x = 1
+`},
+		{"server.timeout", `30`, `# Service configuration.
+name = "svc" # the name
+
+[server]
+# Listen address.
+host = "localhost"
+port = 80
+limits = { rate = 10, burst = 5 }
+timeout = 30
+
+[server.tls]
+cert = "a.pem"
+
+[[rule]]
+x = 1
+`},
+		{"server.limits.rate", `20`, `# Service configuration.
+name = "svc" # the name
+
+[server]
+# Listen address.
+host = "localhost"
+port = 80
+limits = { burst = 5, rate = 20 }
+
+[server.tls]
+cert = "a.pem"
+
+[[rule]]
+x = 1
+`},
+		{"db.url", `"x"`, testDocument + `
+[db]
+url = "x"
+`},
+		{"version", `2`, `# Service configuration.
+name = "svc" # the name
+version = 2
+
+[server]
+# Listen address.
+host = "localhost"
+port = 80
+limits = { rate = 10, burst = 5 }
+
+[server.tls]
+cert = "a.pem"
+
+[[rule]]
+x = 1
+`},
+	}
+	for _, test := range tests {
+		doc, err := ParseDocument([]byte(testDocument))
+		if err != nil {
+			t.Fatal(err)
+		}
+		path, err := SplitKey(test.key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := doc.Set(path, test.value); err != nil {
+			t.Errorf("Set(%s): %v", test.key, err)
+			continue
+		}
+		if d := checkOutput(doc.Bytes(), []byte(test.want)); d != "" {
+			t.Errorf("Set(%s): wrong output:\n%s", test.key, d)
+		}
+	}
+
+	doc, _ := ParseDocument([]byte(testDocument))
+	if err := doc.Set([]string{"rule", "x"}, "2"); err == nil {
+		t.Error("expected error for key in array table")
+	}
+	if err := doc.Set([]string{"name"}, "not a value"); err == nil {
+		t.Error("expected error for invalid value")
+	}
+	if err := doc.Set([]string{"name"}, "2\nother = 3"); err == nil {
+		t.Error("expected error for value followed by another key")
+	}
+	if err := doc.Set([]string{"version"}, "[1,2] # list"); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(doc.Bytes()), "\nversion = [1, 2]\n") {
+		t.Errorf("value not written in canonical form:\n%s", doc.Bytes())
+	}
+}
+
+func TestDocumentDelete(t *testing.T) {
+	tests := []struct {
+		key  string
+		want string
+	}{
+		{"server.port", `# Service configuration.
+name = "svc" # the name
+
+[server]
+# Listen address.
+host = "localhost"
+limits = { rate = 10, burst = 5 }
+
+[server.tls]
+cert = "a.pem"
+
+[[rule]]
+x = 1
+`},
+		{"server", `# Service configuration.
+name = "svc" # the name
+
+[[rule]]
+x = 1
+`},
+		{"server.limits.burst", `# Service configuration.
+name = "svc" # the name
+
+[server]
+# Listen address.
+host = "localhost"
+port = 80
+limits = { rate = 10 }
+
+[server.tls]
+cert = "a.pem"
+
+[[rule]]
+x = 1
+`},
+	}
+	for _, test := range tests {
+		doc, err := ParseDocument([]byte(testDocument))
+		if err != nil {
+			t.Fatal(err)
+		}
+		path, _ := SplitKey(test.key)
+		if err := doc.Delete(path); err != nil {
+			t.Errorf("Delete(%s): %v", test.key, err)
+			continue
+		}
+		if d := checkOutput(doc.Bytes(), []byte(test.want)); d != "" {
+			t.Errorf("Delete(%s): wrong output:\n%s", test.key, d)
+		}
+	}
+
+	doc, _ := ParseDocument([]byte(testDocument))
+	if err := doc.Delete([]string{"missing"}); err == nil {
+		t.Error("expected error for missing key")
+	}
+}
+
+func TestSplitKey(t *testing.T) {
+	tests := map[string][]string{
+		"a":             {"a"},
+		"a.b-c.d_e":     {"a", "b-c", "d_e"},
+		`a."b.c" . 'd'`: {"a", "b.c", "d"},
+		`"A"`:           {"A"},
+	}
+	for key, want := range tests {
+		got, err := SplitKey(key)
+		if err != nil || !reflect.DeepEqual(got, want) {
+			t.Errorf("SplitKey(%q) = %q, %v; want %q", key, got, err, want)
+		}
+	}
+	for _, key := range []string{"", "a.", ".a", "a b", `"a`, "a$"} {
+		if _, err := SplitKey(key); err == nil {
+			t.Errorf("SplitKey(%q): expected error", key)
+		}
+	}
+}
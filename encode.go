@@ -9,22 +9,42 @@ import (
 	"reflect"
 	"sort"
 	"strconv"
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/naoina/toml/ast"
 )
 
 const (
 	tagOmitempty = "omitempty"
+	tagOmitzero  = "omitzero"
+	tagLayout    = "layout"
+	tagString    = "string"
+	tagLiteral   = "literal"
+	tagOrder     = "order"
+	tagSort      = "sort"
+	tagAlias     = "alias"
+	tagRequired  = "required"
 	tagSkip      = "-"
 )
 
 // Marshal returns the TOML encoding of v.
 //
-// Struct values encode as TOML. Each exported struct field becomes a field of
-// the TOML structure unless
+// Struct values encode as TOML. Exported embedded structs are written as a sub-table
+// named after their type, while the exported fields of unexported embedded structs are
+// written as fields of the embedding struct. Each exported struct field becomes a
+// field of the TOML structure unless
 //   - the field's tag is "-", or
-//   - the field is empty and its tag specifies the "omitempty" option.
+//   - the field is empty and its tag specifies the "omitempty" option, or
+//   - the field is zero and its tag specifies the "omitzero" option.
+//
+// Values of types with an IsZero() bool method, such as time.Time, are empty and zero
+// when IsZero returns true. Structs without such a method are empty when all of their
+// fields are empty. Other values are zero if they are the zero value of their type;
+// unlike "omitempty", "omitzero" keeps empty but non-nil slices and maps. Non-nil
+// pointers are zero if the IsZero method of the pointer returns true, but they are
+// never empty.
 //
 // The "toml" key in the struct field's tag value is the key name, followed by
 // an optional comma and options. Examples:
@@ -42,6 +62,36 @@ const (
 //   // Field appears in TOML as key "field", but the field is skipped if
 //   // empty. Note the leading comma.
 //   Field int `toml:",omitempty"`
+//
+//   // Field is skipped if it is zero. Types with an IsZero method decide
+//   // for themselves.
+//   Field time.Time `toml:",omitzero"`
+//
+//   // Field appears in TOML as key "start" and is formatted using the given
+//   // time layout. The result is written as a datetime if it is valid TOML
+//   // datetime syntax and as a string otherwise. The layout must not contain
+//   // commas. Unmarshal parses the value back using the same layout.
+//   Field time.Time `toml:"start,layout=2006-01-02"`
+//
+//   // Field is written as a literal string, i.e. in single quotes without
+//   // escapes, unless it contains single quotes or control characters.
+//   Field string `toml:",literal"`
+//
+//   // Field is written as a string. Unmarshal accepts both numbers and
+//   // quoted numbers for this field. This works for numeric and boolean fields.
+//   Field int `toml:",string"`
+//
+//   // Fields are written in declaration order. The order option changes the
+//   // position of a field: fields with lower weight are written first. Fields
+//   // without the option have weight zero.
+//   Field int `toml:",order=-1"`
+//
+//   // The elements of the slice are written sorted by the value of their
+//   // "name" key, so the output doesn't depend on the order in memory.
+//   Field []Server `toml:",sort=name"`
+//
+// Iterator functions like iter.Seq[T] are drained and written as arrays. Iterators
+// like iter.Seq2[string, T] are written as tables with the keys in iteration order.
 func (cfg *Config) Marshal(v interface{}) ([]byte, error) {
 	buf := new(bytes.Buffer)
 	err := cfg.NewEncoder(buf).Encode(v)
@@ -68,14 +118,29 @@ func (e *Encoder) Encode(v interface{}) error {
 		err error
 	)
 
+	if t, ok := v.(MarshalerTable); ok {
+		kvs, err := t.MarshalTOMLTable()
+		if err != nil {
+			return err
+		}
+		rv = reflect.ValueOf(kvs)
+	}
 	for rv.Kind() == reflect.Ptr {
 		if rv.IsNil() {
 			return &marshalNilError{rv.Type()}
 		}
 		rv = rv.Elem()
 	}
+	if rv.Kind() == reflect.Func && isSeq(rv.Type()) && !rv.IsNil() {
+		rv = drainSeq(rv)
+	}
 
 	switch rv.Kind() {
+	case reflect.Slice:
+		if rv.Type() != keyValuesType {
+			return &marshalTableError{rv.Type()}
+		}
+		_, err = buf.keyValueFields(e.cfg, rv)
 	case reflect.Struct:
 		_, err = buf.structFields(e.cfg, rv)
 	case reflect.Map:
@@ -88,7 +153,113 @@ func (e *Encoder) Encode(v interface{}) error {
 	if err != nil {
 		return err
 	}
-	return buf.writeTo(e.w, "")
+	w, err := newLineEndingWriter(e.w, e.cfg)
+	if err != nil {
+		return err
+	}
+	if e.cfg.Version != "" {
+		return e.writeVersion(w, buf)
+	}
+	return buf.writeTo(w, e.cfg, "")
+}
+
+// writeVersion writes buf to w after checking that the output only uses features of
+// TOML version cfg.Version.
+func (e *Encoder) writeVersion(w io.Writer, buf *tableBuf) error {
+	if !knownVersion(e.cfg.Version) {
+		return fmt.Errorf("toml: unsupported TOML version %q", e.cfg.Version)
+	}
+	out := new(bytes.Buffer)
+	if err := buf.writeTo(out, e.cfg, ""); err != nil {
+		return err
+	}
+	if _, err := parse(out.Bytes(), &parseOptions{version: e.cfg.Version}); err != nil {
+		if lerr, ok := err.(*LineError); ok {
+			err = lerr.Err
+		}
+		return fmt.Errorf("toml: cannot encode as TOML %s: %v", e.cfg.Version, err)
+	}
+	_, err := out.WriteTo(w)
+	return err
+}
+
+// newLineEndingWriter returns a writer which replaces newlines by cfg.LineEnding.
+func newLineEndingWriter(w io.Writer, cfg *Config) (io.Writer, error) {
+	switch cfg.LineEnding {
+	case "", "\n":
+		return w, nil
+	case "\r\n":
+		return &crlfWriter{w: w}, nil
+	default:
+		return nil, fmt.Errorf("toml: invalid line ending %q", cfg.LineEnding)
+	}
+}
+
+// crlfWriter converts LF line endings to CRLF.
+type crlfWriter struct {
+	w      io.Writer
+	lastCR bool // the previous write ended with \r
+}
+
+func (cw *crlfWriter) Write(p []byte) (int, error) {
+	out := make([]byte, 0, len(p)+bytes.Count(p, []byte("\n")))
+	for i, c := range p {
+		if c == '\n' && !(i == 0 && cw.lastCR || i > 0 && p[i-1] == '\r') {
+			out = append(out, '\r')
+		}
+		out = append(out, c)
+	}
+	if len(p) > 0 {
+		cw.lastCR = p[len(p)-1] == '\r'
+	}
+	if _, err := cw.w.Write(out); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// EncodeValue returns the TOML encoding of a single value, without a key and without
+// a trailing newline. Structs and maps are written as inline tables. This is useful for
+// inserting values into hand-written documents, e.g. by a template engine.
+func (cfg *Config) EncodeValue(v interface{}) ([]byte, error) {
+	// The buffer is set up like an element of a mixed array,
+	// which forces all tables to be written inline.
+	buf := &tableBuf{typ: ast.TableTypeInline, mixedArrayDepth: 1}
+	rv := reflect.ValueOf(v)
+	if !rv.IsValid() {
+		return nil, &marshalNilError{reflect.TypeOf((*interface{})(nil)).Elem()}
+	}
+	if t, ok := v.(MarshalerTable); ok {
+		kvs, err := t.MarshalTOMLTable()
+		if err != nil {
+			return nil, err
+		}
+		rv = reflect.ValueOf(kvs)
+	}
+	if _, err := buf.value(cfg, rv, ""); err != nil {
+		return nil, err
+	}
+	if !bytes.Contains(buf.body, []byte("\n")) {
+		return buf.body, nil
+	}
+	out := new(bytes.Buffer)
+	w, err := newLineEndingWriter(out, cfg)
+	if err != nil {
+		return nil, err
+	}
+	_, err = w.Write(buf.body)
+	return out.Bytes(), err
+}
+
+// EncodeKeyValue returns the TOML encoding of a key/value pair, without a trailing
+// newline. The key is quoted if necessary. See EncodeValue for details.
+func (cfg *Config) EncodeKeyValue(key string, v interface{}) ([]byte, error) {
+	value, err := cfg.EncodeValue(v)
+	if err != nil {
+		return nil, err
+	}
+	out := append([]byte(quoteName(key, cfg.allows11())), " = "...)
+	return append(out, value...), nil
 }
 
 // Marshaler can be implemented to override the encoding of TOML values. The returned text
@@ -106,19 +277,41 @@ type MarshalerRec interface {
 	MarshalTOML() (interface{}, error)
 }
 
+// MarshalerTable can be implemented to control the content of the table emitted for a
+// type. The returned key/value pairs are written in order, in place of the fields of
+// the receiver.
+type MarshalerTable interface {
+	MarshalTOMLTable() ([]KeyValue, error)
+}
+
+// KeyValue is a key/value pair of a table. A []KeyValue is encoded as a table
+// containing the pairs in slice order.
+type KeyValue struct {
+	Key   string
+	Value interface{}
+}
+
+var keyValuesType = reflect.TypeOf([]KeyValue(nil))
+
 type tableBuf struct {
-	name string // already escaped / quoted
+	name string   // already escaped / quoted
+	path []string // unescaped key path of the table
 	typ  ast.TableType
 
 	body     []byte      // text below table header
+	keyLines []keyLine   // key/value lines in body
 	children []*tableBuf // sub-tables of this table
 
 	arrayDepth      int // if > 0 in value(x), x is contained in an array.
 	mixedArrayDepth int // if > 0 in value(x), x is contained in a mixed array.
+	inlineDepth     int // nesting depth of inline tables
+
+	commented    bool // if true, the table is written as comments.
+	commentDepth int  // if > 0 in value(x), x is an example of an unset value.
 }
 
 // writeTo writes b and all of its children to w.
-func (b *tableBuf) writeTo(w io.Writer, prefix string) error {
+func (b *tableBuf) writeTo(w io.Writer, cfg *Config, prefix string) error {
 	key := b.name // TODO: escape dots
 	if prefix != "" {
 		key = prefix + "." + key
@@ -130,30 +323,99 @@ func (b *tableBuf) writeTo(w io.Writer, prefix string) error {
 			head = "[" + head + "]"
 		}
 		head += "\n"
+		if b.commented {
+			head = "# " + head
+		}
 		if _, err := io.WriteString(w, head); err != nil {
 			return err
 		}
 	}
-	if _, err := w.Write(b.body); err != nil {
+	body := b.body
+	if cfg.AlignEquals {
+		body = b.alignEquals()
+	}
+	if b.commented {
+		body = commentLines(body)
+	}
+	if _, err := w.Write(body); err != nil {
 		return err
 	}
 
-	for i, child := range b.children {
+	for i, child := range b.sortedChildren(cfg) {
 		if len(b.body) > 0 || i > 0 {
 			if _, err := w.Write([]byte("\n")); err != nil {
 				return err
 			}
 		}
-		if err := child.writeTo(w, key); err != nil {
+		if err := child.writeTo(w, cfg, key); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
+// alignEquals pads the keys of the key/value lines of b so that the equals signs line
+// up. Only the lines recorded by tableBuf.field are changed, other text like multi-line
+// strings is copied as is.
+func (b *tableBuf) alignEquals() []byte {
+	width := 0
+	for _, kl := range b.keyLines {
+		if w := utf8.RuneCount(b.body[kl.begin:kl.end]); w > width {
+			width = w
+		}
+	}
+	var out []byte
+	prev := 0
+	for _, kl := range b.keyLines {
+		out = append(out, b.body[prev:kl.end]...)
+		pad := width - utf8.RuneCount(b.body[kl.begin:kl.end])
+		out = append(out, bytes.Repeat([]byte(" "), pad)...)
+		prev = kl.end
+	}
+	return append(out, b.body[prev:]...)
+}
+
+// keyLine is the location of a key/value line in tableBuf.body.
+type keyLine struct {
+	begin int // start of the line
+	end   int // end of the key, including any comment prefix
+}
+
+// commentLines prefixes all lines of text with "# ".
+func commentLines(text []byte) []byte {
+	var out []byte
+	for _, line := range bytes.SplitAfter(text, []byte("\n")) {
+		if len(line) > 0 {
+			out = append(out, "# "...)
+			out = append(out, line...)
+		}
+	}
+	return out
+}
+
+// sortedChildren returns the children of b in the order configured by cfg.TableOrder.
+func (b *tableBuf) sortedChildren(cfg *Config) []*tableBuf {
+	var less func(c1, c2 *tableBuf) bool
+	switch cfg.TableOrder {
+	case TableOrderAlphabetical:
+		less = func(c1, c2 *tableBuf) bool { return c1.name < c2.name }
+	case TableOrderTablesFirst:
+		less = func(c1, c2 *tableBuf) bool { return c1.typ < c2.typ }
+	case TableOrderArrayTablesFirst:
+		less = func(c1, c2 *tableBuf) bool { return c1.typ > c2.typ }
+	default:
+		return b.children
+	}
+	// The sort is stable, so elements of an array table are never separated.
+	children := append([]*tableBuf(nil), b.children...)
+	sort.SliceStable(children, func(i, j int) bool { return less(children[i], children[j]) })
+	return children
+}
+
 // newChild creates a new child table of b.
-func (b *tableBuf) newChild(name string) *tableBuf {
-	child := &tableBuf{name: quoteName(name), typ: ast.TableTypeNormal}
+func (b *tableBuf) newChild(cfg *Config, name string) *tableBuf {
+	child := &tableBuf{name: quoteName(name, cfg.allows11()), path: b.keyPath(name), typ: ast.TableTypeNormal}
+	child.commented = b.commented || b.commentDepth > 0
 	if b.arrayDepth > 0 {
 		child.typ = ast.TableTypeArray
 		// Note: arrayDepth does not inherit into child tables!
@@ -161,6 +423,7 @@ func (b *tableBuf) newChild(name string) *tableBuf {
 	if b.mixedArrayDepth > 0 {
 		child.typ = ast.TableTypeInline
 		child.mixedArrayDepth = b.mixedArrayDepth
+		child.inlineDepth = b.inlineDepth + 1
 		b.body = append(b.body, '{')
 	}
 	return child
@@ -173,6 +436,10 @@ func (b *tableBuf) addChild(cfg *Config, child *tableBuf) {
 	// Inline tables are not tracked in b.children.
 	if child.typ == ast.TableTypeInline {
 		b.body = append(b.body, child.body...)
+		if cfg.multilineInlineTables() && len(child.body) > 0 {
+			b.body = append(b.body, ',')
+			b.body = appendInlineIndent(b.body, b.inlineDepth)
+		}
 		b.body = append(b.body, '}')
 		return
 	}
@@ -190,41 +457,306 @@ func (b *tableBuf) addChild(cfg *Config, child *tableBuf) {
 	b.children = append(b.children, child)
 }
 
+// keyPath returns the key path of key name in b.
+func (b *tableBuf) keyPath(name string) []string {
+	path := make([]string, len(b.path)+1)
+	copy(path, b.path)
+	path[len(b.path)] = name
+	return path
+}
+
+// filterValue applies cfg.FilterValue to the value of key name in b.
+// It returns the value that should be written and whether to write it at all.
+func (b *tableBuf) filterValue(cfg *Config, name string, rv reflect.Value) (reflect.Value, bool) {
+	if cfg.FilterValue == nil {
+		return rv, true
+	}
+	replacement, omit := cfg.FilterValue(b.keyPath(name), rv)
+	if omit {
+		return rv, false
+	}
+	if replacement != nil {
+		rv = reflect.ValueOf(replacement)
+	}
+	return rv, true
+}
+
 // structFields writes applicable fields of a struct.
 func (b *tableBuf) structFields(cfg *Config, rv reflect.Value) (newTables []*tableBuf, err error) {
+	var index int
+	return b.structFieldsAt(cfg, rv, &index, "", make(map[string]bool))
+}
+
+// structFieldsAt is like structFields, but continues with the given number of fields
+// written before. The exported fields of unexported embedded structs are written as
+// fields of rv. Only fields with a dotted key starting with prefix are written, and
+// fields with further dots in their key are written as sub-tables. The keys written so
+// far are tracked in keys, which maps them to true for tables of dotted keys.
+func (b *tableBuf) structFieldsAt(cfg *Config, rv reflect.Value, index *int, prefix string, keys map[string]bool) (newTables []*tableBuf, err error) {
 	rt := rv.Type()
-	for i := 0; i < rv.NumField(); i++ {
+	order, err := fieldOrder(cfg, rt)
+	if err != nil {
+		return nil, err
+	}
+	for _, i := range order {
 		// Check if the field should be written at all.
 		ft := rt.Field(i)
-		if ft.PkgPath != "" && !ft.Anonymous { // not exported
+		if ft.Anonymous && ft.PkgPath != "" && ft.Type.Kind() == reflect.Struct {
+			tables, err := b.structFieldsAt(cfg, rv.Field(i), index, prefix, keys)
+			newTables = append(newTables, tables...)
+			if err != nil {
+				return newTables, err
+			}
+			continue
+		}
+		name, opts, ok := encodedFieldKey(cfg, rt, ft)
+		if !ok || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		name = name[len(prefix):]
+		if dot := strings.IndexByte(name, '.'); dot >= 0 {
+			if table, ok := keys[name[:dot]]; ok {
+				if table {
+					continue
+				}
+				return newTables, fmt.Errorf("toml: field %v.%s: key `%s' is already used by another field", rt, ft.Name, prefix+name[:dot])
+			}
+			keys[name[:dot]] = true
+			b.inlineSeparator(cfg, *index)
+			tables, err := b.dottedTable(cfg, rv, name[:dot], prefix)
+			newTables = append(newTables, tables...)
+			if err != nil {
+				return newTables, err
+			}
+			*index++
 			continue
 		}
-		name, rest := extractTag(ft.Tag.Get(fieldTagName))
-		if name == tagSkip {
+		if keys[name] {
+			return newTables, fmt.Errorf("toml: field %v.%s: key `%s' is already used for dotted keys", rt, ft.Name, prefix+name)
+		}
+		keys[name] = false
+		fv, ok := b.filterValue(cfg, name, rv.Field(i))
+		if !ok {
 			continue
 		}
-		fv := rv.Field(i)
-		if rest == tagOmitempty && isEmptyValue(fv) {
+		if cfg.CommentUnset && fv.Kind() == reflect.Ptr && fv.IsNil() && b.typ != ast.TableTypeInline {
+			tables, err := b.commentedField(cfg, name, fv.Type().Elem())
+			if err != nil {
+				return newTables, err
+			}
+			newTables = append(newTables, tables...)
 			continue
 		}
-		if name == "" {
-			name = cfg.FieldToKey(rt, ft.Name)
+		if opts.has(tagOmitempty) && isEmptyValue(fv) {
+			continue
+		}
+		if opts.has(tagOmitzero) && isZeroValue(fv) {
+			continue
+		}
+		if layout, ok := opts.lookup(tagLayout); ok {
+			fv = applyTimeLayout(fv, layout)
+		}
+		if opts.has(tagString) {
+			fv = quoteValue(fv)
+		}
+		if opts.has(tagLiteral) {
+			fv = literalValue(fv)
+		}
+		if key, ok := opts.lookup(tagSort); ok {
+			if fv, err = sortedElements(cfg, fv, key); err != nil {
+				return newTables, fmt.Errorf("toml: field %v.%s: %v", rt, ft.Name, err)
+			}
 		}
 
 		// If the current table is inline, write separators.
-		if b.typ == ast.TableTypeInline && i > 0 {
-			b.body = append(b.body, ", "...)
-		}
+		b.inlineSeparator(cfg, *index)
 		// Write the key/value pair.
 		tables, err := b.field(cfg, name, fv)
 		if err != nil {
 			return newTables, err
 		}
 		newTables = append(newTables, tables...)
+		*index++
 	}
 	return newTables, nil
 }
 
+// dottedTable writes the fields of struct rv whose dotted key starts with prefix and
+// name as table name.
+func (b *tableBuf) dottedTable(cfg *Config, rv reflect.Value, name, prefix string) ([]*tableBuf, error) {
+	off := len(b.body)
+	b.body = append(b.body, quoteName(name, cfg.allows11())...)
+	b.body = append(b.body, " = "...)
+	child := b.newChild(cfg, name)
+	var index int
+	tables, err := child.structFieldsAt(cfg, rv, &index, prefix+name+".", make(map[string]bool))
+	b.addChild(cfg, child)
+	if child.typ == ast.TableTypeInline {
+		return nil, err
+	}
+	b.body = b.body[:off]
+	return append(tables, child), err
+}
+
+// encodedFieldKey returns the key and tag options of struct field ft of type rt.
+// It returns ok == false if the field isn't written.
+func encodedFieldKey(cfg *Config, rt reflect.Type, ft reflect.StructField) (name string, opts tagOptions, ok bool) {
+	if ft.PkgPath != "" { // not exported
+		return "", "", false
+	}
+	name, opts = extractTag(ft.Tag.Get(cfg.tagName()))
+	if name == tagSkip || cfg.Protobuf && isProtobufInternal(ft) {
+		return "", "", false
+	}
+	if pbName, _, ok := protobufNames(ft); ok && cfg.Protobuf && name == "" {
+		name = pbName
+	}
+	if name == "" {
+		name = cfg.FieldToKey(rt, ft.Name)
+	}
+	return name, opts, true
+}
+
+// sortedElements returns a copy of slice or array rv with the elements sorted by the
+// value of their key. Elements without the key are placed last.
+func sortedElements(cfg *Config, rv reflect.Value, key string) (reflect.Value, error) {
+	for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return rv, nil
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return rv, fmt.Errorf("option %s requires a slice or array, not %v", tagSort, rv.Type())
+	}
+	sorted := reflect.MakeSlice(reflect.SliceOf(rv.Type().Elem()), rv.Len(), rv.Len())
+	reflect.Copy(sorted, rv)
+	keys := make([]reflect.Value, rv.Len())
+	for i := range keys {
+		keys[i] = elementKey(cfg, rv.Index(i), key)
+	}
+	index := make([]int, len(keys))
+	for i := range index {
+		index[i] = i
+	}
+	sort.SliceStable(index, func(i, j int) bool { return lessSortKey(keys[index[i]], keys[index[j]]) })
+	for i, k := range index {
+		sorted.Index(i).Set(rv.Index(k))
+	}
+	return sorted, nil
+}
+
+// elementKey returns the value of key in struct or map rv, or an invalid value if there
+// is no such key.
+func elementKey(cfg *Config, rv reflect.Value, key string) reflect.Value {
+	for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return reflect.Value{}
+		}
+		rv = rv.Elem()
+	}
+	switch rv.Kind() {
+	case reflect.Struct:
+		rt := rv.Type()
+		for i := 0; i < rt.NumField(); i++ {
+			if name, _, ok := encodedFieldKey(cfg, rt, rt.Field(i)); ok && name == key {
+				return indirectValue(rv.Field(i))
+			}
+		}
+	case reflect.Map:
+		if rv.Type().Key().Kind() == reflect.String {
+			return indirectValue(rv.MapIndex(reflect.ValueOf(key).Convert(rv.Type().Key())))
+		}
+	}
+	return reflect.Value{}
+}
+
+func indirectValue(rv reflect.Value) reflect.Value {
+	for rv.IsValid() && (rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface) {
+		rv = rv.Elem()
+	}
+	return rv
+}
+
+// lessSortKey orders the values of sort keys. Numbers and strings are compared by
+// value, other types by their formatted value.
+func lessSortKey(a, b reflect.Value) bool {
+	if !a.IsValid() || !b.IsValid() {
+		return a.IsValid()
+	}
+	switch {
+	case isIntKind(a.Kind()) && isIntKind(b.Kind()):
+		return a.Int() < b.Int()
+	case isUintKind(a.Kind()) && isUintKind(b.Kind()):
+		return a.Uint() < b.Uint()
+	case isFloatKind(a.Kind()) && isFloatKind(b.Kind()):
+		return a.Float() < b.Float()
+	case a.Kind() == reflect.String && b.Kind() == reflect.String:
+		return a.String() < b.String()
+	}
+	return fmt.Sprint(a.Interface()) < fmt.Sprint(b.Interface())
+}
+
+func isIntKind(k reflect.Kind) bool {
+	return k >= reflect.Int && k <= reflect.Int64
+}
+
+func isUintKind(k reflect.Kind) bool {
+	return k >= reflect.Uint && k <= reflect.Uintptr
+}
+
+func isFloatKind(k reflect.Kind) bool {
+	return k == reflect.Float32 || k == reflect.Float64
+}
+
+// fieldOrder returns the indexes of the fields of struct type rt in the order they
+// should be written. Fields are sorted by the weight given in the "order" tag option.
+// Fields with equal weight, and fields without the option (weight zero), retain
+// their declaration order.
+func fieldOrder(cfg *Config, rt reflect.Type) ([]int, error) {
+	order := make([]int, rt.NumField())
+	weights := make([]int, rt.NumField())
+	sorted := true
+	for i := range order {
+		order[i] = i
+		_, opts := extractTag(rt.Field(i).Tag.Get(cfg.tagName()))
+		if w, ok := opts.lookup(tagOrder); ok {
+			var err error
+			if weights[i], err = strconv.Atoi(w); err != nil {
+				return nil, fmt.Errorf("toml: invalid order %q in tag of field %v.%s", w, rt, rt.Field(i).Name)
+			}
+		}
+		if i > 0 && weights[i] < weights[i-1] {
+			sorted = false
+		}
+	}
+	if !sorted {
+		sort.SliceStable(order, func(i, j int) bool { return weights[order[i]] < weights[order[j]] })
+	}
+	return order, nil
+}
+
+// commentedField writes a commented-out key/value pair for a nil pointer field.
+// The zero value of typ is used as the example value.
+func (b *tableBuf) commentedField(cfg *Config, name string, typ reflect.Type) ([]*tableBuf, error) {
+	if b.commented || b.commentDepth > 0 {
+		// Examples don't nest.
+		return nil, nil
+	}
+	b.commentDepth++
+	defer func() { b.commentDepth-- }()
+
+	off := len(b.body)
+	tables, err := b.field(cfg, name, reflect.Zero(typ))
+	if len(tables) == 0 && len(b.body) > off {
+		b.body = append(b.body[:off], append([]byte("# "), b.body[off:]...)...)
+		if n := len(b.keyLines); n > 0 && b.keyLines[n-1].begin == off {
+			b.keyLines[n-1].end += 2
+		}
+	}
+	return tables, err
+}
+
 // mapFields writes the content of a map.
 func (b *tableBuf) mapFields(cfg *Config, rv reflect.Value) ([]*tableBuf, error) {
 	// Marshal and sort the keys first.
@@ -243,12 +775,40 @@ func (b *tableBuf) mapFields(cfg *Config, rv reflect.Value) ([]*tableBuf, error)
 	var newTables []*tableBuf
 	var index int
 	for _, kv := range keylist {
+		value, ok := b.filterValue(cfg, kv.key, kv.value)
+		if !ok {
+			continue
+		}
+		if cfg.SkipNilMapValues && isNil(value) {
+			continue
+		}
 		// If the current table is inline, add separators.
-		if b.typ == ast.TableTypeInline && index > 0 {
-			b.body = append(b.body, ", "...)
+		b.inlineSeparator(cfg, index)
+		// Write the key/value pair.
+		tables, err := b.field(cfg, kv.key, value)
+		if err != nil {
+			return newTables, err
 		}
+		newTables = append(newTables, tables...)
+		index++
+	}
+	return newTables, nil
+}
+
+// keyValueFields writes the content of a []KeyValue.
+func (b *tableBuf) keyValueFields(cfg *Config, rv reflect.Value) ([]*tableBuf, error) {
+	var newTables []*tableBuf
+	var index int
+	for i := 0; i < rv.Len(); i++ {
+		kv := rv.Index(i).Interface().(KeyValue)
+		value, ok := b.filterValue(cfg, kv.Key, rv.Index(i).Field(1))
+		if !ok {
+			continue
+		}
+		// If the current table is inline, add separators.
+		b.inlineSeparator(cfg, index)
 		// Write the key/value pair.
-		tables, err := b.field(cfg, kv.key, kv.value)
+		tables, err := b.field(cfg, kv.Key, value)
 		if err != nil {
 			return newTables, err
 		}
@@ -258,10 +818,36 @@ func (b *tableBuf) mapFields(cfg *Config, rv reflect.Value) ([]*tableBuf, error)
 	return newTables, nil
 }
 
+// inlineSeparator writes the separator before the key/value pair at index of an
+// inline table.
+func (b *tableBuf) inlineSeparator(cfg *Config, index int) {
+	switch {
+	case b.typ != ast.TableTypeInline:
+	case cfg.multilineInlineTables():
+		if index > 0 {
+			b.body = append(b.body, ',')
+		}
+		b.body = appendInlineIndent(b.body, b.inlineDepth)
+	case index > 0:
+		b.body = append(b.body, ", "...)
+	}
+}
+
+// appendInlineIndent starts a new line of a multi-line inline table at the given
+// nesting depth.
+func appendInlineIndent(buf []byte, depth int) []byte {
+	buf = append(buf, '\n')
+	for i := 0; i < depth; i++ {
+		buf = append(buf, "  "...)
+	}
+	return buf
+}
+
 // field writes a key/value pair.
 func (b *tableBuf) field(cfg *Config, name string, rv reflect.Value) ([]*tableBuf, error) {
 	off := len(b.body)
-	b.body = append(b.body, quoteName(name)...)
+	b.body = append(b.body, quoteName(name, cfg.allows11())...)
+	keyEnd := len(b.body)
 	b.body = append(b.body, " = "...)
 	tables, err := b.value(cfg, rv, name)
 	switch {
@@ -273,12 +859,20 @@ func (b *tableBuf) field(cfg *Config, name string, rv reflect.Value) ([]*tableBu
 	default:
 		// Regular key/value pair in table.
 		b.body = append(b.body, '\n')
+		b.keyLines = append(b.keyLines, keyLine{off, keyEnd})
 	}
 	return tables, err
 }
 
 // value writes a plain value.
 func (b *tableBuf) value(cfg *Config, rv reflect.Value, name string) ([]*tableBuf, error) {
+	if rv.Type() == rawMessageType {
+		return b.rawMessage(cfg, rv.Bytes(), name)
+	}
+	if body, ok := appendBig(b.body, rv); ok {
+		b.body = body
+		return nil, nil
+	}
 	isMarshaler, tables, err := b.marshaler(cfg, rv, name)
 	if isMarshaler {
 		return tables, err
@@ -303,7 +897,11 @@ func (b *tableBuf) value(cfg *Config, rv reflect.Value, name string) ([]*tableBu
 		return nil, nil
 
 	case k == reflect.String:
-		b.body = strconv.AppendQuote(b.body, rv.String())
+		if cfg.LiteralStrings && strings.ContainsRune(rv.String(), '\\') && canBeLiteral(rv.String()) {
+			b.body = appendLiteral(b.body, rv.String())
+		} else {
+			b.body = appendQuote(b.body, rv.String(), cfg.allows11())
+		}
 		return nil, nil
 
 	case k == reflect.Ptr || k == reflect.Interface:
@@ -312,11 +910,21 @@ func (b *tableBuf) value(cfg *Config, rv reflect.Value, name string) ([]*tableBu
 		}
 		return b.value(cfg, rv.Elem(), name)
 
+	case rv.Type() == keyValuesType:
+		child := b.newChild(cfg, name)
+		tables, err := child.keyValueFields(cfg, rv)
+		b.addChild(cfg, child)
+		if child.typ == ast.TableTypeInline {
+			return nil, err
+		}
+		tables = append(tables, child)
+		return tables, err
+
 	case k == reflect.Slice || k == reflect.Array:
 		return b.array(cfg, rv, name)
 
 	case k == reflect.Struct:
-		child := b.newChild(name)
+		child := b.newChild(cfg, name)
 		tables, err := child.structFields(cfg, rv)
 		b.addChild(cfg, child)
 		if child.typ == ast.TableTypeInline {
@@ -326,7 +934,7 @@ func (b *tableBuf) value(cfg *Config, rv reflect.Value, name string) ([]*tableBu
 		return tables, err
 
 	case k == reflect.Map:
-		child := b.newChild(name)
+		child := b.newChild(cfg, name)
 		tables, err := child.mapFields(cfg, rv)
 		b.addChild(cfg, child)
 		if child.typ == ast.TableTypeInline {
@@ -335,11 +943,58 @@ func (b *tableBuf) value(cfg *Config, rv reflect.Value, name string) ([]*tableBu
 		tables = append(tables, child)
 		return tables, err
 
+	case k == reflect.Func && isSeq(rv.Type()):
+		if rv.IsNil() {
+			return nil, &marshalNilError{rv.Type()}
+		}
+		return b.value(cfg, drainSeq(rv), name)
+
 	default:
 		return nil, fmt.Errorf("toml: marshal: unsupported type %v", rv.Kind())
 	}
 }
 
+// isSeq reports whether rt is an iterator function like iter.Seq[T], i.e.
+// func(yield func(T) bool), or like iter.Seq2[K, T] with a string key type.
+func isSeq(rt reflect.Type) bool {
+	if rt.Kind() != reflect.Func || rt.NumIn() != 1 || rt.NumOut() != 0 {
+		return false
+	}
+	yield := rt.In(0)
+	if yield.Kind() != reflect.Func || yield.NumOut() != 1 || yield.Out(0).Kind() != reflect.Bool {
+		return false
+	}
+	switch yield.NumIn() {
+	case 1:
+		return true
+	case 2:
+		return yield.In(0).Kind() == reflect.String
+	}
+	return false
+}
+
+// drainSeq calls iterator function rv and collects the values it yields. Single values
+// are returned as a slice, key/value pairs as a []KeyValue so they keep their order.
+func drainSeq(rv reflect.Value) reflect.Value {
+	yield := rv.Type().In(0)
+	var result reflect.Value
+	if yield.NumIn() == 1 {
+		result = reflect.MakeSlice(reflect.SliceOf(yield.In(0)), 0, 0)
+		rv.Call([]reflect.Value{reflect.MakeFunc(yield, func(args []reflect.Value) []reflect.Value {
+			result = reflect.Append(result, args[0])
+			return []reflect.Value{reflect.ValueOf(true)}
+		})})
+	} else {
+		var kvs []KeyValue
+		rv.Call([]reflect.Value{reflect.MakeFunc(yield, func(args []reflect.Value) []reflect.Value {
+			kvs = append(kvs, KeyValue{args[0].String(), args[1].Interface()})
+			return []reflect.Value{reflect.ValueOf(true)}
+		})})
+		result = reflect.ValueOf(kvs)
+	}
+	return result
+}
+
 func (b *tableBuf) array(cfg *Config, rv reflect.Value, name string) ([]*tableBuf, error) {
 	rvlen := rv.Len()
 	if rvlen == 0 {
@@ -432,7 +1087,10 @@ func (b *tableBuf) mixedArray(cfg *Config, rv reflect.Value, name string) error
 
 // marshaler writes a value that implements any of the marshaler interfaces.
 func (b *tableBuf) marshaler(cfg *Config, rv reflect.Value, name string) (handled bool, newTables []*tableBuf, err error) {
-	switch t := rv.Interface().(type) {
+	if (rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface) && rv.IsNil() {
+		return false, nil, nil
+	}
+	switch t := marshalerInterface(rv).(type) {
 	case encoding.TextMarshaler:
 		enc, err := t.MarshalText()
 		if err != nil {
@@ -440,6 +1098,13 @@ func (b *tableBuf) marshaler(cfg *Config, rv reflect.Value, name string) (handle
 		}
 		b.body = encodeTextMarshaler(b.body, string(enc))
 		return true, nil, nil
+	case MarshalerTable:
+		kvs, err := t.MarshalTOMLTable()
+		if err != nil {
+			return true, nil, err
+		}
+		newTables, err = b.value(cfg, reflect.ValueOf(kvs), name)
+		return true, newTables, err
 	case MarshalerRec:
 		newval, err := t.MarshalTOML()
 		if err != nil {
@@ -455,9 +1120,169 @@ func (b *tableBuf) marshaler(cfg *Config, rv reflect.Value, name string) (handle
 		b.body = append(b.body, enc...)
 		return true, nil, nil
 	}
+	if cfg.MarshalStringers {
+		if s, ok := stringer(rv); ok {
+			b.body = appendQuote(b.body, s.String(), cfg.allows11())
+			return true, nil, nil
+		}
+	}
 	return false, nil, nil
 }
 
+var stringerType = reflect.TypeOf((*fmt.Stringer)(nil)).Elem()
+
+// stringer returns rv as a fmt.Stringer if its type has a String method. Like
+// marshalerInterface, it takes the address of rv for methods with pointer receivers.
+func stringer(rv reflect.Value) (fmt.Stringer, bool) {
+	if rv.Type().Implements(stringerType) {
+		return rv.Interface().(fmt.Stringer), true
+	}
+	if rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface || !reflect.PtrTo(rv.Type()).Implements(stringerType) {
+		return nil, false
+	}
+	if !rv.CanAddr() {
+		ptr := reflect.New(rv.Type())
+		ptr.Elem().Set(rv)
+		rv = ptr.Elem()
+	}
+	return rv.Addr().Interface().(fmt.Stringer), true
+}
+
+var marshalerTypes = []reflect.Type{
+	reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem(),
+	reflect.TypeOf((*MarshalerTable)(nil)).Elem(),
+	reflect.TypeOf((*MarshalerRec)(nil)).Elem(),
+	reflect.TypeOf((*Marshaler)(nil)).Elem(),
+}
+
+// marshalerInterface returns the value of rv as an interface. If rv is not a pointer
+// and its marshaler methods are defined on the pointer receiver, a pointer to rv is
+// returned instead. Non-addressable values are copied to make this work.
+func marshalerInterface(rv reflect.Value) interface{} {
+	if rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+		return rv.Interface()
+	}
+	ptrType := reflect.PtrTo(rv.Type())
+	for _, mt := range marshalerTypes {
+		if rv.Type().Implements(mt) {
+			break
+		}
+		if ptrType.Implements(mt) {
+			if rv.CanAddr() {
+				return rv.Addr().Interface()
+			}
+			ptr := reflect.New(rv.Type())
+			ptr.Elem().Set(rv)
+			return ptr.Interface()
+		}
+	}
+	return rv.Interface()
+}
+
+// quoteValue converts numbers and booleans to strings.
+func quoteValue(rv reflect.Value) reflect.Value {
+	for rv.Kind() == reflect.Ptr && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	var s []byte
+	switch k := rv.Kind(); {
+	case k >= reflect.Int && k <= reflect.Int64:
+		s = strconv.AppendInt(s, rv.Int(), 10)
+	case k >= reflect.Uint && k <= reflect.Uintptr:
+		s = strconv.AppendUint(s, rv.Uint(), 10)
+	case k >= reflect.Float32 && k <= reflect.Float64:
+		s = appendFloat(s, rv.Float())
+	case k == reflect.Bool:
+		s = strconv.AppendBool(s, rv.Bool())
+	default:
+		return rv
+	}
+	return reflect.ValueOf(string(s))
+}
+
+// literalString is a string written as a literal string if possible.
+type literalString string
+
+// literalValue wraps strings in literalString.
+func literalValue(rv reflect.Value) reflect.Value {
+	for rv.Kind() == reflect.Ptr && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.String {
+		return rv
+	}
+	return reflect.ValueOf(literalString(rv.String()))
+}
+
+// MarshalTOML implements Marshaler.
+func (s literalString) MarshalTOML() ([]byte, error) {
+	if canBeLiteral(string(s)) {
+		return appendLiteral(nil, string(s)), nil
+	}
+	return appendQuote(nil, string(s), false), nil
+}
+
+// canBeLiteral reports whether s can be written as a single-line literal string,
+// which cannot contain single quotes or control characters other than tab.
+func canBeLiteral(s string) bool {
+	for _, r := range s {
+		if r == '\'' || r == utf8.RuneError || r < 0x20 && r != '\t' || r == 0x7F {
+			return false
+		}
+	}
+	return true
+}
+
+func appendLiteral(buf []byte, s string) []byte {
+	buf = append(buf, '\'')
+	buf = append(buf, s...)
+	return append(buf, '\'')
+}
+
+// layoutTime is a time with a custom layout given by the struct tag.
+type layoutTime struct {
+	t      time.Time
+	layout string
+}
+
+// applyTimeLayout wraps time.Time values in layoutTime.
+func applyTimeLayout(rv reflect.Value, layout string) reflect.Value {
+	for rv.Kind() == reflect.Ptr && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	if rv.Type() != timeType {
+		return rv
+	}
+	return reflect.ValueOf(layoutTime{rv.Interface().(time.Time), layout})
+}
+
+// MarshalTOML implements Marshaler. The time is written as a TOML datetime if
+// the layout allows it, and as a string otherwise.
+func (lt layoutTime) MarshalTOML() ([]byte, error) {
+	s := lt.t.Format(lt.layout)
+	if isDatetime(s) {
+		return []byte(s), nil
+	}
+	return appendQuote(nil, s, false), nil
+}
+
+var datetimeLayouts = []string{
+	"2006-01-02T15:04:05.999999999Z07:00",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02",
+	"15:04:05.999999999",
+}
+
+// isDatetime reports whether s is a valid TOML datetime.
+func isDatetime(s string) bool {
+	for _, layout := range datetimeLayouts {
+		if _, err := time.Parse(layout, s); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
 func encodeTextMarshaler(buf []byte, v string) []byte {
 	// Emit the value without quotes if possible.
 	if v == "true" || v == "false" {
@@ -471,7 +1296,7 @@ func encodeTextMarshaler(buf []byte, v string) []byte {
 	} else if _, err := strconv.ParseFloat(v, 64); err == nil {
 		return append(buf, v...)
 	}
-	return strconv.AppendQuote(buf, v)
+	return appendQuote(buf, v, false)
 }
 
 func encodeMapKey(rv reflect.Value) (string, error) {
@@ -491,7 +1316,65 @@ func encodeMapKey(rv reflect.Value) (string, error) {
 	return "", fmt.Errorf("toml: invalid map key type %v", rv.Type())
 }
 
+// isNil reports whether rv is a nil pointer or interface, possibly wrapped in
+// further interfaces.
+func isNil(rv reflect.Value) bool {
+	for rv.Kind() == reflect.Interface && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	return (rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface) && rv.IsNil()
+}
+
+type isZeroer interface {
+	IsZero() bool
+}
+
+var isZeroerType = reflect.TypeOf((*isZeroer)(nil)).Elem()
+
+// callIsZero calls the IsZero method of v. It returns false if v doesn't have the method.
+// Methods with pointer receivers are called on a copy if v isn't addressable. Like
+// encoding/json/v2, the method is called for non-nil pointers, while nil pointers are
+// left to the caller.
+func callIsZero(v reflect.Value) (isZero, ok bool) {
+	k := v.Kind()
+	if k == reflect.Interface || !v.CanInterface() {
+		return false, false
+	}
+	if k == reflect.Ptr {
+		if v.IsNil() || !v.Type().Implements(isZeroerType) {
+			return false, false
+		}
+		return v.Interface().(isZeroer).IsZero(), true
+	}
+	if v.Type().Implements(isZeroerType) {
+		return v.Interface().(isZeroer).IsZero(), true
+	}
+	if reflect.PtrTo(v.Type()).Implements(isZeroerType) {
+		if !v.CanAddr() {
+			cp := reflect.New(v.Type()).Elem()
+			cp.Set(v)
+			v = cp
+		}
+		return v.Addr().Interface().(isZeroer).IsZero(), true
+	}
+	return false, false
+}
+
+// isZeroValue reports whether v is zero for the purpose of the "omitzero" option.
+func isZeroValue(v reflect.Value) bool {
+	if isZero, ok := callIsZero(v); ok {
+		return isZero
+	}
+	return v.IsZero()
+}
+
 func isEmptyValue(v reflect.Value) bool {
+	// Types like time.Time know best whether they're empty. Pointers are only empty if
+	// they are nil.
+	if isZero, ok := callIsZero(v); ok && v.Kind() != reflect.Ptr {
+		return isZero
+	}
+
 	switch v.Kind() {
 	case reflect.Array:
 		// encoding/json treats all arrays with non-zero length as non-empty. We check the
@@ -515,6 +1398,14 @@ func isEmptyValue(v reflect.Value) bool {
 		return v.Float() == 0
 	case reflect.Interface, reflect.Ptr:
 		return v.IsNil()
+	case reflect.Struct:
+		// Structs are empty when all of their fields are.
+		for i := 0; i < v.NumField(); i++ {
+			if !isEmptyValue(v.Field(i)) {
+				return false
+			}
+		}
+		return true
 	}
 	return false
 }
@@ -532,19 +1423,83 @@ func appendFloat(out []byte, v float64) []byte {
 	return strconv.AppendFloat(out, v, 'e', -1, 64)
 }
 
-func quoteName(s string) string {
+// appendQuote appends s as a TOML basic string. It uses the escapes of strconv.Quote
+// except for those which TOML doesn't have: \a and \v become \u escapes, and so do
+// \xHH escapes of control characters unless escapes11 is set. With escapes11, the
+// escape character is written as \e.
+func appendQuote(buf []byte, s string, escapes11 bool) []byte {
+	start := len(buf)
+	buf = strconv.AppendQuote(buf, s)
+	quoted := buf[start:]
+	if bytes.IndexByte(quoted[1:len(quoted)-1], '\\') < 0 {
+		return buf
+	}
+	out := append(buf[:start:start], quoted[0])
+	for i := 1; i < len(quoted)-1; i++ {
+		if quoted[i] != '\\' {
+			out = append(out, quoted[i])
+			continue
+		}
+		i++
+		switch c := quoted[i]; {
+		case c == 'a':
+			out = append(out, `\u0007`...)
+		case c == 'v':
+			out = append(out, `\u000b`...)
+		case c == 'x' && quoted[i+1] < '8':
+			hex := quoted[i+1 : i+3]
+			switch {
+			case escapes11 && string(hex) == "1b":
+				out = append(out, `\e`...)
+			case escapes11:
+				out = append(append(out, `\x`...), hex...)
+			default:
+				out = append(append(out, `\u00`...), hex...)
+			}
+			i += 2
+		default:
+			out = append(out, '\\', c)
+		}
+	}
+	return append(out, quoted[len(quoted)-1])
+}
+
+// quoteName returns key s, quoted if it cannot be written as a bare key. If v11 is
+// set, the output may use the features of TOML 1.1, e.g. non-ASCII bare keys.
+func quoteName(s string, v11 bool) string {
 	if len(s) == 0 {
-		return strconv.Quote(s)
+		return `""`
 	}
 	for _, r := range s {
 		if r >= '0' && r <= '9' || r >= 'A' && r <= 'Z' || r >= 'a' && r <= 'z' || r == '-' || r == '_' {
 			continue
 		}
-		return strconv.Quote(s)
+		if v11 && isUnicodeBareKeyChar(r) {
+			continue
+		}
+		return string(appendQuote(nil, s, v11))
 	}
 	return s
 }
 
+// unicodeBareKeyRanges are the ranges of non-ASCII characters allowed in bare keys
+// since TOML 1.1.
+var unicodeBareKeyRanges = [...][2]rune{
+	{0xB2, 0xB3}, {0xB9, 0xB9}, {0xBC, 0xBE}, {0xC0, 0xD6}, {0xD8, 0xF6},
+	{0xF8, 0x37D}, {0x37F, 0x1FFF}, {0x200C, 0x200D}, {0x203F, 0x2040},
+	{0x2070, 0x218F}, {0x2460, 0x24FF}, {0x2C00, 0x2FEF}, {0x3001, 0xD7FF},
+	{0xF900, 0xFDCF}, {0xFDF0, 0xFFFD}, {0x10000, 0xEFFFF},
+}
+
+func isUnicodeBareKeyChar(r rune) bool {
+	for _, rng := range unicodeBareKeyRanges {
+		if r >= rng[0] && r <= rng[1] {
+			return true
+		}
+	}
+	return false
+}
+
 type mapKeyList []struct {
 	key   string
 	value reflect.Value
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"math"
 	"reflect"
+	"strconv"
 	"testing"
 	"time"
 
@@ -41,6 +42,16 @@ func (t *testMarshalerRecPtr) MarshalTOML() (interface{}, error) {
 	return t.replacement, nil
 }
 
+type testMarshalerTable struct{ name, version string }
+
+func (t testMarshalerTable) MarshalTOMLTable() ([]KeyValue, error) {
+	return []KeyValue{
+		{Key: "version", Value: t.version},
+		{Key: "name", Value: t.name},
+		{Key: "sub", Value: []KeyValue{{Key: "b", Value: 1}, {Key: "a", Value: 2}}},
+	}, nil
+}
+
 var marshalTests = []struct {
 	v      interface{}
 	expect []byte
@@ -213,6 +224,33 @@ var marshalTests = []struct {
 		},
 		expect: loadTestData("marshal-marshalerrec.toml"),
 	},
+	// marshaler methods on pointer receiver, value not addressable:
+	{
+		v: map[string]interface{}{
+			"m1": testMarshalerPtr{"1"},
+			"m2": testMarshalerRecPtr{2},
+		},
+		expect: []byte("m1 = 1\nm2 = 2\n"),
+	},
+	// marshaler methods on pointer receiver, value addressable:
+	{
+		v: &struct {
+			M1 testMarshalerPtr
+			M2 testMarshalerRecPtr
+		}{testMarshalerPtr{"1"}, testMarshalerRecPtr{2}},
+		expect: []byte("m1 = 1\nm2 = 2\n"),
+	},
+	// MarshalerTable:
+	{
+		v: struct {
+			Pkg    testMarshalerTable
+			Inline []interface{}
+		}{
+			Pkg:    testMarshalerTable{"toml", "1.0"},
+			Inline: []interface{}{1, []KeyValue{{Key: "z", Value: 1}, {Key: "y", Value: 2}}},
+		},
+		expect: []byte("inline = [1, {z = 1, y = 2}]\n\n[pkg]\nversion = \"1.0\"\nname = \"toml\"\n\n[pkg.sub]\nb = 1\na = 2\n"),
+	},
 	// key escaping:
 	{
 		v: map[string]interface{}{
@@ -267,6 +305,39 @@ func TestMarshalRoundTrip(t *testing.T) {
 	}
 }
 
+type embeddedBase struct {
+	Name    string
+	Port    int
+	private int
+}
+
+func TestMarshalUnexportedEmbedded(t *testing.T) {
+	type Server struct {
+		embeddedBase
+		Extra string
+		Sub   struct{ embeddedBase }
+	}
+	v := Server{embeddedBase: embeddedBase{"a", 80, 1}, Extra: "x"}
+	v.Sub.Name = "b"
+	b, err := Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "name = \"a\"\nport = 80\nextra = \"x\"\n\n[sub]\nname = \"b\"\nport = 0\n"
+	if string(b) != want {
+		t.Errorf("wrong output:\n%s", b)
+	}
+
+	var dest Server
+	if err := Unmarshal(b, &dest); err != nil {
+		t.Fatal(err)
+	}
+	v.private = 0
+	if !reflect.DeepEqual(dest, v) {
+		t.Errorf("wrong round trip:\n%s", pretty.Compare(dest, v))
+	}
+}
+
 func TestMarshalArrayTableEmptyParent(t *testing.T) {
 	type Baz struct {
 		Key int
@@ -315,6 +386,10 @@ func TestMarshalOmitempty(t *testing.T) {
 		Slice      []int   `toml:",omitempty"`
 		Pointer    *int    `toml:",omitempty"`
 		Int        int     `toml:",omitempty"`
+		Struct     struct {
+			A string
+			B struct{ C []int }
+		} `toml:",omitempty"`
 	}
 	out, err := Marshal(x)
 	if err != nil {
@@ -325,9 +400,400 @@ func TestMarshalOmitempty(t *testing.T) {
 	}
 }
 
+func TestMarshalOmitemptyStruct(t *testing.T) {
+	type Options struct {
+		Level int
+		Names []string
+	}
+	x := struct {
+		Logging Options `toml:",omitempty"`
+		Tracing Options `toml:",omitempty"`
+	}{Tracing: Options{Level: 1}}
+	out, err := Marshal(x)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "[tracing]\nlevel = 1\nnames = []\n"
+	if string(out) != want {
+		t.Fatalf("got %q, want %q", out, want)
+	}
+}
+
+type testIsZeroer struct{ unset bool }
+
+func (z *testIsZeroer) IsZero() bool { return z.unset }
+
+func TestMarshalOmitemptyIsZero(t *testing.T) {
+	x := struct {
+		Created time.Time    `toml:",omitempty"`
+		Updated time.Time    `toml:",omitempty"`
+		Custom  testIsZeroer `toml:",omitempty"`
+	}{
+		Created: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC).In(time.FixedZone("X", 3600)),
+		Updated: time.Time{}.In(time.FixedZone("X", 3600)),
+		Custom:  testIsZeroer{unset: true},
+	}
+	out, err := Marshal(&x)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "created = 2020-01-01T01:00:00+01:00\n"
+	if string(out) != want {
+		t.Fatalf("got %q, want %q", out, want)
+	}
+}
+
 func checkOutput(got, want []byte) string {
 	if bytes.Equal(got, want) {
 		return ""
 	}
 	return diff.Diff(string(got), string(want))
 }
+
+func TestMarshalTimeLayout(t *testing.T) {
+	end := time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)
+	x := struct {
+		Start time.Time  `toml:"start,layout=2006-01-02"`
+		End   *time.Time `toml:"end,layout=Jan 2 2006"`
+		At    time.Time  `toml:"at,omitempty,layout=15:04"`
+	}{
+		Start: time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+		End:   &end,
+	}
+	out, err := Marshal(&x)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "start = 2020-01-02\nend = \"Mar 4 2021\"\n"
+	if string(out) != want {
+		t.Fatalf("got %q, want %q", out, want)
+	}
+}
+
+func TestMarshalQuotedValue(t *testing.T) {
+	x := struct {
+		Int   int     `toml:",string"`
+		Float float64 `toml:",string"`
+		Bool  *bool   `toml:",string"`
+		Str   string  `toml:",string"`
+	}{Int: 5, Float: 0.5, Bool: new(bool), Str: "x"}
+	out, err := Marshal(&x)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "int = \"5\"\nfloat = \"5e-01\"\nbool = \"false\"\nstr = \"x\"\n"
+	if string(out) != want {
+		t.Fatalf("got %q, want %q", out, want)
+	}
+}
+
+func TestMarshalFieldOrder(t *testing.T) {
+	x := struct {
+		Port    int `toml:"port,order=1"`
+		Host    string
+		Enabled bool   `toml:",order=-1"`
+		Name    string `toml:"name,order=-2"`
+	}{Port: 80, Host: "localhost", Enabled: true, Name: "web"}
+	out, err := Marshal(&x)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "name = \"web\"\nenabled = true\nhost = \"localhost\"\nport = 80\n"
+	if string(out) != want {
+		t.Fatalf("got %q, want %q", out, want)
+	}
+
+	y := struct {
+		A int `toml:",order=first"`
+	}{}
+	if _, err := Marshal(&y); err == nil {
+		t.Fatal("expected error for invalid order")
+	}
+}
+
+// money has an IsZero method with pointer receiver.
+type money struct{ cents int64 }
+
+func (m *money) IsZero() bool { return m.cents == 0 }
+
+func (m money) MarshalText() ([]byte, error) { return []byte(strconv.FormatInt(m.cents, 10)), nil }
+
+func TestMarshalOmitZero(t *testing.T) {
+	type config struct {
+		Start  time.Time `toml:",omitzero"`
+		Price  money     `toml:",omitempty"`
+		Tags   []string  `toml:",omitzero"`
+		Empty  []string  `toml:",omitzero"`
+		Count  int       `toml:",omitzero"`
+		Nested struct {
+			A int
+		} `toml:",omitzero"`
+	}
+	out, err := Marshal(config{Empty: []string{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "empty = []\n"; string(out) != want {
+		t.Errorf("got %q, want %q", out, want)
+	}
+	out, err = Marshal(config{Price: money{5}, Count: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "price = 5\ncount = 1\n"; string(out) != want {
+		t.Errorf("got %q, want %q", out, want)
+	}
+
+	// IsZero is called for non-nil pointers.
+	type pointers struct {
+		Start *time.Time `toml:",omitzero"`
+		Price *money     `toml:",omitzero"`
+		Set   *money     `toml:",omitzero"`
+	}
+	out, err = Marshal(pointers{Start: new(time.Time), Price: new(money), Set: &money{7}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "set = 7\n"; string(out) != want {
+		t.Errorf("got %q, want %q", out, want)
+	}
+}
+
+func TestMarshalLiteralStrings(t *testing.T) {
+	v := struct {
+		Path    string
+		Regex   string
+		Quote   string
+		Plain   string
+		Literal string `toml:",literal"`
+	}{`C:\Users\x`, `^\d+$`, `it's\n`, "plain", "text"}
+
+	out, err := Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "path = \"C:\\\\Users\\\\x\"\nregex = \"^\\\\d+$\"\nquote = \"it's\\\\n\"\nplain = \"plain\"\nliteral = 'text'\n"
+	if string(out) != want {
+		t.Errorf("wrong output:\ngot  %s\nwant %s", out, want)
+	}
+
+	cfg := DefaultConfig.With(LiteralStrings(true))
+	out, err = cfg.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = "path = 'C:\\Users\\x'\nregex = '^\\d+$'\nquote = \"it's\\\\n\"\nplain = \"plain\"\nliteral = 'text'\n"
+	if string(out) != want {
+		t.Errorf("wrong output with LiteralStrings:\ngot  %s\nwant %s", out, want)
+	}
+	got := v
+	got.Path, got.Regex, got.Literal = "", "", ""
+	if err := Unmarshal(out, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got != v {
+		t.Errorf("round trip failed: got %+v, want %+v", got, v)
+	}
+}
+
+func TestEncodeValue(t *testing.T) {
+	type server struct {
+		Host  string
+		Ports []int
+	}
+	tests := []struct {
+		v    interface{}
+		want string
+	}{
+		{1, "1"},
+		{"a\"b", `"a\"b"`},
+		{[]string{"a", "b"}, `["a", "b"]`},
+		{server{"h", []int{1}}, `{host = "h", ports = [1]}`},
+		{&server{"h", nil}, `{host = "h", ports = []}`},
+		{[]server{{Host: "a"}, {Host: "b"}}, `[{host = "a", ports = []}, {host = "b", ports = []}]`},
+		{map[string]interface{}{"a": map[string]int{"b": 1}}, `{a = {b = 1}}`},
+		{time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC), `2020-01-02T03:04:05Z`},
+	}
+	for _, test := range tests {
+		out, err := EncodeValue(test.v)
+		if err != nil {
+			t.Errorf("EncodeValue(%#v): %v", test.v, err)
+			continue
+		}
+		if string(out) != test.want {
+			t.Errorf("EncodeValue(%#v) = %s, want %s", test.v, out, test.want)
+		}
+	}
+	if _, err := EncodeValue(nil); err == nil {
+		t.Error("expected error for nil")
+	}
+
+	out, err := EncodeKeyValue("a.b", []int{1, 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `"a.b" = [1, 2]`; string(out) != want {
+		t.Errorf("EncodeKeyValue = %s, want %s", out, want)
+	}
+}
+
+func TestMarshalLineEnding(t *testing.T) {
+	v := struct {
+		Name  string
+		Text  testMarshaler
+		Table struct{ A int }
+	}{"x", testMarshaler{"\"\"\"\nline 1\r\nline 2\n\"\"\""}, struct{ A int }{1}}
+
+	out, err := DefaultConfig.With(LineEnding("\r\n")).Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "name = \"x\"\r\ntext = \"\"\"\r\nline 1\r\nline 2\r\n\"\"\"\r\n\r\n[table]\r\na = 1\r\n"
+	if string(out) != want {
+		t.Errorf("wrong output:\ngot  %q\nwant %q", out, want)
+	}
+
+	if _, err := DefaultConfig.With(LineEnding("\r")).Marshal(v); err == nil {
+		t.Error("no error for invalid line ending")
+	}
+}
+
+func TestMarshalMultilineInlineTables(t *testing.T) {
+	v := map[string]interface{}{
+		"mixed": []interface{}{1, map[string]interface{}{"a": 1, "b": map[string]interface{}{"c": "x"}}, map[string]interface{}{}},
+	}
+
+	out, err := DefaultConfig.With(MultilineInlineTables(true), UseVersion("1.1")).Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "mixed = [1, {\n  a = 1,\n  b = {\n    c = \"x\",\n  },\n}, {}]\n"
+	if string(out) != want {
+		t.Errorf("wrong output:\ngot  %q\nwant %q", out, want)
+	}
+
+	// Earlier versions don't allow newlines in inline tables.
+	out, err = DefaultConfig.With(MultilineInlineTables(true)).Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = "mixed = [1, {a = 1, b = {c = \"x\"}}, {}]\n"
+	if string(out) != want {
+		t.Errorf("wrong output without version:\ngot  %q\nwant %q", out, want)
+	}
+}
+
+func TestMarshalUnicodeKeys(t *testing.T) {
+	v := map[string]interface{}{"schlüssel": 1, "ключ": map[string]int{"×": 2}}
+
+	out, err := DefaultConfig.With(UseVersion("1.1")).Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "schlüssel = 1\n\n[ключ]\n\"×\" = 2\n"; string(out) != want {
+		t.Errorf("wrong 1.1 output:\ngot  %q\nwant %q", out, want)
+	}
+	out, err = Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "\"schlüssel\" = 1\n\n[\"ключ\"]\n\"×\" = 2\n"; string(out) != want {
+		t.Errorf("wrong output:\ngot  %q\nwant %q", out, want)
+	}
+}
+
+func TestMarshalSortOption(t *testing.T) {
+	type server struct {
+		Name string
+		Port int
+	}
+	v := struct {
+		Servers []server                 `toml:",sort=name"`
+		ByPort  []*server                `toml:"by_port,sort=port"`
+		Maps    []map[string]interface{} `toml:",sort=id"`
+	}{
+		Servers: []server{{"b", 2}, {"c", 1}, {"a", 3}},
+		ByPort:  []*server{{"x", 443}, {"y", 80}},
+		Maps:    []map[string]interface{}{{"id": 2}, {"other": true}, {"id": 1}},
+	}
+	out, err := Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `[[servers]]
+name = "a"
+port = 3
+
+[[servers]]
+name = "b"
+port = 2
+
+[[servers]]
+name = "c"
+port = 1
+
+[[by_port]]
+name = "y"
+port = 80
+
+[[by_port]]
+name = "x"
+port = 443
+
+[[maps]]
+id = 1
+
+[[maps]]
+id = 2
+
+[[maps]]
+other = true
+`
+	if string(out) != want {
+		t.Errorf("wrong output:\ngot:\n%s\nwant:\n%s", out, want)
+	}
+	if v.Servers[0].Name != "b" {
+		t.Error("Marshal modified the slice")
+	}
+
+	bad := struct {
+		S string `toml:",sort=name"`
+	}{"x"}
+	if _, err := Marshal(bad); err == nil {
+		t.Error("no error for sort option on string field")
+	}
+}
+
+func TestMarshalIterators(t *testing.T) {
+	ports := func(yield func(int) bool) {
+		for _, p := range []int{80, 443} {
+			if !yield(p) {
+				return
+			}
+		}
+	}
+	env := func(yield func(string, string) bool) {
+		_ = yield("PATH", "/bin") && yield("HOME", "/root")
+	}
+	v := struct {
+		Ports func(func(int) bool)
+		Env   func(func(string, string) bool)
+	}{ports, env}
+
+	out, err := Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "ports = [80, 443]\n\n[env]\nPATH = \"/bin\"\nHOME = \"/root\"\n"
+	if string(out) != want {
+		t.Errorf("wrong output:\ngot  %q\nwant %q", out, want)
+	}
+
+	out, err = Marshal(env)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "PATH = \"/bin\"\nHOME = \"/root\"\n"; string(out) != want {
+		t.Errorf("wrong output for top-level iterator:\ngot  %q\nwant %q", out, want)
+	}
+}
@@ -1,22 +1,33 @@
 package toml
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
 	"reflect"
+	"sort"
 	"strconv"
+	"strings"
 )
 
 // LineError is returned by Unmarshal, UnmarshalTable and Parse
 // if the error is local to a line.
 type LineError struct {
 	Line        int
+	Column      int    // 1-based column of the value, zero if not known
+	Path        string // key path of the value in the form used by Positions, if known
 	StructField string
 	Err         error
 }
 
 func (err *LineError) Error() string {
 	field := ""
-	if err.StructField != "" {
+	switch {
+	case err.Path != "" && err.StructField != "":
+		field = err.Path + " (" + err.StructField + "): "
+	case err.Path != "":
+		field = err.Path + ": "
+	case err.StructField != "":
 		field = "(" + err.StructField + ") "
 	}
 	return fmt.Sprintf("line %d: %s%v", err.Line, field, err.Err)
@@ -26,6 +37,34 @@ func (err *LineError) Unwrap() error {
 	return err.Err
 }
 
+// Position returns the line and column of err.
+func (err *LineError) Position() Position {
+	return Position{err.Line, err.Column}
+}
+
+// KeyPath returns err.Path.
+func (err *LineError) KeyPath() string {
+	return err.Path
+}
+
+// LocatedError is implemented by errors which refer to a location in a TOML document.
+// The parser and decoder return such errors as *LineError values, which may be
+// contained in an *ErrorList. Use errors.As to retrieve them:
+//
+//	var lerr toml.LocatedError
+//	if errors.As(err, &lerr) {
+//		pos := lerr.Position()
+//		...
+//	}
+type LocatedError interface {
+	error
+	// Position returns the location of the error. The column is zero if not known.
+	Position() Position
+	// KeyPath returns the key path of the value causing the error, e.g.
+	// "servers[1].port", or the empty string if not known.
+	KeyPath() string
+}
+
 func lineError(line int, err error) error {
 	if err == nil {
 		return nil
@@ -45,6 +84,161 @@ func lineErrorField(line int, field string, err error) error {
 	return err
 }
 
+// mustPanic panics with err, which was returned by function fn for the given input.
+// The message includes the source line of line errors.
+func mustPanic(fn string, data []byte, err error) {
+	msg := "toml: " + fn + ": " + err.Error()
+	var serr *SyntaxError
+	if lerr, ok := err.(*LineError); ok && lerr.Line > 0 && !(errors.As(err, &serr) && serr.column > 0) {
+		lines := bytes.Split(data, []byte("\n"))
+		if lerr.Line <= len(lines) {
+			msg += "\n\t" + string(bytes.TrimRight(lines[lerr.Line-1], "\r"))
+		}
+	}
+	panic(msg)
+}
+
+// errorList collects the errors of modes which report multiple errors at once.
+type errorList struct {
+	errs    []error
+	max     int  // negative for no limit
+	stopped bool // the limit has been reached
+}
+
+func newErrorList(cfg *Config) *errorList {
+	return &errorList{max: cfg.maxErrors()}
+}
+
+// errTooManyErrors is returned through the decoder when an errorList is full.
+var errTooManyErrors = errors.New("too many errors")
+
+// add records err. It returns false when the limit has been reached and the caller
+// should stop.
+func (l *errorList) add(err error) bool {
+	l.errs = append(l.errs, err)
+	l.stopped = l.max >= 0 && len(l.errs) >= l.max
+	return !l.stopped
+}
+
+// err returns the collected errors as a single error, or nil if there are none.
+func (l *errorList) err() error {
+	switch {
+	case len(l.errs) == 0:
+		return nil
+	case len(l.errs) == 1 && !l.stopped:
+		return l.errs[0]
+	}
+	return &ErrorList{Errors: l.errs, Limited: l.stopped}
+}
+
+// finish is like err, but also includes err, which ended decoding early. The collected
+// errors are sorted by line.
+func (l *errorList) finish(err error) error {
+	if err != nil && !errors.Is(err, errTooManyErrors) {
+		l.errs = append(l.errs, err)
+	}
+	sort.SliceStable(l.errs, func(i, j int) bool { return errorLine(l.errs[i]) < errorLine(l.errs[j]) })
+	return l.err()
+}
+
+// collect records err, which occurred while decoding a single key, if errors of its kind
+// are gathered. It returns nil if decoding should continue with the next key.
+func (cfg *Config) collect(err error, unknownField bool) error {
+	if err == nil || cfg.errs == nil || errors.Is(err, errTooManyErrors) {
+		return err
+	}
+	if !cfg.ContinueOnError && !(unknownField && cfg.CollectUnknownFields) {
+		return err
+	}
+	if !cfg.errs.add(err) {
+		return errTooManyErrors
+	}
+	return nil
+}
+
+func errorLine(err error) int {
+	if lerr, ok := err.(*LineError); ok {
+		return lerr.Line
+	}
+	return 0
+}
+
+// ErrorList is returned by the decoder in modes which report multiple errors at once,
+// e.g. if Config.ContinueOnError is set. It is only used when there is more than one
+// error.
+type ErrorList struct {
+	Errors  []error
+	Limited bool // decoding stopped at Config.MaxErrors
+}
+
+func (err *ErrorList) Error() string {
+	var buf bytes.Buffer
+	for i, e := range err.Errors {
+		if i > 0 {
+			buf.WriteByte('\n')
+		}
+		buf.WriteString(e.Error())
+	}
+	if err.Limited {
+		buf.WriteString("\ntoo many errors")
+	}
+	return buf.String()
+}
+
+// Unwrap returns the errors in the list.
+func (err *ErrorList) Unwrap() []error {
+	return err.Errors
+}
+
+// SyntaxError is returned by the parser if the input doesn't conform to the TOML
+// grammar. It is wrapped in a *LineError holding the location of the error. Errors of
+// valid syntax with invalid meaning, e.g. keys defined twice, are not SyntaxErrors.
+type SyntaxError struct {
+	Msg      string
+	Expected []string // descriptions of the tokens which would be valid, if known
+
+	source string // the offending line, if known
+	column int    // 1-based column of the error in source
+}
+
+// Error returns the message. If the location of the error in the line is known, the line
+// is shown with a caret under the offending character, e.g.
+//
+//	invalid TOML syntax, expected a value
+//		name = = "x"
+//		       ^
+func (err *SyntaxError) Error() string {
+	if err.column == 0 {
+		return err.message()
+	}
+	// Tabs are kept so that the caret lines up with the source.
+	indent := []rune(err.source)[:err.column-1]
+	for i, c := range indent {
+		if c != '\t' {
+			indent[i] = ' '
+		}
+	}
+	return err.message() + "\n\t" + err.source + "\n\t" + string(indent) + "^"
+}
+
+// message returns the message without the source line.
+func (err *SyntaxError) message() string {
+	switch n := len(err.Expected); n {
+	case 0:
+		return err.Msg
+	case 1:
+		return err.Msg + ", expected " + err.Expected[0]
+	default:
+		return err.Msg + ", expected " + strings.Join(err.Expected[:n-1], ", ") + " or " + err.Expected[n-1]
+	}
+}
+
+// Is reports whether target is a SyntaxError with the same message, e.g. ErrSyntax.
+func (err *SyntaxError) Is(target error) bool {
+	t, ok := target.(*SyntaxError)
+	return ok && t.Msg == err.Msg
+}
+
 type rawControlError struct {
 	char rune
 }
@@ -53,22 +247,55 @@ func (err *rawControlError) Error() string {
 	return fmt.Sprintf("raw control character %q", err.char)
 }
 
-type overflowError struct {
-	kind reflect.Kind
-	v    string
+type deprecatedKeyError struct {
+	key string
+	msg string
+}
+
+func (err *deprecatedKeyError) Error() string {
+	if err.msg == "" {
+		return fmt.Sprintf("key `%s' is deprecated", err.key)
+	}
+	return fmt.Sprintf("key `%s' is deprecated: %s", err.key, err.msg)
+}
+
+// requiredKeyError is returned for a missing key of a field tagged "required".
+type requiredKeyError struct {
+	key string
 }
 
-func (err *overflowError) Error() string {
-	return fmt.Sprintf("value %s is out of range for %v", err.v, err.kind)
+func (err *requiredKeyError) Error() string {
+	return fmt.Sprintf("required key `%s' is missing", err.key)
+}
+
+// OverflowError is returned by the decoder if a number doesn't fit into the Go type it
+// is decoded into.
+type OverflowError struct {
+	Kind  reflect.Kind // the kind of the Go type
+	Value string       // the number as written in the input
+}
+
+func (err *OverflowError) Error() string {
+	return fmt.Sprintf("value %s is out of range for %v", err.Value, err.Kind)
 }
 
 func convertNumError(kind reflect.Kind, err error) error {
 	if numerr, ok := err.(*strconv.NumError); ok && numerr.Err == strconv.ErrRange {
-		return &overflowError{kind, numerr.Num}
+		return &OverflowError{kind, numerr.Num}
 	}
 	return err
 }
 
+type fieldConflictError struct {
+	typ            reflect.Type
+	field1, field2 string
+	key            string
+}
+
+func (err *fieldConflictError) Error() string {
+	return fmt.Sprintf("toml: fields %s and %s of %v both correspond to key `%s'", err.field1, err.field2, err.typ, err.key)
+}
+
 type invalidUnmarshalError struct {
 	typ reflect.Type
 }
@@ -83,16 +310,18 @@ func (err *invalidUnmarshalError) Error() string {
 	return "toml: Unmarshal(nil " + err.typ.String() + ")"
 }
 
-type unmarshalTypeError struct {
-	what string
-	want string
-	typ  reflect.Type
+// UnmarshalTypeError is returned by the decoder if a TOML value cannot be stored in a Go
+// value of the given type.
+type UnmarshalTypeError struct {
+	Value string       // description of the TOML value, e.g. "string" or "array table"
+	Want  string       // description of the Go types accepted for the value, if known
+	Type  reflect.Type // the type of the Go value
 }
 
-func (err *unmarshalTypeError) Error() string {
-	msg := fmt.Sprintf("cannot unmarshal TOML %s into %s", err.what, err.typ)
-	if err.want != "" {
-		msg += " (need " + err.want + ")"
+func (err *UnmarshalTypeError) Error() string {
+	msg := fmt.Sprintf("cannot unmarshal TOML %s into %s", err.Value, err.Type)
+	if err.Want != "" {
+		msg += " (need " + err.Want + ")"
 	}
 	return msg
 }
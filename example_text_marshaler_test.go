@@ -46,5 +46,5 @@ servers = ["192.0.2.10", "198.51.100.500"]
 
 	// Output:
 	// Unmarshal error:
-	// line 2: (toml_test.Config.Servers) invalid IP address: 198.51.100.500
+	// line 2: servers (toml_test.Config.Servers): invalid IP address: 198.51.100.500
 }
@@ -0,0 +1,184 @@
+package toml
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"math/big"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/naoina/toml/ast"
+)
+
+// Hash returns the SHA-256 hash of the semantic content of a TOML document. Documents
+// which differ only in formatting, comments, the order of keys and tables, the notation
+// of numbers and strings, or the way tables are defined (standard or inline) have
+// the same hash. Datetimes with a time zone offset are compared as instants.
+func Hash(data []byte) ([32]byte, error) {
+	var sum [32]byte
+	t, err := Parse(data)
+	if err != nil {
+		return sum, err
+	}
+	h := sha256.New()
+	if err := hashTable(h, t); err != nil {
+		return sum, err
+	}
+	copy(sum[:], h.Sum(nil))
+	return sum, nil
+}
+
+func hashTable(h hash.Hash, t *ast.Table) error {
+	hashHeader(h, 't', len(t.Fields))
+	for _, key := range sortedKeys(t) {
+		hashString(h, key)
+		if err := hashField(h, t.Fields[key]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func hashField(h hash.Hash, field interface{}) error {
+	switch f := field.(type) {
+	case *ast.Table:
+		return hashTable(h, f)
+	case []*ast.Table:
+		hashHeader(h, 'a', len(f))
+		for _, t := range f {
+			if err := hashTable(h, t); err != nil {
+				return err
+			}
+		}
+		return nil
+	case *ast.KeyValue:
+		if err := hashValue(h, f.Value); err != nil {
+			return lineError(f.Line, err)
+		}
+		return nil
+	default:
+		panic(fmt.Sprintf("BUG: unhandled node type %T", field))
+	}
+}
+
+func hashValue(h hash.Hash, v ast.Value) error {
+	switch v := v.(type) {
+	case *ast.Table:
+		return hashTable(h, v)
+	case *ast.Array:
+		hashHeader(h, 'a', len(v.Value))
+		for _, elem := range v.Value {
+			if err := hashValue(h, elem); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		kind, text, err := canonicalValue(v)
+		if err != nil {
+			return err
+		}
+		hashHeader(h, kind, 0)
+		hashString(h, text)
+		return nil
+	}
+}
+
+func hashHeader(h hash.Hash, kind byte, n int) {
+	var buf [1 + binary.MaxVarintLen64]byte
+	buf[0] = kind
+	h.Write(buf[:1+binary.PutUvarint(buf[1:], uint64(n))])
+}
+
+func hashString(h hash.Hash, s string) {
+	var buf [binary.MaxVarintLen64]byte
+	h.Write(buf[:binary.PutUvarint(buf[:], uint64(len(s)))])
+	h.Write([]byte(s))
+}
+
+func sortedKeys(t *ast.Table) []string {
+	keys := make([]string, 0, len(t.Fields))
+	for key := range t.Fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// canonicalValue returns the canonical TOML notation of a primitive value.
+// The kind identifies the type of the value: 's' for strings, 'i' for integers,
+// 'f' for floats, 'b' for booleans, and 'd', 'D', 'l', 'L' for offset datetimes,
+// local datetimes, local dates and local times.
+func canonicalValue(v ast.Value) (kind byte, text string, err error) {
+	switch v := v.(type) {
+	case *ast.String:
+		return 's', quoteString(v.Value), nil
+	case *ast.Integer:
+		i, ok := new(big.Int).SetString(v.Value, 0)
+		if !ok {
+			return 0, "", fmt.Errorf("invalid integer %s", v.Value)
+		}
+		return 'i', i.String(), nil
+	case *ast.Float:
+		f, err := v.Float()
+		if err != nil {
+			return 0, "", err
+		}
+		return 'f', string(appendFloat(nil, f)), nil
+	case *ast.Boolean:
+		return 'b', v.Value, nil
+	case *ast.Datetime:
+		t, err := v.Time()
+		if err != nil {
+			return 0, "", err
+		}
+		switch {
+		case !strings.Contains(v.Value, ":"):
+			return 'l', t.Format("2006-01-02"), nil
+		case !strings.Contains(v.Value, "-"):
+			return 'L', t.Format("15:04:05.999999999"), nil
+		case hasTimeOffset(v.Value):
+			return 'd', t.UTC().Format(time.RFC3339Nano), nil
+		default:
+			return 'D', t.Format("2006-01-02T15:04:05.999999999"), nil
+		}
+	default:
+		panic(fmt.Sprintf("BUG: unhandled node type %T", v))
+	}
+}
+
+// quoteString returns s as a TOML basic string. Only characters which must be escaped
+// are escaped.
+func quoteString(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			b.WriteString(`\"`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\b':
+			b.WriteString(`\b`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\f':
+			b.WriteString(`\f`)
+		case '\r':
+			b.WriteString(`\r`)
+		default:
+			if r < 0x20 || r == 0x7F {
+				fmt.Fprintf(&b, `\u%04X`, r)
+			} else {
+				b.WriteRune(r)
+			}
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
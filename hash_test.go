@@ -0,0 +1,69 @@
+package toml
+
+import (
+	"testing"
+)
+
+func TestHash(t *testing.T) {
+	equal := [][]string{
+		{
+			"a = 1\nb = \"x\"\n[t]\nc = true\n",
+			"# comment\nb = 'x'\na = +1\nt = { c = true }\n",
+			"b = \"\\u0078\"\na = 0x1\n[t]\nc = true\n",
+		},
+		{
+			"f = 1.5\nd = 1979-05-27T07:32:00-08:00\n",
+			"f = 15e-1\nd = 1979-05-27 15:32:00Z\n",
+		},
+		{
+			"[[a]]\nx = 1\n[[a]]\nx = 2\n",
+			"a = [{ x = 1 }, { x = 2 }]\n",
+		},
+	}
+	hashes := make(map[[32]byte]int)
+	for i, docs := range equal {
+		first, err := Hash([]byte(docs[0]))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, doc := range docs[1:] {
+			h, err := Hash([]byte(doc))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if h != first {
+				t.Errorf("hash of %q differs from hash of %q", doc, docs[0])
+			}
+		}
+		if j, ok := hashes[first]; ok {
+			t.Errorf("group %d has the same hash as group %d", i, j)
+		}
+		hashes[first] = i
+	}
+
+	different := []string{
+		"a = 1",
+		"a = 1.0",
+		"a = \"1\"",
+		"a = [1]",
+		"a = 1979-05-27",
+		"a = 1979-05-27T00:00:00",
+		"a = {}",
+		"b = 1",
+	}
+	seen := make(map[[32]byte]string)
+	for _, doc := range different {
+		h, err := Hash([]byte(doc))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if other, ok := seen[h]; ok {
+			t.Errorf("%q and %q have the same hash", doc, other)
+		}
+		seen[h] = doc
+	}
+
+	if _, err := Hash([]byte("a = ")); err == nil {
+		t.Error("expected error for invalid document")
+	}
+}
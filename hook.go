@@ -0,0 +1,55 @@
+package toml
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/naoina/toml/ast"
+)
+
+// DecodeHookFunc converts TOML values to Go values of type to. It returns handled ==
+// false to leave the value to the next hook or the default conversion. The result is
+// assigned to the target, converting it if it has a different type of the same kind. A
+// nil result stores the zero value.
+type DecodeHookFunc func(from ast.Value, to reflect.Type) (result interface{}, handled bool, err error)
+
+// ComposeDecodeHooks returns a hook which calls the given hooks in order until one of
+// them handles the value.
+func ComposeDecodeHooks(hooks ...DecodeHookFunc) DecodeHookFunc {
+	return func(from ast.Value, to reflect.Type) (interface{}, bool, error) {
+		for _, hook := range hooks {
+			if hook == nil {
+				continue
+			}
+			if result, handled, err := hook(from, to); handled || err != nil {
+				return result, true, err
+			}
+		}
+		return nil, false, nil
+	}
+}
+
+// setDecodeHook calls cfg.DecodeHook for val and assigns its result to rv.
+func setDecodeHook(cfg *Config, rv reflect.Value, val ast.Value) (bool, error) {
+	if cfg.DecodeHook == nil || !rv.CanSet() {
+		return false, nil
+	}
+	result, handled, err := cfg.DecodeHook(val, rv.Type())
+	if !handled || err != nil {
+		return handled, err
+	}
+	if result == nil {
+		rv.Set(reflect.Zero(rv.Type()))
+		return true, nil
+	}
+	v := reflect.ValueOf(result)
+	switch {
+	case v.Type().AssignableTo(rv.Type()):
+		rv.Set(v)
+	case v.Kind() == rv.Kind() && v.Type().ConvertibleTo(rv.Type()):
+		rv.Set(v.Convert(rv.Type()))
+	default:
+		return true, fmt.Errorf("decode hook returned %s, which cannot be assigned to %s", v.Type(), rv.Type())
+	}
+	return true, nil
+}
@@ -0,0 +1,212 @@
+package toml
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/naoina/toml/ast"
+)
+
+// minPartSize is the size of the input which is read before the incremental parser
+// looks for the end of the current part. Tests lower it to parse every table on its own.
+var minPartSize = 64 << 10
+
+// partReader splits a document into parts which can be parsed on their own. Parts end
+// before a line starting with a table header, so every part but the first starts with
+// a table. The lines are scanned just enough to skip strings, comments and brackets.
+// Syntax errors may make a part longer, but never split an expression.
+type partReader struct {
+	r       *bufio.Reader
+	minSize int
+	next    []byte // the header line which starts the next part
+	str     byte   // the quote of the current multi-line string, or zero
+	depth   int    // nesting depth of arrays and inline tables
+}
+
+func newPartReader(r io.Reader, minSize int) *partReader {
+	return &partReader{r: bufio.NewReader(r), minSize: minSize}
+}
+
+// part returns the next part of the input. It returns io.EOF with the last part.
+func (pr *partReader) part() ([]byte, error) {
+	buf := pr.next
+	pr.next = nil
+	for {
+		line, err := pr.readLine()
+		if len(line) > 0 {
+			if pr.scan(line) && len(buf) >= pr.minSize {
+				pr.next = append([]byte(nil), line...)
+				return buf, nil
+			}
+			buf = append(buf, line...)
+		}
+		if err != nil {
+			return buf, err
+		}
+	}
+}
+
+// readLine returns the next line including the line feed. The line is only valid until
+// the next read.
+func (pr *partReader) readLine() ([]byte, error) {
+	line, err := pr.r.ReadSlice('\n')
+	if err != bufio.ErrBufferFull {
+		return line, err
+	}
+	long := append([]byte(nil), line...)
+	for err == bufio.ErrBufferFull {
+		line, err = pr.r.ReadSlice('\n')
+		long = append(long, line...)
+	}
+	return long, err
+}
+
+// scan updates the lexical state for line and reports whether the line starts with a
+// table header.
+func (pr *partReader) scan(line []byte) (header bool) {
+	i := 0
+	if pr.str == 0 && pr.depth == 0 {
+		for i < len(line) && (line[i] == ' ' || line[i] == '\t') {
+			i++
+		}
+		header = i < len(line) && line[i] == '['
+	}
+	for i < len(line) {
+		c := line[i]
+		if pr.str != 0 {
+			switch {
+			case c == '\\' && pr.str == '"':
+				i += 2
+			case c == pr.str:
+				// The delimiter may be preceded by up to two quotes of the content.
+				n := 0
+				for i+n < len(line) && line[i+n] == pr.str {
+					n++
+				}
+				if n >= 3 {
+					pr.str = 0
+				}
+				i += n
+			default:
+				i++
+			}
+			continue
+		}
+		switch c {
+		case '#':
+			return header
+		case '"', '\'':
+			if bytes.HasPrefix(line[i:], []byte{c, c, c}) {
+				pr.str = c
+				i += 3
+				continue
+			}
+			for i++; i < len(line) && line[i] != c && line[i] != '\n'; i++ {
+				if c == '"' && line[i] == '\\' {
+					i++
+				}
+			}
+		case '[', '{':
+			pr.depth++
+		case ']', '}':
+			if pr.depth > 0 {
+				pr.depth--
+			}
+		}
+		i++
+	}
+	return header
+}
+
+// parseIncremental parses the document read from r in parts. The parts are parsed into
+// the same AST, which doesn't reference the source.
+func parseIncremental(r io.Reader, o *parseOptions) (*ast.Table, error) {
+	if o.version != "" && !knownVersion(o.version) {
+		return nil, fmt.Errorf("toml: unsupported TOML version %q", o.version)
+	}
+	d := &parseState{p: getParser()}
+	defer putParser(d.p)
+	var comments []*ast.Comment
+	if o.maxSize > 0 {
+		r = io.LimitReader(r, o.maxSize+1)
+	}
+	var size int64
+	pr := newPartReader(r, minPartSize)
+	for first, base, line := true, 0, 1; ; first = false {
+		data, rerr := pr.part()
+		if rerr != nil && rerr != io.EOF {
+			return nil, rerr
+		}
+		if size += int64(len(data)); o.maxSize > 0 && size > o.maxSize {
+			return nil, fmt.Errorf("toml: input exceeds the maximum size of %d bytes", o.maxSize)
+		}
+		data, err := preparePart(data, o, first, line)
+		if err != nil {
+			return nil, err
+		}
+		if first {
+			d.init(data)
+			d.p.toml.version = o.version
+			d.p.toml.maxDepth = o.maxDepth
+			d.p.toml.allowLeadingZeros = o.leadingZeros
+			d.p.toml.warn = o.warn
+			d.p.toml.topTable.Data = nil
+			d.p.toml.noData = true
+		} else {
+			d.initPart(data, base, line)
+		}
+		if err := d.parse(); err != nil {
+			return nil, err
+		}
+		if o.comments {
+			comments = append(comments, d.comments()...)
+		}
+		base += len(d.p.toml.src)
+		line = d.p.toml.line
+		if rerr == io.EOF {
+			d.p.toml.topTable.Position.End = base
+			break
+		}
+	}
+	t := d.p.toml.topTable
+	t.Comments = comments
+	if o.version != "" {
+		if err := checkTable(t, o); err != nil {
+			return nil, err
+		}
+	}
+	return t, nil
+}
+
+// preparePart applies the encoding checks of parse to a part of a document which starts
+// at the given line.
+func preparePart(data []byte, o *parseOptions, first bool, line int) ([]byte, error) {
+	if first && bytes.HasPrefix(data, utf8BOM) {
+		if o.rejectBOM {
+			return nil, lineError(1, errors.New("byte order mark at start of document"))
+		}
+		data = data[len(utf8BOM):]
+	}
+	if err := checkEncoding(data, o.bareCR); err != nil {
+		if lerr, ok := err.(*LineError); ok {
+			lerr.Line += line - 1
+		}
+		return nil, err
+	}
+	if o.bareCR {
+		data = replaceBareCR(data)
+	}
+	return data, nil
+}
+
+// initPart sets up the parser for a part of a document which starts at offset base and
+// the given line. The AST isn't reset.
+func (d *parseState) initPart(data []byte, base, line int) {
+	d.p.Buffer = string(data)
+	d.p.Reset()
+	d.p.toml.src = d.p.buffer[:len(d.p.buffer)-1]
+	d.p.toml.base, d.p.toml.firstLine = base, line
+}
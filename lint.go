@@ -0,0 +1,256 @@
+package toml
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/naoina/toml/ast"
+)
+
+// Severity is the severity of a Diagnostic.
+type Severity uint8
+
+// Severities of diagnostics, in increasing order.
+const (
+	SeverityInfo Severity = iota
+	SeverityWarning
+	SeverityError
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityInfo:
+		return "info"
+	case SeverityWarning:
+		return "warning"
+	case SeverityError:
+		return "error"
+	default:
+		return fmt.Sprintf("Severity(%d)", uint8(s))
+	}
+}
+
+// Diagnostic codes reported by Lint.
+const (
+	LintSyntax             = "syntax"              // the document is invalid
+	LintTrailingWhitespace = "trailing-whitespace" // line ends with spaces or tabs
+	LintFinalNewline       = "final-newline"       // document doesn't end with a newline
+	LintMixedLineEndings   = "mixed-line-endings"  // both LF and CRLF line endings are used
+	LintEmptyTable         = "empty-table"         // table header without keys
+	LintSimilarKeys        = "similar-keys"        // keys which match the same struct field
+)
+
+// Diagnostic is a problem found by Lint.
+type Diagnostic struct {
+	Line     int // 1-based line number
+	Severity Severity
+	Code     string // one of the Lint* constants
+	Message  string
+}
+
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("line %d: %s: %s [%s]", d.Line, d.Severity, d.Message, d.Code)
+}
+
+// lintIgnore is the comment directive that suppresses diagnostics.
+const lintIgnore = "tomllint:ignore"
+
+// Lint checks a TOML document for problems which don't prevent decoding, like
+// inconsistent formatting or keys which would be assigned to the same struct field by
+// DefaultConfig. Syntax errors are reported as diagnostics with severity
+// SeverityError. The result is sorted by line.
+//
+// Diagnostics can be suppressed with a comment on the same line or on the line above:
+//
+//	Path = "a" # tomllint:ignore similar-keys
+//	# tomllint:ignore
+//	[empty]
+//
+// Codes are separated by commas. Without codes, all diagnostics of the line are
+// suppressed.
+func Lint(data []byte) []Diagnostic {
+	t, err := parse(data, &parseOptions{comments: true})
+	if err != nil {
+		line := 1
+		if lerr, ok := err.(*LineError); ok {
+			line = lerr.Line
+			err = lerr.Err
+		}
+		msg := err.Error()
+		if serr, ok := err.(*SyntaxError); ok {
+			msg = serr.message() // diagnostics don't include the source
+		}
+		return []Diagnostic{{line, SeverityError, LintSyntax, msg}}
+	}
+	l := &linter{src: []rune(string(data))}
+	l.lines(t)
+	l.table(t)
+	return l.suppress(t.Comments)
+}
+
+type linter struct {
+	src   []rune
+	diags []Diagnostic
+}
+
+func (l *linter) report(line int, sev Severity, code, format string, args ...interface{}) {
+	l.diags = append(l.diags, Diagnostic{line, sev, code, fmt.Sprintf(format, args...)})
+}
+
+// lines runs the checks which work on the text of the document.
+func (l *linter) lines(t *ast.Table) {
+	spans := stringSpans(t, nil)
+	var crlf, lf int
+	line, begin := 1, 0
+	for pos, r := range l.src {
+		if r != '\n' {
+			continue
+		}
+		end := pos
+		if end > begin && l.src[end-1] == '\r' {
+			end--
+			crlf++
+		} else {
+			lf++
+		}
+		if end > begin && (l.src[end-1] == ' ' || l.src[end-1] == '\t') && !inSpans(spans, end-1) {
+			l.report(line, SeverityWarning, LintTrailingWhitespace, "trailing whitespace")
+		}
+		line, begin = line+1, pos+1
+	}
+	if begin < len(l.src) {
+		end := len(l.src)
+		if l.src[end-1] == ' ' || l.src[end-1] == '\t' {
+			l.report(line, SeverityWarning, LintTrailingWhitespace, "trailing whitespace")
+		}
+		l.report(line, SeverityWarning, LintFinalNewline, "no newline at end of document")
+	}
+	if crlf > 0 && lf > 0 {
+		l.report(1, SeverityWarning, LintMixedLineEndings, "document uses both LF (%d lines) and CRLF (%d lines) line endings", lf, crlf)
+	}
+}
+
+// stringSpans returns the positions of all string values in v.
+func stringSpans(v interface{}, spans []ast.Position) []ast.Position {
+	switch v := v.(type) {
+	case *ast.Table:
+		for _, field := range v.Fields {
+			spans = stringSpans(field, spans)
+		}
+	case []*ast.Table:
+		for _, t := range v {
+			spans = stringSpans(t, spans)
+		}
+	case *ast.KeyValue:
+		spans = stringSpans(v.Value, spans)
+	case *ast.Array:
+		for _, elem := range v.Value {
+			spans = stringSpans(elem, spans)
+		}
+	case *ast.String:
+		spans = append(spans, v.Position)
+	}
+	return spans
+}
+
+func inSpans(spans []ast.Position, pos int) bool {
+	for _, span := range spans {
+		if pos >= span.Begin && pos < span.End {
+			return true
+		}
+	}
+	return false
+}
+
+// table runs the checks which work on the AST.
+func (l *linter) table(t *ast.Table) {
+	keys := sortedKeys(t)
+	byLine := append([]string(nil), keys...)
+	sort.SliceStable(byLine, func(i, j int) bool {
+		return fieldLineNumber(t.Fields[byLine[i]]) < fieldLineNumber(t.Fields[byLine[j]])
+	})
+	seen := make(map[string]string)
+	for _, key := range byLine {
+		norm := DefaultConfig.NormFieldName(nil, key)
+		if prev, ok := seen[norm]; ok {
+			l.report(fieldLineNumber(t.Fields[key]), SeverityWarning, LintSimilarKeys,
+				"keys `%s' and `%s' match the same struct field", prev, key)
+		} else {
+			seen[norm] = key
+		}
+	}
+	for _, key := range keys {
+		switch f := t.Fields[key].(type) {
+		case *ast.Table:
+			l.subTable(f)
+		case []*ast.Table:
+			for _, elem := range f {
+				l.table(elem)
+			}
+		case *ast.KeyValue:
+			if inline, ok := f.Value.(*ast.Table); ok {
+				l.table(inline)
+			}
+		}
+	}
+}
+
+func (l *linter) subTable(t *ast.Table) {
+	if len(t.Fields) == 0 && t.Position != (ast.Position{}) {
+		l.report(t.Line, SeverityInfo, LintEmptyTable, "table `%s' is empty", t.Name)
+	}
+	l.table(t)
+}
+
+// suppress removes diagnostics for lines with an ignore directive and sorts the rest.
+func (l *linter) suppress(comments []*ast.Comment) []Diagnostic {
+	ignored := make(map[int][]string) // line -> codes, empty for all codes
+	for _, c := range comments {
+		text := strings.TrimSpace(c.Text)
+		if !strings.HasPrefix(text, lintIgnore) {
+			continue
+		}
+		var codes []string
+		for _, code := range strings.Split(strings.TrimPrefix(text, lintIgnore), ",") {
+			if code = strings.TrimSpace(code); code != "" {
+				codes = append(codes, code)
+			}
+		}
+		line := c.Line
+		if l.ownLine(c) {
+			line++
+		}
+		ignored[line] = codes
+	}
+
+	diags := l.diags[:0]
+	for _, d := range l.diags {
+		codes, ok := ignored[d.Line]
+		if ok && (len(codes) == 0 || containsString(codes, d.Code)) {
+			continue
+		}
+		diags = append(diags, d)
+	}
+	sort.SliceStable(diags, func(i, j int) bool { return diags[i].Line < diags[j].Line })
+	return diags
+}
+
+// ownLine reports whether comment c is the only content of its line.
+func (l *linter) ownLine(c *ast.Comment) bool {
+	for pos := c.Position.Begin - 1; pos >= 0 && l.src[pos] != '\n'; pos-- {
+		if l.src[pos] != ' ' && l.src[pos] != '\t' {
+			return false
+		}
+	}
+	return true
+}
+
+func containsString(list []string, s string) bool {
+	for _, elem := range list {
+		if elem == s {
+			return true
+		}
+	}
+	return false
+}
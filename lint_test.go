@@ -0,0 +1,37 @@
+package toml
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLint(t *testing.T) {
+	input := "a = 1 \r\n" +
+		"s = \"\"\"\nx  \n\"\"\"\n" +
+		"api_key = 1\n" +
+		"APIKey = 2 # tomllint:ignore trailing-whitespace\n" +
+		"[empty]\n" +
+		"# tomllint:ignore\n" +
+		"[ignored]\n" +
+		"[t]\n" +
+		"x = { ab = 1, a_b = 2 }\t"
+	want := []Diagnostic{
+		{1, SeverityWarning, LintTrailingWhitespace, "trailing whitespace"},
+		{1, SeverityWarning, LintMixedLineEndings, "document uses both LF (9 lines) and CRLF (1 lines) line endings"},
+		{6, SeverityWarning, LintSimilarKeys, "keys `api_key' and `APIKey' match the same struct field"},
+		{7, SeverityInfo, LintEmptyTable, "table `empty' is empty"},
+		{11, SeverityWarning, LintTrailingWhitespace, "trailing whitespace"},
+		{11, SeverityWarning, LintFinalNewline, "no newline at end of document"},
+		{11, SeverityWarning, LintSimilarKeys, "keys `a_b' and `ab' match the same struct field"},
+	}
+	got := Lint([]byte(input))
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("wrong diagnostics:\ngot  %v\nwant %v", got, want)
+	}
+
+	got = Lint([]byte("a = 1\nb = ]\n"))
+	want = []Diagnostic{{2, SeverityError, LintSyntax, "invalid TOML syntax, expected a value"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("wrong diagnostics for invalid document:\ngot  %v\nwant %v", got, want)
+	}
+}
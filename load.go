@@ -0,0 +1,311 @@
+package toml
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/naoina/toml/ast"
+)
+
+// Source provides TOML input to Load. Open is called once per Load and the returned
+// reader is closed after its content has been read.
+//
+// Sources may implement fmt.Stringer to identify themselves in error messages.
+type Source interface {
+	Open(ctx context.Context) (io.ReadCloser, error)
+}
+
+// RelativeSource is implemented by sources which can locate other documents relative
+// to themselves. It is required for resolving Config.ExtendsKey.
+type RelativeSource interface {
+	Source
+	Relative(name string) Source
+}
+
+// FileSource returns a Source that reads the named file from the local file system.
+// Relative names are resolved against the directory containing the file.
+func FileSource(name string) Source {
+	return fileSource(name)
+}
+
+type fileSource string
+
+func (s fileSource) Open(ctx context.Context) (io.ReadCloser, error) {
+	return os.Open(string(s))
+}
+
+func (s fileSource) Relative(name string) Source {
+	if filepath.IsAbs(name) {
+		return fileSource(name)
+	}
+	return fileSource(filepath.Join(filepath.Dir(string(s)), name))
+}
+
+func (s fileSource) String() string {
+	return string(s)
+}
+
+// FSSource returns a Source that reads the named file from fsys.
+// Relative names are resolved against the directory containing the file.
+func FSSource(fsys fs.FS, name string) Source {
+	return &fsSource{fsys, name}
+}
+
+type fsSource struct {
+	fsys fs.FS
+	name string
+}
+
+func (s *fsSource) Open(ctx context.Context) (io.ReadCloser, error) {
+	return s.fsys.Open(s.name)
+}
+
+func (s *fsSource) Relative(name string) Source {
+	return &fsSource{s.fsys, path.Join(path.Dir(s.name), name)}
+}
+
+func (s *fsSource) String() string {
+	return s.name
+}
+
+// Load reads TOML from each source in turn and stores the merged result in the value
+// pointed to by v.
+//
+// Tables defined by later sources are merged into the tables of earlier sources. All
+// other values, including arrays and array tables, replace the values of earlier
+// sources. If cfg.AppendSlices is set, arrays and array tables are concatenated
+// instead. See the documentation for Unmarshal for details about the conversion of
+// TOML into a Go value.
+//
+// If cfg.ExtendsKey is set, documents can name a base document that is loaded first, see
+// the documentation of ExtendsKey.
+func (cfg *Config) Load(ctx context.Context, v interface{}, sources ...Source) error {
+	var merged *ast.Table
+	for _, src := range sources {
+		table, err := cfg.loadSource(ctx, src, nil)
+		if err != nil {
+			return err
+		}
+		if merged == nil {
+			merged = table
+		} else {
+			mergeTables(merged, table, cfg.AppendSlices)
+		}
+	}
+	if merged == nil {
+		merged = &ast.Table{Fields: make(map[string]interface{})}
+	}
+	return cfg.UnmarshalTable(merged, v)
+}
+
+// loadSource reads src and the documents it extends. The chain contains the names of
+// the documents extended by src, for cycle detection.
+func (cfg *Config) loadSource(ctx context.Context, src Source, chain []string) (*ast.Table, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	table, err := cfg.readSource(ctx, src)
+	if err != nil {
+		if name, ok := src.(fmt.Stringer); ok {
+			err = fmt.Errorf("%s: %w", name, err)
+		}
+		return nil, err
+	}
+	if cfg.ExtendsKey == "" {
+		return table, nil
+	}
+	field, ok := table.Fields[cfg.ExtendsKey]
+	if !ok {
+		return table, nil
+	}
+	name := sourceName(src)
+	base, err := extendedSource(src, field, cfg.ExtendsKey)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	chain = append(chain[:len(chain):len(chain)], name)
+	baseName := sourceName(base)
+	for i, n := range chain {
+		if n == baseName {
+			cycle := strings.Join(append(chain[i:], baseName), " -> ")
+			return nil, fmt.Errorf("%s: extends cycle %s", name, cycle)
+		}
+	}
+	baseTable, err := cfg.loadSource(ctx, base, chain)
+	if err != nil {
+		return nil, err
+	}
+	delete(table.Fields, cfg.ExtendsKey)
+	mergeTables(baseTable, table, cfg.AppendSlices)
+	return baseTable, nil
+}
+
+// extendedSource returns the source named by the extends field of src.
+func extendedSource(src Source, field interface{}, key string) (Source, error) {
+	kv, ok := field.(*ast.KeyValue)
+	if !ok {
+		return nil, lineError(fieldLineNumber(field), fmt.Errorf("`%s' must be a string", key))
+	}
+	s, ok := kv.Value.(*ast.String)
+	if !ok {
+		return nil, lineError(kv.Line, fmt.Errorf("`%s' must be a string", key))
+	}
+	rs, ok := src.(RelativeSource)
+	if !ok {
+		return nil, lineError(kv.Line, fmt.Errorf("source does not support `%s'", key))
+	}
+	return rs.Relative(s.Value), nil
+}
+
+func sourceName(src Source) string {
+	if name, ok := src.(fmt.Stringer); ok {
+		return name.String()
+	}
+	return fmt.Sprintf("%T", src)
+}
+
+func (cfg *Config) readSource(ctx context.Context, src Source) (*ast.Table, error) {
+	r, err := src.Open(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return cfg.parseReader(r)
+}
+
+// Merge merges the fields of src into dst using the same rules as Load: tables are
+// merged recursively and all other values of src replace those in dst. If appendArrays
+// is true, arrays and array tables are concatenated instead. The fields of src may be
+// shared with dst afterwards.
+func Merge(dst, src *ast.Table, appendArrays bool) {
+	mergeTables(dst, src, appendArrays)
+}
+
+// mergeTables merges the fields of src into dst. If appendArrays is true,
+// arrays and array tables are concatenated.
+func mergeTables(dst, src *ast.Table, appendArrays bool) {
+	for key, sv := range src.Fields {
+		dt, st := mergeableTable(dst.Fields[key]), mergeableTable(sv)
+		if dt != nil && st != nil {
+			mergeTables(dt, st, appendArrays)
+			continue
+		}
+		if appendArrays {
+			if merged := appendArrayField(dst.Fields[key], sv); merged != nil {
+				dst.Fields[key] = merged
+				continue
+			}
+		}
+		dst.Fields[key] = sv
+	}
+}
+
+// appendArrayField concatenates two arrays or array tables.
+// It returns nil if the fields aren't both arrays of the same kind.
+func appendArrayField(dst, src interface{}) interface{} {
+	switch d := dst.(type) {
+	case []*ast.Table:
+		if s, ok := src.([]*ast.Table); ok {
+			return append(d[:len(d):len(d)], s...)
+		}
+	case *ast.KeyValue:
+		s, ok := src.(*ast.KeyValue)
+		if !ok {
+			return nil
+		}
+		da, ok1 := d.Value.(*ast.Array)
+		sa, ok2 := s.Value.(*ast.Array)
+		if ok1 && ok2 {
+			array := *sa
+			array.Value = append(da.Value[:len(da.Value):len(da.Value)], sa.Value...)
+			kv := *s
+			kv.Value = &array
+			return &kv
+		}
+	}
+	return nil
+}
+
+// mergeableTable returns the table contained in a field,
+// or nil if the field isn't a table.
+func mergeableTable(field interface{}) *ast.Table {
+	switch f := field.(type) {
+	case *ast.Table:
+		return f
+	case *ast.KeyValue:
+		if t, ok := f.Value.(*ast.Table); ok {
+			return t
+		}
+	}
+	return nil
+}
+
+// ProfilesKey is the key of the table holding profiles. See ApplyProfile.
+const ProfilesKey = "profiles"
+
+// ApplyProfile activates a profile defined in t. Profiles are sub-tables of the
+// top-level "profiles" table. The fields of the selected profile are merged into t
+// in the same way Load merges documents, and the "profiles" table is removed.
+//
+// For example, with profile "production" applied, the document
+//
+//	[server]
+//	host = "localhost"
+//	port = 8080
+//
+//	[profiles.production.server]
+//	host = "example.com"
+//
+// is equivalent to
+//
+//	[server]
+//	host = "example.com"
+//	port = 8080
+//
+// If name is empty, the profiles table is removed without applying any profile.
+func ApplyProfile(t *ast.Table, name string) error {
+	field, ok := t.Fields[ProfilesKey]
+	if !ok {
+		if name != "" {
+			return fmt.Errorf("profile `%s' is not defined", name)
+		}
+		return nil
+	}
+	profiles := mergeableTable(field)
+	if profiles == nil {
+		return lineError(fieldLineNumber(field), fmt.Errorf("`%s' must be a table", ProfilesKey))
+	}
+	delete(t.Fields, ProfilesKey)
+	if name == "" {
+		return nil
+	}
+	field, ok = profiles.Fields[name]
+	if !ok {
+		return fmt.Errorf("profile `%s' is not defined", name)
+	}
+	profile := mergeableTable(field)
+	if profile == nil {
+		return lineError(fieldLineNumber(field), fmt.Errorf("profile `%s' must be a table", name))
+	}
+	mergeTables(t, profile, false)
+	return nil
+}
+
+// UnmarshalProfile parses the TOML data, applies the named profile using ApplyProfile
+// and stores the result in the value pointed to by v.
+func (cfg *Config) UnmarshalProfile(data []byte, profile string, v interface{}) error {
+	table, err := cfg.parseBytes(data)
+	if err != nil {
+		return err
+	}
+	if err := ApplyProfile(table, profile); err != nil {
+		return err
+	}
+	return cfg.UnmarshalTable(table, v)
+}
@@ -0,0 +1,190 @@
+package toml
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+func TestLoad(t *testing.T) {
+	fsys := fstest.MapFS{
+		"base.toml": {Data: []byte(`
+name = "base"
+ports = [1, 2]
+
+[server]
+host = "localhost"
+port = 80
+
+[labels]
+a = "1"
+`)},
+		"override.toml": {Data: []byte(`
+ports = [3]
+
+[server]
+port = 8080
+
+[labels]
+b = "2"
+`)},
+	}
+	type config struct {
+		Name   string
+		Ports  []int
+		Server struct {
+			Host string
+			Port int
+		}
+		Labels map[string]string
+	}
+
+	var v config
+	err := Load(context.Background(), &v, FSSource(fsys, "base.toml"), FSSource(fsys, "override.toml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := config{Name: "base", Ports: []int{3}, Labels: map[string]string{"a": "1", "b": "2"}}
+	want.Server.Host = "localhost"
+	want.Server.Port = 8080
+	if !reflect.DeepEqual(v, want) {
+		t.Errorf("wrong value after Load: got %+v, want %+v", v, want)
+	}
+}
+
+func TestLoadAppendSlices(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a.toml": {Data: []byte("ports = [1]\n[[rule]]\nname = \"a\"\n")},
+		"b.toml": {Data: []byte("ports = [2]\n[[rule]]\nname = \"b\"\n")},
+	}
+	type rule struct{ Name string }
+	type config struct {
+		Ports []int
+		Rule  []rule
+	}
+	cfg := DefaultConfig
+	cfg.AppendSlices = true
+	v := config{Ports: []int{0}}
+	err := cfg.Load(context.Background(), &v, FSSource(fsys, "a.toml"), FSSource(fsys, "b.toml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := config{Ports: []int{0, 1, 2}, Rule: []rule{{"a"}, {"b"}}}
+	if !reflect.DeepEqual(v, want) {
+		t.Errorf("wrong value after Load: got %+v, want %+v", v, want)
+	}
+}
+
+func TestLoadExtends(t *testing.T) {
+	fsys := fstest.MapFS{
+		"base.toml":      {Data: []byte("name = \"base\"\nport = 80\n")},
+		"conf/mid.toml":  {Data: []byte("extends = \"../base.toml\"\nport = 8080\n")},
+		"conf/app.toml":  {Data: []byte("extends = \"mid.toml\"\nname = \"app\"\n")},
+		"cycle/a.toml":   {Data: []byte("extends = \"b.toml\"\n")},
+		"cycle/b.toml":   {Data: []byte("extends = \"a.toml\"\n")},
+		"bad/type.toml":  {Data: []byte("extends = 1\n")},
+		"bad/noent.toml": {Data: []byte("extends = \"missing.toml\"\n")},
+	}
+	type config struct {
+		Name string
+		Port int
+	}
+	cfg := DefaultConfig
+	cfg.ExtendsKey = "extends"
+
+	var v config
+	if err := cfg.Load(context.Background(), &v, FSSource(fsys, "conf/app.toml")); err != nil {
+		t.Fatal(err)
+	}
+	if want := (config{Name: "app", Port: 8080}); v != want {
+		t.Errorf("wrong value after Load: got %+v, want %+v", v, want)
+	}
+
+	tests := map[string]string{
+		"cycle/a.toml":   "cycle/b.toml: extends cycle cycle/a.toml -> cycle/b.toml -> cycle/a.toml",
+		"bad/type.toml":  "bad/type.toml: line 1: `extends' must be a string",
+		"bad/noent.toml": "bad/missing.toml: open bad/missing.toml: file does not exist",
+	}
+	for file, want := range tests {
+		err := cfg.Load(context.Background(), &v, FSSource(fsys, file))
+		if err == nil || err.Error() != want {
+			t.Errorf("%s: got error %q, want %q", file, err, want)
+		}
+	}
+}
+
+func TestLoadError(t *testing.T) {
+	fsys := fstest.MapFS{"bad.toml": {Data: []byte(`a = `)}}
+	var v map[string]interface{}
+
+	err := Load(context.Background(), &v, FSSource(fsys, "bad.toml"))
+	if err == nil || err.Error() != "bad.toml: line 1: invalid TOML syntax, expected a value\n\ta = \n\t    ^" {
+		t.Errorf("wrong error: %v", err)
+	}
+	err = Load(context.Background(), &v, FSSource(fsys, "missing.toml"))
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("wrong error for missing file: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := Load(ctx, &v, FSSource(fsys, "bad.toml")); err != context.Canceled {
+		t.Errorf("wrong error for canceled context: %v", err)
+	}
+}
+
+func TestUnmarshalProfile(t *testing.T) {
+	input := []byte(`
+debug = true
+
+[server]
+host = "localhost"
+port = 8080
+
+[profiles.production]
+debug = false
+
+[profiles.production.server]
+host = "example.com"
+
+[profiles.test]
+server = {port = 9999}
+`)
+	type server struct {
+		Host string
+		Port int
+	}
+	type config struct {
+		Debug  bool
+		Server server
+	}
+	tests := []struct {
+		profile string
+		want    config
+		err     string
+	}{
+		{profile: "", want: config{true, server{"localhost", 8080}}},
+		{profile: "production", want: config{false, server{"example.com", 8080}}},
+		{profile: "test", want: config{true, server{"localhost", 9999}}},
+		{profile: "missing", err: "profile `missing' is not defined"},
+	}
+	for _, test := range tests {
+		var v config
+		err := UnmarshalProfile(input, test.profile, &v)
+		if test.err != "" {
+			if err == nil || err.Error() != test.err {
+				t.Errorf("profile %q: wrong error %v", test.profile, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("profile %q: unexpected error: %v", test.profile, err)
+		}
+		if !reflect.DeepEqual(v, test.want) {
+			t.Errorf("profile %q: got %+v, want %+v", test.profile, v, test.want)
+		}
+	}
+}
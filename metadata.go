@@ -0,0 +1,176 @@
+package toml
+
+import (
+	"reflect"
+	"sort"
+
+	"github.com/naoina/toml/ast"
+)
+
+// MetaData describes the keys of a decoded document. Key paths have the form used by
+// Positions, e.g.
+//
+//	servers[1].port
+type MetaData struct {
+	table   *ast.Table
+	decoded map[string]bool // paths of decoded keys, true if decoded as a whole
+	set     map[fieldRef]bool
+}
+
+// fieldRef identifies a struct field by its address. The type is needed because the
+// first field of a struct has the same address as the struct.
+type fieldRef struct {
+	addr uintptr
+	typ  reflect.Type
+}
+
+// UnmarshalMetaData is like Unmarshal, but also returns the keys of data and whether they
+// were decoded into v. The MetaData is returned even if decoding fails.
+func (cfg *Config) UnmarshalMetaData(data []byte, v interface{}) (*MetaData, error) {
+	table, err := cfg.parseBytes(data)
+	if err != nil {
+		return nil, err
+	}
+	md := &MetaData{table: table, decoded: make(map[string]bool), set: make(map[fieldRef]bool)}
+	c := *cfg
+	c.meta = md
+	return md, c.UnmarshalTable(table, v)
+}
+
+// markDecoded records that the key at path has been decoded. If whole is true, all keys
+// contained in its value have been decoded too, e.g. by an Unmarshaler.
+func (cfg *Config) markDecoded(path *keyPath, whole bool) {
+	if cfg.meta != nil {
+		p := path.String()
+		cfg.meta.decoded[p] = cfg.meta.decoded[p] || whole
+	}
+}
+
+// markSet records that struct field fv has been assigned.
+func (cfg *Config) markSet(fv reflect.Value) {
+	if cfg.meta != nil && fv.CanAddr() {
+		cfg.meta.set[fieldRef{fv.Addr().Pointer(), fv.Type()}] = true
+	}
+}
+
+// WasSet reports whether the struct field pointed to by ptr was assigned while decoding.
+// This distinguishes keys which are absent from keys set to the zero value:
+//
+//	md, err := toml.UnmarshalMetaData(data, &override)
+//	if md.WasSet(&override.Port) {
+//		cfg.Port = override.Port
+//	}
+//
+// Fields which failed to decode are not set. With Config.AppendSlices, the fields of
+// elements appended to an existing slice may have been moved by append and aren't found.
+func (md *MetaData) WasSet(ptr interface{}) bool {
+	rv := reflect.ValueOf(ptr)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return false
+	}
+	return md.set[fieldRef{rv.Pointer(), rv.Type().Elem()}]
+}
+
+// IsDefined reports whether the key with the given path is defined in the document.
+// Keys of array tables and of inline tables in arrays are defined if any element
+// defines them:
+//
+//	md.IsDefined("servers", "port")
+func (md *MetaData) IsDefined(key ...string) bool {
+	return len(key) > 0 && isDefined(md.table, key)
+}
+
+func isDefined(field interface{}, key []string) bool {
+	if len(key) == 0 {
+		return true
+	}
+	switch f := field.(type) {
+	case *ast.Table:
+		child, ok := f.Fields[key[0]]
+		return ok && isDefined(child, key[1:])
+	case []*ast.Table:
+		for _, t := range f {
+			if isDefined(t, key) {
+				return true
+			}
+		}
+	case *ast.KeyValue:
+		return isDefined(f.Value, key)
+	case *ast.Array:
+		for _, elem := range f.Value {
+			if isDefined(elem, key) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Keys returns the paths of all keys in the document, in document order. Tables which
+// are only defined by the headers of their sub-tables are included.
+func (md *MetaData) Keys() []string {
+	var keys []string
+	md.walk(func(path string, covered bool) { keys = append(keys, path) })
+	return keys
+}
+
+// Undecoded returns the paths of all keys in the document which were not decoded, in
+// document order. These are keys which didn't match a struct field, e.g. because unknown
+// fields are ignored, and keys removed by Config.SkipKeys. The keys contained in values
+// decoded by Unmarshaler or UnmarshalerRec, or captured by Primitive or RawMessage, count
+// as decoded.
+func (md *MetaData) Undecoded() []string {
+	var keys []string
+	md.walk(func(path string, covered bool) {
+		if !covered {
+			keys = append(keys, path)
+		}
+	})
+	return keys
+}
+
+// walk calls fn for every key of the document. covered is true if the key or one of its
+// parents was decoded as a whole.
+func (md *MetaData) walk(fn func(path string, covered bool)) {
+	w := &metaWalker{md: md, fn: fn}
+	w.table(nil, md.table, md.decoded[""])
+}
+
+type metaWalker struct {
+	md *MetaData
+	fn func(path string, covered bool)
+}
+
+func (w *metaWalker) table(path *keyPath, t *ast.Table, covered bool) {
+	keys := make([]string, 0, len(t.Fields))
+	for key := range t.Fields {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return fieldPos(t.Fields[keys[i]]) < fieldPos(t.Fields[keys[j]]) })
+	for _, key := range keys {
+		child := path.child(key)
+		p := child.String()
+		whole, decoded := w.md.decoded[p]
+		w.fn(p, covered || decoded)
+		w.field(child, t.Fields[key], covered || whole)
+	}
+}
+
+func (w *metaWalker) field(path *keyPath, field interface{}, covered bool) {
+	switch f := field.(type) {
+	case *ast.Table:
+		w.table(path, f, covered)
+	case []*ast.Table:
+		for i, t := range f {
+			elem := path.elem(i)
+			w.table(elem, t, covered || w.md.decoded[elem.String()])
+		}
+	case *ast.KeyValue:
+		w.field(path, f.Value, covered)
+	case *ast.Array:
+		for i, v := range f.Value {
+			elem := path.elem(i)
+			w.field(elem, v, covered || w.md.decoded[elem.String()])
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package toml
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMetaData(t *testing.T) {
+	input := `name = "app"
+extra = 1
+
+[[servers]]
+host = "a"
+port = 80
+
+[[servers]]
+host = "b"
+debug = true
+
+[raw]
+x = 1
+y = { z = 2 }
+
+[a.b]
+c = [{ d = 1 }]
+`
+	var v struct {
+		Name    string
+		Servers []struct {
+			Host string
+			Port int
+		}
+		Raw RawMessage
+	}
+	md, err := DefaultConfig.With(IgnoreUnknownFields()).UnmarshalMetaData([]byte(input), &v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantKeys := []string{
+		"name", "extra", "servers", "servers[0].host", "servers[0].port",
+		"servers[1].host", "servers[1].debug", "raw", "raw.x", "raw.y", "raw.y.z",
+		"a", "a.b", "a.b.c", "a.b.c[0].d",
+	}
+	if keys := md.Keys(); !reflect.DeepEqual(keys, wantKeys) {
+		t.Errorf("wrong keys:\ngot  %q\nwant %q", keys, wantKeys)
+	}
+	wantUndecoded := []string{"extra", "servers[1].debug", "a", "a.b", "a.b.c", "a.b.c[0].d"}
+	if keys := md.Undecoded(); !reflect.DeepEqual(keys, wantUndecoded) {
+		t.Errorf("wrong undecoded keys:\ngot  %q\nwant %q", keys, wantUndecoded)
+	}
+
+	tests := []struct {
+		key  []string
+		want bool
+	}{
+		{[]string{"name"}, true},
+		{[]string{"servers", "debug"}, true},
+		{[]string{"servers", "other"}, false},
+		{[]string{"raw", "y", "z"}, true},
+		{[]string{"a", "b", "c", "d"}, true},
+		{[]string{"name", "x"}, false},
+		{nil, false},
+	}
+	for _, test := range tests {
+		if got := md.IsDefined(test.key...); got != test.want {
+			t.Errorf("IsDefined(%q) = %t, want %t", test.key, got, test.want)
+		}
+	}
+}
+
+func TestMetaDataWasSet(t *testing.T) {
+	input := `
+port = 0
+[server]
+host = ""
+[[users]]
+name = "a"
+`
+	var v struct {
+		Port    int
+		Timeout int
+		Server  struct {
+			Host string
+			TLS  bool
+		}
+		Users []struct {
+			Name  string
+			Admin bool
+		}
+		Log *struct{ Level string }
+	}
+	md, err := UnmarshalMetaData([]byte(input), &v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		ptr  interface{}
+		want bool
+	}{
+		{"port", &v.Port, true},
+		{"timeout", &v.Timeout, false},
+		{"server", &v.Server, true},
+		{"server.host", &v.Server.Host, true},
+		{"server.tls", &v.Server.TLS, false},
+		{"users", &v.Users, true},
+		{"users[0].name", &v.Users[0].Name, true},
+		{"users[0].admin", &v.Users[0].Admin, false},
+		{"log", &v.Log, false},
+		{"non-pointer", v.Port, false},
+	}
+	for _, test := range tests {
+		if got := md.WasSet(test.ptr); got != test.want {
+			t.Errorf("WasSet(%s) = %t, want %t", test.name, got, test.want)
+		}
+	}
+}
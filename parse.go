@@ -1,10 +1,18 @@
 package toml
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"reflect"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
 
 	"github.com/naoina/toml/ast"
 )
@@ -16,11 +24,15 @@ import (
 
 //go:generate peg -switch -inline parse.peg
 
+// ErrSyntax is the SyntaxError for input which cannot be parsed at all. More specific
+// syntax errors are returned for some common mistakes.
+var ErrSyntax error = &SyntaxError{Msg: "invalid TOML syntax"}
+
 var (
-	errParse                    = errors.New("invalid TOML syntax")
-	errNewlineRequired          = errors.New("newline required in table")
-	errInlineTableCommaRequired = errors.New("missing ',' in inline table")
-	errInlineTableCommaAtEnd    = errors.New("inline table cannot contain ',' after last key/value pair")
+	errNewlineRequired          = &SyntaxError{Msg: "newline required in table"}
+	errInlineTableCommaRequired = &SyntaxError{Msg: "missing ',' in inline table"}
+	errInlineTableCommaAtEnd    = &SyntaxError{Msg: "inline table cannot contain ',' after last key/value pair"}
+	errBareCR                   = &SyntaxError{Msg: "carriage return without line feed"}
 )
 
 var (
@@ -38,29 +50,334 @@ var (
 // Parse returns an AST representation of TOML.
 // The toplevel is represented by a table.
 func Parse(data []byte) (*ast.Table, error) {
-	d := &parseState{p: &tomlParser{Buffer: string(data)}}
-	d.init()
+	return parse(data, &parseOptions{})
+}
+
+// Parser converts TOML data into its AST representation. It can be set in Config to
+// replace the built-in parser.
+type Parser interface {
+	Parse(data []byte) (*ast.Table, error)
+}
+
+// ParserFunc adapts an ordinary function to the Parser interface. For example,
+// ParserFunc(Parse) is the built-in parser.
+type ParserFunc func(data []byte) (*ast.Table, error)
+
+// Parse calls f(data).
+func (f ParserFunc) Parse(data []byte) (*ast.Table, error) {
+	return f(data)
+}
+
+// parseBytes parses data using the configured parser.
+func (cfg *Config) parseBytes(data []byte) (*ast.Table, error) {
+	if cfg.Parser != nil {
+		return cfg.Parser.Parse(data)
+	}
+	return parse(data, cfg.parseOptions())
+}
+
+// parseOptions returns the options of the built-in parser.
+func (cfg *Config) parseOptions() *parseOptions {
+	return &parseOptions{
+		version:      cfg.Version,
+		rejectBOM:    cfg.RejectBOM,
+		bareCR:       cfg.AllowBareCR,
+		leadingZeros: cfg.AllowLeadingZeros,
+		warn:         cfg.Warning,
+	}
+}
+
+// parseReader parses the data read from r using the configured parser. The options
+// apply to the built-in parser.
+func (cfg *Config) parseReader(r io.Reader, opts ...ParseOption) (*ast.Table, error) {
+	if cfg.Parser == nil {
+		o := cfg.parseOptions()
+		for _, opt := range opts {
+			opt(o)
+		}
+		return readAndParse(r, o)
+	}
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return cfg.Parser.Parse(data)
+}
+
+// MustParse is like Parse but panics if the data cannot be parsed. The panic message
+// includes the offending source line. It is intended for validating embedded
+// default configurations during package initialization.
+func MustParse(data []byte) *ast.Table {
+	t, err := Parse(data)
+	if err != nil {
+		mustPanic("MustParse", data, err)
+	}
+	return t
+}
+
+// ParseReader reads all data from r and returns its AST representation.
+// The options can be used to restrict the input and to control the content of the AST.
+func ParseReader(r io.Reader, opts ...ParseOption) (*ast.Table, error) {
+	var o parseOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return readAndParse(r, &o)
+}
+
+func readAndParse(r io.Reader, o *parseOptions) (*ast.Table, error) {
+	if o.incremental {
+		return parseIncremental(r, o)
+	}
+	if o.maxSize > 0 {
+		r = io.LimitReader(r, o.maxSize+1)
+	}
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if o.maxSize > 0 && int64(len(data)) > o.maxSize {
+		return nil, fmt.Errorf("toml: input exceeds the maximum size of %d bytes", o.maxSize)
+	}
+	return parse(data, o)
+}
+
+// ParseOption is an option of ParseReader.
+type ParseOption func(*parseOptions)
+
+type parseOptions struct {
+	maxSize   int64
+	maxDepth  int
+	version   string
+	comments  bool
+	noSource  bool
+	rejectBOM bool
+	bareCR    bool
+
+	incremental bool
+
+	leadingZeros bool
+	warn         func(err error)
+}
+
+// MaxSize limits the size of the input to n bytes.
+func MaxSize(n int64) ParseOption {
+	return func(o *parseOptions) { o.maxSize = n }
+}
+
+// MaxDepth limits the nesting depth of tables and arrays. Keys of the top-level table
+// are at depth 1. The limit is checked while the AST is built, which stops at the first
+// value exceeding it.
+func MaxDepth(n int) ParseOption {
+	return func(o *parseOptions) { o.maxDepth = n }
+}
+
+// Version restricts the input to the features of the given TOML version, e.g. "0.4.0".
+// The supported versions are 0.4, 0.5, 1.0 and 1.1. By default, the features of TOML 1.0
+// are accepted, and the features added by TOML 1.1 are rejected.
+func Version(v string) ParseOption {
+	return func(o *parseOptions) { o.version = v }
+}
+
+// Comments makes the parser record all comments in the Comments field of the top-level
+// table.
+func Comments() ParseOption {
+	return func(o *parseOptions) { o.comments = true }
+}
+
+// RejectBOM makes the parser reject documents starting with a UTF-8 byte order mark.
+// By default, the byte order mark is skipped.
+func RejectBOM() ParseOption {
+	return func(o *parseOptions) { o.rejectBOM = true }
+}
+
+// AllowBareCR makes the parser accept a carriage return which isn't followed by a line
+// feed as a line break. By default, such carriage returns are rejected.
+func AllowBareCR() ParseOption {
+	return func(o *parseOptions) { o.bareCR = true }
+}
+
+// AllowLeadingZeros makes the parser accept decimal integers and floats with leading
+// zeros, e.g. 08080, which some parsers for TOML 0.4 accepted. The zeros are ignored,
+// so such numbers are never octal. If warn is non-nil, it is called with a *LineError
+// for every such number.
+func AllowLeadingZeros(warn func(err error)) ParseOption {
+	return func(o *parseOptions) { o.leadingZeros, o.warn = true, warn }
+}
+
+// DiscardSource removes the source text (the Data fields) from the AST, so the input
+// doesn't need to be retained in memory as long as the AST is. Values that implement
+// Unmarshaler receive the canonical form of their value when decoding such an AST.
+// Use it to reduce memory use further in slim builds (see the toml_slim build tag).
+func DiscardSource() ParseOption {
+	return func(o *parseOptions) { o.noSource = true }
+}
+
+// Incremental makes ParseReader parse the input in parts while reading it, instead of
+// reading all of it first. Parts end before table headers, so memory use is bounded by
+// the size of the AST and the largest table rather than the whole document. It implies
+// DiscardSource.
+func Incremental() ParseOption {
+	return func(o *parseOptions) { o.incremental = true }
+}
+
+// utf8BOM is the UTF-8 encoding of the byte order mark U+FEFF.
+var utf8BOM = []byte("\xef\xbb\xbf")
+
+func parse(data []byte, o *parseOptions) (*ast.Table, error) {
+	if o.version != "" && !knownVersion(o.version) {
+		return nil, fmt.Errorf("toml: unsupported TOML version %q", o.version)
+	}
+	if bytes.HasPrefix(data, utf8BOM) {
+		if o.rejectBOM {
+			return nil, lineError(1, errors.New("byte order mark at start of document"))
+		}
+		data = data[len(utf8BOM):]
+	}
+	if err := checkEncoding(data, o.bareCR); err != nil {
+		return nil, err
+	}
+	if o.bareCR {
+		data = replaceBareCR(data)
+	}
+	d := &parseState{p: getParser()}
+	defer putParser(d.p)
+	d.init(data)
+	d.p.toml.version = o.version
+	d.p.toml.maxDepth = o.maxDepth
+	d.p.toml.allowLeadingZeros = o.leadingZeros
+	d.p.toml.warn = o.warn
 
 	if err := d.parse(); err != nil {
 		return nil, err
 	}
+	t := d.p.toml.topTable
+	if o.comments {
+		t.Comments = d.comments()
+	}
+	if o.version != "" || o.noSource {
+		if err := checkTable(t, o); err != nil {
+			return nil, err
+		}
+	}
+	return t, nil
+}
+
+// checkEncoding reports the first invalid UTF-8 sequence or raw control character in
+// data. The parser works on runes, which would silently replace invalid sequences.
+// Carriage returns which aren't followed by a line feed are reported unless bareCR is
+// set.
+func checkEncoding(data []byte, bareCR bool) error {
+	line := 1
+	for i := 0; i < len(data); {
+		c := data[i]
+		if c < utf8.RuneSelf {
+			switch {
+			case c == '\n':
+				line++
+			case c == '\r' && !bytes.HasPrefix(data[i+1:], []byte{'\n'}):
+				if !bareCR {
+					return lineError(line, errBareCR)
+				}
+				line++
+			case c < 0x20 && c != '\t' && c != '\r' || c == 0x7F:
+				return lineError(line, &rawControlError{rune(c)})
+			}
+			i++
+			continue
+		}
+		r, size := utf8.DecodeRune(data[i:])
+		if r == utf8.RuneError && size == 1 {
+			return lineError(line, fmt.Errorf("invalid UTF-8 at byte offset %d", i))
+		}
+		i += size
+	}
+	return nil
+}
+
+// replaceBareCR returns data with all carriage returns which aren't followed by a line
+// feed replaced by line feeds. The input is copied if it contains such carriage returns.
+func replaceBareCR(data []byte) []byte {
+	copied := false
+	for i, c := range data {
+		if c != '\r' || i+1 < len(data) && data[i+1] == '\n' {
+			continue
+		}
+		if !copied {
+			data = append([]byte(nil), data...)
+			copied = true
+		}
+		data[i] = '\n'
+	}
+	return data
+}
+
+// parserPool holds initialized parsers. Setting up the rule table of a parser is
+// expensive, so parsers are reused by Parse.
+var parserPool = sync.Pool{
+	New: func() interface{} {
+		p := new(tomlParser)
+		p.Init()
+		return p
+	},
+}
+
+func getParser() *tomlParser {
+	return parserPool.Get().(*tomlParser)
+}
 
-	return d.p.toml.topTable, nil
+func putParser(p *tomlParser) {
+	if cap(p.tokens32.tree) > maxPooledTokens {
+		return
+	}
+	// Release the document. The AST keeps referencing the old p.buffer, but Reset
+	// always allocates a new one.
+	p.Buffer = ""
+	p.Reset()
+	p.toml = toml{}
+	parserPool.Put(p)
 }
 
 type parseState struct {
 	p *tomlParser
 }
 
-func (d *parseState) init() {
-	d.p.Init()
+func (d *parseState) init(data []byte) {
+	d.p.Buffer = string(data)
+	d.p.Reset()
 	d.p.toml.init(d.p.buffer)
 }
 
+// comments returns the comments matched by the parser.
+func (d *parseState) comments() []*ast.Comment {
+	var comments []*ast.Comment
+	for _, token := range d.p.Tokens() {
+		if token.pegRule == rulecomment {
+			begin, end := int(token.begin), int(token.end)
+			comments = append(comments, &ast.Comment{
+				Position: d.p.position(begin, end),
+				Text:     string(d.p.buffer[begin+1 : end]),
+			})
+		}
+	}
+	sort.Slice(comments, func(i, j int) bool { return comments[i].Position.Begin < comments[j].Position.Begin })
+	line, pos := d.p.firstLine, 0
+	for _, c := range comments {
+		for ; pos < c.Position.Begin-d.p.base; pos++ {
+			if d.p.buffer[pos] == '\n' {
+				line++
+			}
+		}
+		c.Line = line
+	}
+	return comments
+}
+
 func (d *parseState) parse() error {
 	if err := d.p.Parse(); err != nil {
 		if err, ok := err.(*parseError); ok {
-			return lineError(err.Line(), errParse)
+			serr := err.syntaxError()
+			return &LineError{Line: err.Line(), Column: serr.column, Err: serr}
 			// return lineError(err.Line(), errors.New("parse error:\n"+d.p.SprintSyntaxTree()))
 		}
 		return err
@@ -82,6 +399,76 @@ func (d *parseState) execute() (err error) {
 	return nil
 }
 
+// syntaxError returns ErrSyntax with the source line containing the first character
+// which couldn't be matched.
+func (e *parseError) syntaxError() *SyntaxError {
+	buf, pos := e.p.buffer, int(e.max.end)
+	if pos >= len(buf) {
+		return ErrSyntax.(*SyntaxError)
+	}
+	start, end := pos, pos
+	for start > 0 && buf[start-1] != '\n' {
+		start--
+	}
+	for end < len(buf) && buf[end] != '\n' && buf[end] != endSymbol {
+		end++
+	}
+	line := strings.TrimSuffix(string(buf[start:end]), "\r")
+	if pos-start > len([]rune(line)) || e.p.firstLine+strings.Count(string(buf[:start]), "\n") != e.Line() {
+		return ErrSyntax.(*SyntaxError)
+	}
+	return &SyntaxError{
+		Msg:      ErrSyntax.(*SyntaxError).Msg,
+		Expected: expectedTokens(buf[:len(buf)-1], pos),
+		source:   line,
+		column:   pos - start + 1,
+	}
+}
+
+// isBareKeyChar reports whether the grammar allows r in bare keys. Non-ASCII characters
+// are matched for all versions and rejected later.
+func isBareKeyChar(r rune) bool {
+	return r >= '0' && r <= '9' || r >= 'A' && r <= 'Z' || r >= 'a' && r <= 'z' || r == '-' || r == '_' || isUnicodeBareKeyChar(r)
+}
+
+// syntaxCandidates are the tokens tried by expectedTokens. The key and value are chosen
+// so that neither of them is also valid as the other.
+var syntaxCandidates = []struct {
+	text, desc string
+}{
+	{"=", "'='"},
+	{".", "'.'"},
+	{",", "','"},
+	{"]", "']'"},
+	{"}", "'}'"},
+	{"k", "a key"},
+	{"{}", "a value"},
+	{"\n", "end of line"},
+}
+
+// expectedTokens returns the descriptions of the tokens which are valid at offset pos of
+// the document src, which cannot be parsed beyond pos. The grammar doesn't record which
+// characters it tried, so each candidate is inserted at pos, followed by a space, and
+// accepted if the parser gets past it. The parser only records the progress of rules,
+// and the space is matched by the whitespace rule after any of the candidates.
+func expectedTokens(src []rune, pos int) []string {
+	var expected []string
+	p := getParser()
+	defer putParser(p)
+	for _, c := range syntaxCandidates {
+		if c.desc == "a key" && pos > 0 && isBareKeyChar(src[pos-1]) {
+			continue // this would only extend the previous key
+		}
+		p.Buffer = string(src[:pos]) + c.text + " " + string(src[pos:])
+		p.Reset()
+		err := p.Parse()
+		if perr, ok := err.(*parseError); err == nil || ok && int(perr.max.end) > pos+len(c.text) {
+			expected = append(expected, c.desc)
+		}
+	}
+	return expected
+}
+
 func (e *parseError) Line() int {
 	tokens := []token32{e.max}
 	positions, p := make([]int, 2*len(tokens)), 0
@@ -89,17 +476,19 @@ func (e *parseError) Line() int {
 		positions[p], p = int(token.begin), p+1
 		positions[p], p = int(token.end), p+1
 	}
+	line := e.p.firstLine
 	for _, t := range translatePositions(e.p.buffer, positions) {
-		if e.p.line < t.line {
-			e.p.line = t.line
+		if l := e.p.firstLine + t.line - 1; line < l {
+			line = l
 		}
 	}
-	return e.p.line
+	return line
 }
 
 type tabStackElem struct {
-	key   string
-	table *ast.Table
+	key    string
+	keyPos int
+	table  *ast.Table
 }
 
 type array struct {
@@ -116,21 +505,125 @@ type toml struct {
 	curArray    *array          // the current array
 	stringBuf   string          // temporary buffer for string values
 	key         string          // the current table key
+	pos         int             // offset of the last key or value, for error columns
+	keyPos      int             // offset of the current table key
 	tableKeyAcc []string        // accumulator for dotted keys
+	header      []string        // keys of the last table header
 	val         ast.Value       // last decoded value
 	tabStack    []*tabStackElem // table stack (for inline tables)
+	interned    map[uint64]string
+	version     string // the selected TOML version
+	maxDepth    int    // the maximum nesting depth, 0 for no limit
+
+	src       []rune // the source being parsed, without the end symbol
+	base      int    // offset of src in the document
+	firstLine int    // line number of the start of src
+	noData    bool   // don't reference src in the AST
+
+	allowLeadingZeros bool            // accept numbers with leading zeros
+	warn              func(err error) // receives warnings, may be nil
+}
+
+// maxInternLen is the maximum length of interned strings.
+const maxInternLen = 32
+
+// intern returns buf as a string. Keys and short strings repeat a lot in large
+// documents, especially in array tables, so their storage is shared. The lookup
+// doesn't allocate when buf was seen before.
+func (p *toml) intern(buf []rune) string {
+	if len(buf) > maxInternLen {
+		return string(buf)
+	}
+	h := fnvOffset
+	for _, r := range buf {
+		h = (h ^ uint64(r)) * fnvPrime
+	}
+	if s, ok := p.interned[h]; ok && runesEqual(s, buf) {
+		return s
+	}
+	s := string(buf)
+	p.interned[h] = s
+	return s
+}
+
+// internString is like intern, but for strings which are already allocated.
+func (p *toml) internString(s string) string {
+	if len(s) > maxInternLen {
+		return s
+	}
+	h := fnvOffset
+	for _, r := range s {
+		h = (h ^ uint64(r)) * fnvPrime
+	}
+	if is, ok := p.interned[h]; ok && is == s {
+		return is
+	}
+	p.interned[h] = s
+	return s
+}
+
+const (
+	fnvOffset uint64 = 14695981039346656037
+	fnvPrime  uint64 = 1099511628211
+)
+
+func runesEqual(s string, buf []rune) bool {
+	i := 0
+	for _, r := range s {
+		if i >= len(buf) || buf[i] != r {
+			return false
+		}
+		i++
+	}
+	return i == len(buf)
 }
 
 func (p *toml) init(data []rune) {
 	p.line = 1
+	p.interned = make(map[uint64]string)
 	p.topTable = p.newTable(ast.TableTypeNormal, "")
 	p.topTable.Position.End = len(data) - 1
 	p.topTable.Data = data[:len(data)-1] // truncate the end_symbol added by PEG parse generator.
 	p.curTable = p.topTable
+	p.src, p.firstLine = p.topTable.Data, 1
+}
+
+// position returns the position of the source between begin and end in the document.
+func (p *toml) position(begin, end int) ast.Position {
+	return ast.Position{Begin: p.base + begin, End: p.base + end}
+}
+
+// source returns the source between begin and end for the Data field of AST nodes.
+func (p *toml) source(begin, end int) []rune {
+	if p.noData {
+		return nil
+	}
+	return p.src[begin:end]
 }
 
+// Error panics with err as a *LineError for the current line. If the last key or value
+// is on the current line, its column is reported.
 func (p *toml) Error(err error) {
-	panic(lineError(p.line, err))
+	panic(p.lineError(err))
+}
+
+func (p *toml) lineError(err error) error {
+	if lerr, ok := err.(*LineError); ok {
+		return lerr
+	}
+	lerr := &LineError{Line: p.line, Err: err}
+	if data := p.src; p.pos < len(data) {
+		line, start := p.firstLine, 0
+		for i, c := range data[:p.pos] {
+			if c == '\n' {
+				line, start = line+1, i+1
+			}
+		}
+		if line == p.line {
+			lerr.Column = p.pos - start + 1
+		}
+	}
+	return lerr
 }
 
 // Newline is called whenever the parser moves to a new line.
@@ -141,40 +634,87 @@ func (p *toml) Newline() {
 // -- Primitive Value Callbacks --
 
 func (p *tomlParser) SetTime(begin, end int) {
+	p.pos = begin
 	// Make value compatible with time.Parse.
 	v := timeLetterReplacer.Replace(string(p.buffer[begin:end]))
+	// Times without seconds are normalized to :00.
+	sec := 5
+	if strings.IndexByte(v, '-') == 4 {
+		sec = 16 // after the date and the delimiter
+	}
+	if len(v) == sec || len(v) > sec && v[sec] != ':' {
+		if versions[p.version] < 11 {
+			p.Error(versionError("times without seconds", "1.1.0", p.version))
+		}
+		v = v[:sec] + ":00" + v[sec:]
+	}
+	if err := checkDatetime(v); err != nil {
+		p.Error(fmt.Errorf("invalid datetime %s for key `%s': %v", string(p.buffer[begin:end]), p.key, err))
+	}
 	p.val = &ast.Datetime{
-		Position: ast.Position{Begin: begin, End: end},
-		Data:     p.buffer[begin:end],
+		Position: p.position(begin, end),
+		Data:     p.source(begin, end),
 		Value:    v,
 	}
 }
 
 func (p *tomlParser) SetFloat(begin, end int) {
+	p.pos = begin
 	// Make value compatible with strconv.ParseFloat.
 	v := underscoreReplacer.Replace(string(p.buffer[begin:end]))
 	if v == "+nan" || v == "-nan" {
 		v = "nan"
 	}
+	p.leadingZeros(v)
 	p.val = &ast.Float{
-		Position: ast.Position{Begin: begin, End: end},
-		Data:     p.buffer[begin:end],
+		Position: p.position(begin, end),
+		Data:     p.source(begin, end),
 		Value:    v,
 	}
 }
 
 func (p *tomlParser) SetInteger(begin, end int) {
+	p.pos = begin
+	v := underscoreReplacer.Replace(string(p.buffer[begin:end]))
+	if p.leadingZeros(v) {
+		// Strip the zeros, the value would be parsed as octal otherwise.
+		sign, digits := "", v
+		if v[0] == '+' || v[0] == '-' {
+			sign, digits = v[:1], v[1:]
+		}
+		if digits = strings.TrimLeft(digits, "0"); digits == "" {
+			digits = "0"
+		}
+		v = sign + digits
+	}
 	p.val = &ast.Integer{
-		Position: ast.Position{Begin: begin, End: end},
-		Data:     p.buffer[begin:end],
-		Value:    underscoreReplacer.Replace(string(p.buffer[begin:end])),
+		Position: p.position(begin, end),
+		Data:     p.source(begin, end),
+		Value:    v,
+	}
+}
+
+// leadingZeros reports whether decimal number v has leading zeros, e.g. 08080. This is
+// an error unless leading zeros are allowed.
+func (p *toml) leadingZeros(v string) bool {
+	digits := strings.TrimLeft(v, "+-")
+	if len(digits) < 2 || digits[0] != '0' || digits[1] < '0' || digits[1] > '9' {
+		return false
+	}
+	err := fmt.Errorf("number %s has leading zeros", v)
+	if !p.allowLeadingZeros {
+		p.Error(err)
+	}
+	if p.warn != nil {
+		p.warn(p.lineError(err))
 	}
+	return true
 }
 
 func (p *tomlParser) SetString(begin, end int) {
 	p.val = &ast.String{
-		Position: ast.Position{Begin: begin, End: end},
-		Data:     p.buffer[begin:end],
+		Position: p.position(begin, end),
+		Data:     p.source(begin, end),
 		Value:    p.stringBuf,
 	}
 	p.stringBuf = ""
@@ -182,9 +722,9 @@ func (p *tomlParser) SetString(begin, end int) {
 
 func (p *tomlParser) SetBool(begin, end int) {
 	p.val = &ast.Boolean{
-		Position: ast.Position{Begin: begin, End: end},
-		Data:     p.buffer[begin:end],
-		Value:    string(p.buffer[begin:end]),
+		Position: p.position(begin, end),
+		Data:     p.source(begin, end),
+		Value:    p.intern(p.buffer[begin:end]),
 	}
 }
 
@@ -193,7 +733,7 @@ func (p *tomlParser) SetBool(begin, end int) {
 // These run during string parsing and build up the string in p.stringBuf.
 
 func (p *toml) SetBasicString(buf []rune, begin, end int) {
-	p.stringBuf = p.unquote(string(buf[begin:end]))
+	p.stringBuf = p.internString(p.unquote(string(buf[begin:end])))
 }
 
 func (p *toml) SetMultilineBasicString() {
@@ -209,7 +749,7 @@ func (p *toml) AddMultilineBasicQuote() {
 }
 
 func (p *toml) SetLiteralString(buf []rune, begin, end int) {
-	p.stringBuf = string(buf[begin:end])
+	p.stringBuf = p.intern(buf[begin:end])
 }
 
 func (p *toml) SetMultilineLiteralString(buf []rune, begin, end int) {
@@ -217,6 +757,14 @@ func (p *toml) SetMultilineLiteralString(buf []rune, begin, end int) {
 }
 
 func (p *toml) unquote(s string) string {
+	if strings.Contains(s, `\e`) || strings.Contains(s, `\x`) {
+		s = p.convertEscapes(s)
+	}
+	if strings.Contains(s, `\u`) || strings.Contains(s, `\U`) {
+		if err := checkUnicodeEscapes(s); err != nil {
+			p.Error(err)
+		}
+	}
 	s, err := strconv.Unquote(s)
 	if err != nil {
 		p.Error(err)
@@ -224,6 +772,64 @@ func (p *toml) unquote(s string) string {
 	return s
 }
 
+// convertEscapes replaces the \e and \xHH escapes of TOML 1.1 by the equivalent \u
+// escapes, which strconv.Unquote understands. They are only accepted if version 1.1 is
+// selected.
+func (p *toml) convertEscapes(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 'e', 'x':
+			if versions[p.version] < 11 {
+				p.Error(versionError("\\e and \\x escapes", "1.1.0", p.version))
+			}
+			if s[i] == 'e' {
+				b.WriteString(`\u001b`)
+			} else {
+				b.WriteString(`\u00`)
+			}
+		default:
+			b.WriteByte('\\')
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
+
+// checkUnicodeEscapes reports the first \u or \U escape in s which isn't a Unicode
+// scalar value, i.e. a surrogate half or a value above U+10FFFF.
+func checkUnicodeEscapes(s string) error {
+	for i := 0; i < len(s)-1; i++ {
+		if s[i] != '\\' {
+			continue
+		}
+		i++
+		var n int
+		switch s[i] {
+		case 'u':
+			n = 4
+		case 'U':
+			n = 8
+		default:
+			continue
+		}
+		if i+n >= len(s) {
+			break
+		}
+		r, err := strconv.ParseUint(s[i+1:i+1+n], 16, 32)
+		if err != nil || !utf8.ValidRune(rune(r)) {
+			return fmt.Errorf("invalid escape %s: not a Unicode scalar value", s[i-1:i+1+n])
+		}
+		i += n
+	}
+	return nil
+}
+
 // -- Array Callbacks --
 //
 // These callbacks maintain the array stack and accumulate elements.
@@ -238,12 +844,30 @@ func (p *toml) StartArray() {
 }
 
 func (p *toml) AddArrayVal() {
+	p.checkDepth(p.valueDepth())
 	p.curArray.a.Value = append(p.curArray.a.Value, p.val)
 }
 
+// valueDepth returns the nesting depth of the value being parsed. Keys of the top-level
+// table are at depth 1, and every table header key, inline table and array adds one.
+func (p *toml) valueDepth() int {
+	depth := len(p.header) + len(p.tabStack) + 1
+	for a := p.curArray; a != nil; a = a.parent {
+		depth++
+	}
+	return depth
+}
+
+// checkDepth reports an error if depth exceeds the limit set by MaxDepth.
+func (p *toml) checkDepth(depth int) {
+	if p.maxDepth > 0 && depth > p.maxDepth {
+		p.Error(fmt.Errorf("nesting depth exceeds the maximum of %d", p.maxDepth))
+	}
+}
+
 func (p *tomlParser) SetArray(begin, end int) {
-	p.curArray.a.Position = ast.Position{Begin: begin, End: end}
-	p.curArray.a.Data = p.buffer[begin:end]
+	p.curArray.a.Position = p.position(begin, end)
+	p.curArray.a.Data = p.source(begin, end)
 	p.val = &p.curArray.a
 	p.curArray = p.curArray.parent
 }
@@ -251,9 +875,11 @@ func (p *tomlParser) SetArray(begin, end int) {
 // -- Table Callbacks --
 
 func (p *toml) SetTable(buf []rune, begin, end int) {
+	p.pos = begin
+	p.checkDepth(len(p.tableKeyAcc))
 	rawName := string(buf[begin:end])
 	p.setTable(p.topTable, rawName, p.tableKeyAcc)
-	p.tableKeyAcc = nil
+	p.header, p.tableKeyAcc = p.tableKeyAcc, nil
 }
 
 func (p *toml) setTable(parent *ast.Table, name string, names []string) {
@@ -319,9 +945,8 @@ func (p *toml) lookupTable(t *ast.Table, keys []string) (*ast.Table, error) {
 
 // SetTableSource assigns the source data of a complete table.
 func (p *tomlParser) SetTableSource(begin, end int) {
-	p.curTable.Data = p.buffer[begin:end]
-	p.curTable.Position.Begin = begin
-	p.curTable.Position.End = end
+	p.curTable.Data = p.source(begin, end)
+	p.curTable.Position = p.position(begin, end)
 }
 
 func (p *toml) AddTableKey() {
@@ -330,14 +955,25 @@ func (p *toml) AddTableKey() {
 
 // SetKey is called after a table key has been parsed.
 func (p *toml) SetKey(buf []rune, begin, end int) {
-	p.key = string(buf[begin:end])
-	if len(p.key) > 0 && p.key[0] == '"' {
-		p.key = p.unquote(p.key)
+	p.pos, p.keyPos = begin, begin
+	if end > begin && buf[begin] == '"' {
+		p.key = p.internString(p.unquote(string(buf[begin:end])))
+	} else {
+		if versions[p.version] < 11 {
+			for _, r := range buf[begin:end] {
+				if r >= utf8.RuneSelf {
+					p.Error(versionError("non-ASCII bare keys", "1.1.0", p.version))
+				}
+			}
+		}
+		p.key = p.intern(buf[begin:end])
 	}
 }
 
 // AddKeyValue is called after a complete key/value pair has been parsed.
 func (p *toml) AddKeyValue() {
+	p.pos = p.keyPos
+	p.checkDepth(p.valueDepth())
 	if val, exists := p.curTable.Fields[p.key]; exists {
 		switch v := val.(type) {
 		case []*ast.Table:
@@ -356,9 +992,11 @@ func (p *toml) AddKeyValue() {
 // -- Array Table Callbacks --
 
 func (p *toml) SetArrayTable(buf []rune, begin, end int) {
+	p.pos = begin
+	p.checkDepth(len(p.tableKeyAcc))
 	rawName := string(buf[begin:end])
 	p.setArrayTable(p.topTable, rawName, p.tableKeyAcc)
-	p.tableKeyAcc = nil
+	p.header, p.tableKeyAcc = p.tableKeyAcc, nil
 }
 
 func (p *toml) setArrayTable(parent *ast.Table, name string, names []string) {
@@ -387,7 +1025,7 @@ func (p *toml) setArrayTable(parent *ast.Table, name string, names []string) {
 
 func (p *toml) StartInlineTable() {
 	tbl := p.newTable(ast.TableTypeInline, "")
-	p.tabStack = append(p.tabStack, &tabStackElem{p.key, p.curTable})
+	p.tabStack = append(p.tabStack, &tabStackElem{p.key, p.keyPos, p.curTable})
 	p.curTable = tbl
 }
 
@@ -396,16 +1034,189 @@ func (p *toml) EndInlineTable() {
 
 	// Restore parent table from stack.
 	st := p.tabStack[len(p.tabStack)-1]
-	p.key, p.curTable = st.key, st.table
+	p.key, p.keyPos, p.curTable = st.key, st.keyPos, st.table
 	p.tabStack = p.tabStack[:len(p.tabStack)-1]
 }
 
+// InlineTableNewline is called after a newline between the key/value pairs of an
+// inline table. Multi-line inline tables require TOML 1.1.
+func (p *toml) InlineTableNewline() {
+	if versions[p.version] < 11 {
+		// p.line has already moved past the newline.
+		panic(lineError(p.line-1, versionError("newlines in inline tables", "1.1.0", p.version)))
+	}
+}
+
+// InlineTableTrailingComma is called for a ',' after the last key/value pair of an
+// inline table. Trailing commas require TOML 1.1.
+func (p *toml) InlineTableTrailingComma() {
+	if versions[p.version] < 11 {
+		p.Error(errInlineTableCommaAtEnd)
+	}
+}
+
 // SetInlineTableSource sets the source data of an inline table.
 // This is called just after parsing the table value, when the table
 // is still in p.val.
 func (p *tomlParser) SetInlineTableSource(begin, end int) {
 	tbl := p.val.(*ast.Table)
-	tbl.Data = p.buffer[begin:end]
-	tbl.Position.Begin = begin
-	tbl.Position.End = end
+	tbl.Data = p.source(begin, end)
+	tbl.Position = p.position(begin, end)
+}
+
+// -- AST checks for parse options --
+
+var versions = map[string]int{
+	"0.4": 4, "0.4.0": 4,
+	"0.5": 5, "0.5.0": 5,
+	"1.0": 10, "1.0.0": 10,
+	"1.1": 11, "1.1.0": 11,
+}
+
+func knownVersion(v string) bool {
+	_, ok := versions[v]
+	return ok
+}
+
+// checkTable applies the version restrictions and source removal of o to t.
+func checkTable(t *ast.Table, o *parseOptions) error {
+	if o.noSource {
+		t.Data = nil
+	}
+	keys := make([]string, 0, len(t.Fields))
+	for key := range t.Fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		var err error
+		switch f := t.Fields[key].(type) {
+		case *ast.Table:
+			err = checkTable(f, o)
+		case []*ast.Table:
+			for _, at := range f {
+				if err = checkTable(at, o); err != nil {
+					break
+				}
+			}
+		case *ast.KeyValue:
+			err = checkValue(f.Value, o, f.Line)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func checkValue(v ast.Value, o *parseOptions, line int) error {
+	version := versions[o.version]
+	switch v := v.(type) {
+	case *ast.Table:
+		return checkTable(v, o)
+	case *ast.Array:
+		if o.noSource {
+			v.Data = nil
+		}
+		for _, elem := range v.Value {
+			if version > 0 && version < 10 && reflect.TypeOf(elem) != reflect.TypeOf(v.Value[0]) {
+				return lineError(line, versionError("arrays with mixed element types", "1.0.0", o.version))
+			}
+			if err := checkValue(elem, o, line); err != nil {
+				return err
+			}
+		}
+	case *ast.Integer:
+		if o.noSource {
+			v.Data = nil
+		}
+		if version > 0 && version < 5 && len(v.Value) > 1 && v.Value[0] == '0' && strings.ContainsAny(v.Value[1:2], "xob") {
+			return lineError(line, versionError("hexadecimal, octal and binary integers", "0.5.0", o.version))
+		}
+	case *ast.Float:
+		if o.noSource {
+			v.Data = nil
+		}
+		if version > 0 && version < 5 && (strings.HasSuffix(v.Value, "inf") || strings.HasSuffix(v.Value, "nan")) {
+			return lineError(line, versionError("inf and nan", "0.5.0", o.version))
+		}
+	case *ast.Datetime:
+		if o.noSource {
+			v.Data = nil
+		}
+		if version > 0 && version < 5 {
+			if strings.Contains(v.Value, " ") {
+				return lineError(line, versionError("spaces as date/time delimiters", "0.5.0", o.version))
+			}
+			if !hasTimeOffset(v.Value) {
+				return lineError(line, versionError("local dates and times", "0.5.0", o.version))
+			}
+		}
+	case *ast.String:
+		if o.noSource {
+			v.Data = nil
+		}
+	case *ast.Boolean:
+		if o.noSource {
+			v.Data = nil
+		}
+	}
+	return nil
+}
+
+// checkDatetime reports components of datetime value v which are out of range.
+// The syntax of v has already been checked by the grammar.
+func checkDatetime(v string) error {
+	num := func(s string) int {
+		n, _ := strconv.Atoi(s)
+		return n
+	}
+	if strings.IndexByte(v, '-') == 4 {
+		year, month, day := num(v[0:4]), num(v[5:7]), num(v[8:10])
+		if month < 1 || month > 12 {
+			return errors.New("month out of range")
+		}
+		// Day zero of the next month is the last day of this month.
+		if day < 1 || day > time.Date(year, time.Month(month)+1, 0, 0, 0, 0, 0, time.UTC).Day() {
+			return errors.New("day out of range")
+		}
+		if len(v) == 10 {
+			return nil
+		}
+		v = v[11:]
+	}
+	if num(v[0:2]) > 23 {
+		return errors.New("hour out of range")
+	}
+	if num(v[3:5]) > 59 {
+		return errors.New("minute out of range")
+	}
+	if num(v[6:8]) > 60 { // 60 is a leap second
+		return errors.New("second out of range")
+	}
+	if i := strings.IndexAny(v, "+-"); i > 0 {
+		if num(v[i+1:i+3]) > 23 || num(v[i+4:i+6]) > 59 {
+			return errors.New("time zone offset out of range")
+		}
+	}
+	return nil
+}
+
+// hasTimeOffset reports whether a datetime value contains a time zone offset.
+func hasTimeOffset(v string) bool {
+	if !strings.ContainsAny(v, "T ") {
+		return false // local date or time
+	}
+	if strings.HasSuffix(v, "Z") {
+		return true
+	}
+	n := len(v)
+	return n > 6 && (v[n-6] == '+' || v[n-6] == '-') && v[n-3] == ':'
+}
+
+func versionError(feature, since, version string) error {
+	if version == "" {
+		return fmt.Errorf("%s require TOML %s, which must be selected explicitly", feature, since)
+	}
+	return fmt.Errorf("%s require TOML %s, but version %s is selected", feature, since, version)
 }
@@ -91,6 +91,8 @@ const (
 	rulearray
 	rulearrayValues
 	rulearraySep
+	ruleinlineTableWs
+	ruleunicodeBareKeyChar
 	ruleAction0
 	rulePegText
 	ruleAction1
@@ -125,6 +127,7 @@ const (
 	ruleAction30
 	ruleAction31
 	ruleAction32
+	ruleAction33
 )
 
 var rul3s = [...]string{
@@ -202,6 +205,8 @@ var rul3s = [...]string{
 	"array",
 	"arrayValues",
 	"arraySep",
+	"inlineTableWs",
+	"unicodeBareKeyChar",
 	"Action0",
 	"PegText",
 	"Action1",
@@ -236,6 +241,7 @@ var rul3s = [...]string{
 	"Action30",
 	"Action31",
 	"Action32",
+	"Action33",
 }
 
 type token32 struct {
@@ -352,7 +358,7 @@ type tomlParser struct {
 
 	Buffer string
 	buffer []rune
-	rules  [108]func() bool
+	rules  [111]func() bool
 	parse  func(rule ...int) error
 	reset  func()
 	Pretty bool
@@ -497,7 +503,7 @@ func (p *tomlParser) Execute() {
 		case ruleAction19:
 			p.EndInlineTable()
 		case ruleAction20:
-			p.Error(errInlineTableCommaAtEnd)
+			p.InlineTableTrailingComma()
 		case ruleAction21:
 			p.Error(errInlineTableCommaRequired)
 		case ruleAction22:
@@ -523,6 +529,8 @@ func (p *tomlParser) Execute() {
 			p.AddArrayVal()
 		case ruleAction32:
 			p.AddArrayVal()
+		case ruleAction33:
+			p.InlineTableNewline()
 
 		}
 	}
@@ -1162,7 +1170,7 @@ func (p *tomlParser) Init(options ...func(*tomlParser) error) error {
 									{
 										add(ruleAction18, position)
 									}
-									if !_rules[rulews]() {
+									if !_rules[ruleinlineTableWs]() {
 										goto l40
 									}
 									{
@@ -1175,7 +1183,7 @@ func (p *tomlParser) Init(options ...func(*tomlParser) error) error {
 										l88:
 											{
 												position89, tokenIndex89 := position, tokenIndex
-												if !_rules[rulews]() {
+												if !_rules[ruleinlineTableWs]() {
 													goto l89
 												}
 												{
@@ -1206,7 +1214,7 @@ func (p *tomlParser) Init(options ...func(*tomlParser) error) error {
 												l91:
 													add(ruleinlineTableCommaRequired, position90)
 												}
-												if !_rules[rulews]() {
+												if !_rules[ruleinlineTableWs]() {
 													goto l89
 												}
 												if !_rules[rulekeyval]() {
@@ -1216,7 +1224,7 @@ func (p *tomlParser) Init(options ...func(*tomlParser) error) error {
 											l89:
 												position, tokenIndex = position89, tokenIndex89
 											}
-											if !_rules[rulews]() {
+											if !_rules[ruleinlineTableWs]() {
 												goto l85
 											}
 											{
@@ -1254,7 +1262,7 @@ func (p *tomlParser) Init(options ...func(*tomlParser) error) error {
 										position, tokenIndex = position85, tokenIndex85
 									}
 								l86:
-									if !_rules[rulews]() {
+									if !_rules[ruleinlineTableWs]() {
 										goto l40
 									}
 									if buffer[position] != rune('}') {
@@ -2369,32 +2377,37 @@ func (p *tomlParser) Init(options ...func(*tomlParser) error) error {
 										switch buffer[position] {
 										case '_':
 											if buffer[position] != rune('_') {
-												goto l260
+												goto l407
 											}
 											position++
 										case '-':
 											if buffer[position] != rune('-') {
-												goto l260
+												goto l407
 											}
 											position++
 										case 'a', 'b', 'c', 'd', 'e', 'f', 'g', 'h', 'i', 'j', 'k', 'l', 'm', 'n', 'o', 'p', 'q', 'r', 's', 't', 'u', 'v', 'w', 'x', 'y', 'z':
 											if c := buffer[position]; c < rune('a') || c > rune('z') {
-												goto l260
+												goto l407
 											}
 											position++
 										case '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
 											if c := buffer[position]; c < rune('0') || c > rune('9') {
-												goto l260
+												goto l407
 											}
 											position++
 										default:
 											if c := buffer[position]; c < rune('A') || c > rune('Z') {
-												goto l260
+												goto l407
 											}
 											position++
 										}
 									}
-
+									goto l266
+								l407:
+									position, tokenIndex = position266, tokenIndex266
+									if !_rules[ruleunicodeBareKeyChar]() {
+										goto l260
+									}
 								}
 							l266:
 								add(rulebareKeyChar, position265)
@@ -2416,32 +2429,37 @@ func (p *tomlParser) Init(options ...func(*tomlParser) error) error {
 											switch buffer[position] {
 											case '_':
 												if buffer[position] != rune('_') {
-													goto l264
+													goto l408
 												}
 												position++
 											case '-':
 												if buffer[position] != rune('-') {
-													goto l264
+													goto l408
 												}
 												position++
 											case 'a', 'b', 'c', 'd', 'e', 'f', 'g', 'h', 'i', 'j', 'k', 'l', 'm', 'n', 'o', 'p', 'q', 'r', 's', 't', 'u', 'v', 'w', 'x', 'y', 'z':
 												if c := buffer[position]; c < rune('a') || c > rune('z') {
-													goto l264
+													goto l408
 												}
 												position++
 											case '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
 												if c := buffer[position]; c < rune('0') || c > rune('9') {
-													goto l264
+													goto l408
 												}
 												position++
 											default:
 												if c := buffer[position]; c < rune('A') || c > rune('Z') {
-													goto l264
+													goto l408
 												}
 												position++
 											}
 										}
-
+										goto l270
+									l408:
+										position, tokenIndex = position270, tokenIndex270
+										if !_rules[ruleunicodeBareKeyChar]() {
+											goto l264
+										}
 									}
 								l270:
 									add(rulebareKeyChar, position269)
@@ -2500,7 +2518,7 @@ func (p *tomlParser) Init(options ...func(*tomlParser) error) error {
 		},
 		/* 14 bareKey <- <(<bareKeyChar+> Action15)> */
 		nil,
-		/* 15 bareKeyChar <- <(badControl / ((&('_') '_') | (&('-') '-') | (&('a' | 'b' | 'c' | 'd' | 'e' | 'f' | 'g' | 'h' | 'i' | 'j' | 'k' | 'l' | 'm' | 'n' | 'o' | 'p' | 'q' | 'r' | 's' | 't' | 'u' | 'v' | 'w' | 'x' | 'y' | 'z') [a-z]) | (&('0' | '1' | '2' | '3' | '4' | '5' | '6' | '7' | '8' | '9') [0-9]) | (&('A' | 'B' | 'C' | 'D' | 'E' | 'F' | 'G' | 'H' | 'I' | 'J' | 'K' | 'L' | 'M' | 'N' | 'O' | 'P' | 'Q' | 'R' | 'S' | 'T' | 'U' | 'V' | 'W' | 'X' | 'Y' | 'Z') [A-Z])))> */
+		/* 15 bareKeyChar <- <(badControl / ((&('_') '_') | (&('-') '-') | (&('a' | 'b' | 'c' | 'd' | 'e' | 'f' | 'g' | 'h' | 'i' | 'j' | 'k' | 'l' | 'm' | 'n' | 'o' | 'p' | 'q' | 'r' | 's' | 't' | 'u' | 'v' | 'w' | 'x' | 'y' | 'z') [a-z]) | (&('0' | '1' | '2' | '3' | '4' | '5' | '6' | '7' | '8' | '9') [0-9]) | (&('A' | 'B' | 'C' | 'D' | 'E' | 'F' | 'G' | 'H' | 'I' | 'J' | 'K' | 'L' | 'M' | 'N' | 'O' | 'P' | 'Q' | 'R' | 'S' | 'T' | 'U' | 'V' | 'W' | 'X' | 'Y' | 'Z') [A-Z])) / unicodeBareKeyChar)> */
 		nil,
 		/* 16 quotedKey <- <(<('"' basicChar* '"')> Action16)> */
 		nil,
@@ -2563,9 +2581,9 @@ func (p *tomlParser) Init(options ...func(*tomlParser) error) error {
 		},
 		/* 19 tableKeySep <- <(ws '.' ws)> */
 		nil,
-		/* 20 inlineTable <- <('{' Action18 ws inlineTableKeyValues? ws '}' Action19)> */
+		/* 20 inlineTable <- <('{' Action18 inlineTableWs inlineTableKeyValues? inlineTableWs '}' Action19)> */
 		nil,
-		/* 21 inlineTableKeyValues <- <(keyval (ws inlineTableCommaRequired ws keyval)* ws inlineTableCommaForbidden)> */
+		/* 21 inlineTableKeyValues <- <(keyval (inlineTableWs inlineTableCommaRequired inlineTableWs keyval)* inlineTableWs inlineTableCommaForbidden)> */
 		nil,
 		/* 22 inlineTableCommaForbidden <- <(!',' / (',' Action20))> */
 		nil,
@@ -2575,7 +2593,7 @@ func (p *tomlParser) Init(options ...func(*tomlParser) error) error {
 		nil,
 		/* 25 integer <- <(hexInt / octalInt / binaryInt / decimalInt / (('+' / '-') decimalInt))> */
 		nil,
-		/* 26 decimalInt <- <(([1-9] (decimalDigit / ('_' decimalDigit))+) / decimalDigit)> */
+		/* 26 decimalInt <- <(([1-9] (decimalDigit / ('_' decimalDigit))+) / (decimalDigit (decimalDigit / ('_' decimalDigit))*))> */
 		func() bool {
 			position297, tokenIndex297 := position, tokenIndex
 			{
@@ -2633,6 +2651,30 @@ func (p *tomlParser) Init(options ...func(*tomlParser) error) error {
 					if !_rules[ruledecimalDigit]() {
 						goto l297
 					}
+				l431:
+					{
+						position432, tokenIndex432 := position, tokenIndex
+						{
+							position433, tokenIndex433 := position, tokenIndex
+							if !_rules[ruledecimalDigit]() {
+								goto l434
+							}
+							goto l433
+						l434:
+							position, tokenIndex = position433, tokenIndex433
+							if buffer[position] != rune('_') {
+								goto l432
+							}
+							position++
+							if !_rules[ruledecimalDigit]() {
+								goto l432
+							}
+						}
+					l433:
+						goto l431
+					l432:
+						position, tokenIndex = position432, tokenIndex432
+					}
 				}
 			l299:
 				add(ruledecimalInt, position298)
@@ -2875,7 +2917,7 @@ func (p *tomlParser) Init(options ...func(*tomlParser) error) error {
 			position, tokenIndex = position333, tokenIndex333
 			return false
 		},
-		/* 38 escaped <- <(escape ((&('U') ('U' hexQuad hexQuad)) | (&('u') ('u' hexQuad)) | (&('\\') '\\') | (&('/') '/') | (&('"') '"') | (&('r') 'r') | (&('f') 'f') | (&('n') 'n') | (&('t') 't') | (&('b') 'b')))> */
+		/* 38 escaped <- <(escape ((&('U') ('U' hexQuad hexQuad)) | (&('u') ('u' hexQuad)) | (&('x') ('x' hexDigit hexDigit)) | (&('\\') '\\') | (&('/') '/') | (&('"') '"') | (&('e') 'e') | (&('r') 'r') | (&('f') 'f') | (&('n') 'n') | (&('t') 't') | (&('b') 'b')))> */
 		nil,
 		/* 39 escape <- <'\\'> */
 		func() bool {
@@ -2992,6 +3034,17 @@ func (p *tomlParser) Init(options ...func(*tomlParser) error) error {
 								if !_rules[rulehexQuad]() {
 									goto l352
 								}
+							case 'x':
+								if buffer[position] != rune('x') {
+									goto l352
+								}
+								position++
+								if !_rules[rulehexDigit]() {
+									goto l352
+								}
+								if !_rules[rulehexDigit]() {
+									goto l352
+								}
 							case '\\':
 								if buffer[position] != rune('\\') {
 									goto l352
@@ -3007,6 +3060,11 @@ func (p *tomlParser) Init(options ...func(*tomlParser) error) error {
 									goto l352
 								}
 								position++
+							case 'e':
+								if buffer[position] != rune('e') {
+									goto l352
+								}
+								position++
 							case 'r':
 								if buffer[position] != rune('r') {
 									goto l352
@@ -3070,7 +3128,7 @@ func (p *tomlParser) Init(options ...func(*tomlParser) error) error {
 		nil,
 		/* 55 datetime <- <((fullDate (((&(' ') ' ') | (&('T') 'T') | (&('t') 't')) fullTime)?) / partialTime)> */
 		nil,
-		/* 56 partialTime <- <(timeHour ':' timeMinute ':' timeSecond timeSecfrac?)> */
+		/* 56 partialTime <- <(timeHour ':' timeMinute (':' timeSecond timeSecfrac?)?)> */
 		func() bool {
 			position373, tokenIndex373 := position, tokenIndex
 			{
@@ -3085,45 +3143,52 @@ func (p *tomlParser) Init(options ...func(*tomlParser) error) error {
 				if !_rules[ruletimeMinute]() {
 					goto l373
 				}
-				if buffer[position] != rune(':') {
-					goto l373
-				}
-				position++
 				{
-					position375 := position
-					if !_rules[ruledigitDual]() {
-						goto l373
+					position405, tokenIndex405 := position, tokenIndex
+					if buffer[position] != rune(':') {
+						goto l405
 					}
-					add(ruletimeSecond, position375)
-				}
-				{
-					position376, tokenIndex376 := position, tokenIndex
+					position++
 					{
-						position378 := position
-						if buffer[position] != rune('.') {
-							goto l376
-						}
-						position++
-						if !_rules[ruledecimalDigit]() {
-							goto l376
+						position375 := position
+						if !_rules[ruledigitDual]() {
+							goto l405
 						}
-					l379:
+						add(ruletimeSecond, position375)
+					}
+					{
+						position376, tokenIndex376 := position, tokenIndex
 						{
-							position380, tokenIndex380 := position, tokenIndex
+							position378 := position
+							if buffer[position] != rune('.') {
+								goto l376
+							}
+							position++
 							if !_rules[ruledecimalDigit]() {
-								goto l380
+								goto l376
+							}
+						l379:
+							{
+								position380, tokenIndex380 := position, tokenIndex
+								if !_rules[ruledecimalDigit]() {
+									goto l380
+								}
+								goto l379
+							l380:
+								position, tokenIndex = position380, tokenIndex380
 							}
-							goto l379
-						l380:
-							position, tokenIndex = position380, tokenIndex380
+							add(ruletimeSecfrac, position378)
 						}
-						add(ruletimeSecfrac, position378)
+						goto l377
+					l376:
+						position, tokenIndex = position376, tokenIndex376
 					}
-					goto l377
-				l376:
-					position, tokenIndex = position376, tokenIndex376
+				l377:
+					goto l406
+				l405:
+					position, tokenIndex = position405, tokenIndex405
 				}
-			l377:
+			l406:
 				add(rulepartialTime, position374)
 			}
 			return true
@@ -3219,74 +3284,249 @@ func (p *tomlParser) Init(options ...func(*tomlParser) error) error {
 			position, tokenIndex = position399, tokenIndex399
 			return false
 		},
-		/* 74 Action0 <- <{ _ = buffer }> */
+		/* 73 inlineTableWs <- <((&('\t') '\t') | (&(' ') ' ') | (&('#') comment) | (&('\n' | '\r') (newline Action33)))*> */
+		func() bool {
+			{
+				position402 := position
+			l403:
+				{
+					position404, tokenIndex404 := position, tokenIndex
+					{
+						switch buffer[position] {
+						case '\t':
+							if buffer[position] != rune('\t') {
+								goto l404
+							}
+							position++
+						case ' ':
+							if buffer[position] != rune(' ') {
+								goto l404
+							}
+							position++
+						case '#':
+							if !_rules[rulecomment]() {
+								goto l404
+							}
+						default:
+							if !_rules[rulenewline]() {
+								goto l404
+							}
+							{
+								add(ruleAction33, position)
+							}
+						}
+					}
+
+					goto l403
+				l404:
+					position, tokenIndex = position404, tokenIndex404
+				}
+				add(ruleinlineTableWs, position402)
+			}
+			return true
+		},
+		/* 74 unicodeBareKeyChar <- <('\u00b2' / '\u00b3' / '\u00b9' / [\u00bc-\u00be] / [\u00c0-\u00d6] / [\u00d8-\u00f6] / [\u00f8-\u037d] / [\u037f-\u1fff] / [\u200c-\u200d] / [\u203f-\u2040] / [\u2070-\u218f] / [\u2460-\u24ff] / [\u2c00-\u2fef] / [\u3001-\ud7ff] / [\uf900-\ufdcf] / [\ufdf0-\ufffd] / [\U00010000-\U000effff])> */
+		func() bool {
+			position411, tokenIndex411 := position, tokenIndex
+			{
+				position412 := position
+				{
+					position413, tokenIndex413 := position, tokenIndex
+					if buffer[position] != rune('\u00b2') {
+						goto l414
+					}
+					position++
+					goto l413
+				l414:
+					position, tokenIndex = position413, tokenIndex413
+					if buffer[position] != rune('\u00b3') {
+						goto l415
+					}
+					position++
+					goto l413
+				l415:
+					position, tokenIndex = position413, tokenIndex413
+					if buffer[position] != rune('\u00b9') {
+						goto l416
+					}
+					position++
+					goto l413
+				l416:
+					position, tokenIndex = position413, tokenIndex413
+					if c := buffer[position]; c < rune('\u00bc') || c > rune('\u00be') {
+						goto l417
+					}
+					position++
+					goto l413
+				l417:
+					position, tokenIndex = position413, tokenIndex413
+					if c := buffer[position]; c < rune('\u00c0') || c > rune('\u00d6') {
+						goto l418
+					}
+					position++
+					goto l413
+				l418:
+					position, tokenIndex = position413, tokenIndex413
+					if c := buffer[position]; c < rune('\u00d8') || c > rune('\u00f6') {
+						goto l419
+					}
+					position++
+					goto l413
+				l419:
+					position, tokenIndex = position413, tokenIndex413
+					if c := buffer[position]; c < rune('\u00f8') || c > rune('\u037d') {
+						goto l420
+					}
+					position++
+					goto l413
+				l420:
+					position, tokenIndex = position413, tokenIndex413
+					if c := buffer[position]; c < rune('\u037f') || c > rune('\u1fff') {
+						goto l421
+					}
+					position++
+					goto l413
+				l421:
+					position, tokenIndex = position413, tokenIndex413
+					if c := buffer[position]; c < rune('\u200c') || c > rune('\u200d') {
+						goto l422
+					}
+					position++
+					goto l413
+				l422:
+					position, tokenIndex = position413, tokenIndex413
+					if c := buffer[position]; c < rune('\u203f') || c > rune('\u2040') {
+						goto l423
+					}
+					position++
+					goto l413
+				l423:
+					position, tokenIndex = position413, tokenIndex413
+					if c := buffer[position]; c < rune('\u2070') || c > rune('\u218f') {
+						goto l424
+					}
+					position++
+					goto l413
+				l424:
+					position, tokenIndex = position413, tokenIndex413
+					if c := buffer[position]; c < rune('\u2460') || c > rune('\u24ff') {
+						goto l425
+					}
+					position++
+					goto l413
+				l425:
+					position, tokenIndex = position413, tokenIndex413
+					if c := buffer[position]; c < rune('\u2c00') || c > rune('\u2fef') {
+						goto l426
+					}
+					position++
+					goto l413
+				l426:
+					position, tokenIndex = position413, tokenIndex413
+					if c := buffer[position]; c < rune('\u3001') || c > rune('\ud7ff') {
+						goto l427
+					}
+					position++
+					goto l413
+				l427:
+					position, tokenIndex = position413, tokenIndex413
+					if c := buffer[position]; c < rune('\uf900') || c > rune('\ufdcf') {
+						goto l428
+					}
+					position++
+					goto l413
+				l428:
+					position, tokenIndex = position413, tokenIndex413
+					if c := buffer[position]; c < rune('\ufdf0') || c > rune('\ufffd') {
+						goto l429
+					}
+					position++
+					goto l413
+				l429:
+					position, tokenIndex = position413, tokenIndex413
+					if c := buffer[position]; c < rune('\U00010000') || c > rune('\U000effff') {
+						goto l411
+					}
+					position++
+				}
+			l413:
+				add(ruleunicodeBareKeyChar, position412)
+			}
+			return true
+		l411:
+			position, tokenIndex = position411, tokenIndex411
+			return false
+		},
+		/* 76 Action0 <- <{ _ = buffer }> */
 		nil,
 		nil,
-		/* 76 Action1 <- <{ p.SetTableSource(begin, end) }> */
+		/* 78 Action1 <- <{ p.SetTableSource(begin, end) }> */
 		nil,
-		/* 77 Action2 <- <{ p.SetTime(begin, end) }> */
+		/* 79 Action2 <- <{ p.SetTime(begin, end) }> */
 		nil,
-		/* 78 Action3 <- <{ p.SetFloat(begin, end) }> */
+		/* 80 Action3 <- <{ p.SetFloat(begin, end) }> */
 		nil,
-		/* 79 Action4 <- <{ p.SetInteger(begin, end) }> */
+		/* 81 Action4 <- <{ p.SetInteger(begin, end) }> */
 		nil,
-		/* 80 Action5 <- <{ p.SetString(begin, end) }> */
+		/* 82 Action5 <- <{ p.SetString(begin, end) }> */
 		nil,
-		/* 81 Action6 <- <{ p.SetBool(begin, end) }> */
+		/* 83 Action6 <- <{ p.SetBool(begin, end) }> */
 		nil,
-		/* 82 Action7 <- <{ p.SetArray(begin, end) }> */
+		/* 84 Action7 <- <{ p.SetArray(begin, end) }> */
 		nil,
-		/* 83 Action8 <- <{ p.SetInlineTableSource(begin, end) }> */
+		/* 85 Action8 <- <{ p.SetInlineTableSource(begin, end) }> */
 		nil,
-		/* 84 Action9 <- <{ p.Newline() }> */
+		/* 86 Action9 <- <{ p.Newline() }> */
 		nil,
-		/* 85 Action10 <- <{ p.Error(errNewlineRequired) }> */
+		/* 87 Action10 <- <{ p.Error(errNewlineRequired) }> */
 		nil,
-		/* 86 Action11 <- <{
+		/* 88 Action11 <- <{
 		    p.Error(&rawControlError{p.buffer[begin]})
 		}> */
 		nil,
-		/* 87 Action12 <- <{ p.SetTable(p.buffer, begin, end) }> */
+		/* 89 Action12 <- <{ p.SetTable(p.buffer, begin, end) }> */
+		nil,
+		/* 90 Action13 <- <{ p.SetArrayTable(p.buffer, begin, end) }> */
 		nil,
-		/* 88 Action13 <- <{ p.SetArrayTable(p.buffer, begin, end) }> */
+		/* 91 Action14 <- <{ p.AddKeyValue() }> */
 		nil,
-		/* 89 Action14 <- <{ p.AddKeyValue() }> */
+		/* 92 Action15 <- <{ p.SetKey(p.buffer, begin, end) }> */
 		nil,
-		/* 90 Action15 <- <{ p.SetKey(p.buffer, begin, end) }> */
+		/* 93 Action16 <- <{ p.SetKey(p.buffer, begin, end) }> */
 		nil,
-		/* 91 Action16 <- <{ p.SetKey(p.buffer, begin, end) }> */
+		/* 94 Action17 <- <{ p.AddTableKey() }> */
 		nil,
-		/* 92 Action17 <- <{ p.AddTableKey() }> */
+		/* 95 Action18 <- <{ p.StartInlineTable() }> */
 		nil,
-		/* 93 Action18 <- <{ p.StartInlineTable() }> */
+		/* 96 Action19 <- <{ p.EndInlineTable() }> */
 		nil,
-		/* 94 Action19 <- <{ p.EndInlineTable() }> */
+		/* 97 Action20 <- <{ p.InlineTableTrailingComma() }> */
 		nil,
-		/* 95 Action20 <- <{ p.Error(errInlineTableCommaAtEnd) }> */
+		/* 98 Action21 <- <{ p.Error(errInlineTableCommaRequired) }> */
 		nil,
-		/* 96 Action21 <- <{ p.Error(errInlineTableCommaRequired) }> */
+		/* 99 Action22 <- <{ p.SetBasicString(p.buffer, begin, end) }> */
 		nil,
-		/* 97 Action22 <- <{ p.SetBasicString(p.buffer, begin, end) }> */
+		/* 100 Action23 <- <{ p.SetMultilineBasicString() }> */
 		nil,
-		/* 98 Action23 <- <{ p.SetMultilineBasicString() }> */
+		/* 101 Action24 <- <{ p.AddMultilineBasicQuote() }> */
 		nil,
-		/* 99 Action24 <- <{ p.AddMultilineBasicQuote() }> */
+		/* 102 Action25 <- <{ p.AddMultilineBasicBody(p.buffer, begin, end) }> */
 		nil,
-		/* 100 Action25 <- <{ p.AddMultilineBasicBody(p.buffer, begin, end) }> */
+		/* 103 Action26 <- <{ p.AddMultilineBasicQuote(); p.AddMultilineBasicQuote() }> */
 		nil,
-		/* 101 Action26 <- <{ p.AddMultilineBasicQuote(); p.AddMultilineBasicQuote() }> */
+		/* 104 Action27 <- <{ p.AddMultilineBasicQuote() }> */
 		nil,
-		/* 102 Action27 <- <{ p.AddMultilineBasicQuote() }> */
+		/* 105 Action28 <- <{ p.SetLiteralString(p.buffer, begin, end) }> */
 		nil,
-		/* 103 Action28 <- <{ p.SetLiteralString(p.buffer, begin, end) }> */
+		/* 106 Action29 <- <{ p.SetMultilineLiteralString(p.buffer, begin, end) }> */
 		nil,
-		/* 104 Action29 <- <{ p.SetMultilineLiteralString(p.buffer, begin, end) }> */
+		/* 107 Action30 <- <{ p.StartArray() }> */
 		nil,
-		/* 105 Action30 <- <{ p.StartArray() }> */
+		/* 108 Action31 <- <{ p.AddArrayVal() }> */
 		nil,
-		/* 106 Action31 <- <{ p.AddArrayVal() }> */
+		/* 109 Action32 <- <{ p.AddArrayVal() }> */
 		nil,
-		/* 107 Action32 <- <{ p.AddArrayVal() }> */
+		/* 110 Action33 <- <{ p.InlineTableNewline() }> */
 		nil,
 	}
 	p.rules = _rules
@@ -0,0 +1,154 @@
+package toml
+
+import (
+	"sort"
+	"strconv"
+
+	"github.com/naoina/toml/ast"
+)
+
+// Position is the location of a value in a TOML document.
+type Position struct {
+	Line   int // 1-based line number
+	Column int // 1-based column in characters, zero if the source is not available
+}
+
+// Positions maps key paths to the location of their values in a TOML document. Paths
+// consist of keys in canonical form (see Canonicalize) separated by dots. Elements of
+// arrays are selected by their index, e.g.
+//
+//	servers[1].port
+//
+// The position of a table is the position of its header. Implicitly created tables
+// have no entry.
+type Positions map[string]Position
+
+// KeyPositions returns the positions of all keys in document t.
+func KeyPositions(t *ast.Table) Positions {
+	p := make(Positions)
+	p.record(t)
+	return p
+}
+
+// UnmarshalPositions is like Unmarshal, but also returns the positions of all keys in
+// data. This allows reporting problems found while validating the decoded value at the
+// right location:
+//
+//	if cfg.Server.Port > 65535 {
+//		return pos.Error("server.port", errors.New("port out of range"))
+//	}
+func (cfg *Config) UnmarshalPositions(data []byte, v interface{}) (Positions, error) {
+	table, err := cfg.parseBytes(data)
+	if err != nil {
+		return nil, err
+	}
+	return KeyPositions(table), cfg.UnmarshalTable(table, v)
+}
+
+// Error returns err as a *LineError for the position of the value at path. It returns
+// err unchanged if path has no position.
+func (p Positions) Error(path string, err error) error {
+	pos, ok := p[path]
+	if !ok || err == nil {
+		return err
+	}
+	if _, ok := err.(*LineError); ok {
+		return err
+	}
+	return &LineError{Line: pos.Line, Column: pos.Column, Path: path, Err: err}
+}
+
+// positionRecorder adds the positions of keys in a document to a Positions map.
+type positionRecorder struct {
+	p          Positions
+	lineStarts []int // offsets of all lines in the source
+}
+
+func (p Positions) record(t *ast.Table) {
+	r := &positionRecorder{p: p, lineStarts: lineStarts(t.Data)}
+	r.field("", t)
+}
+
+// lineStarts returns the offsets of all lines in data, or nil if data is nil.
+func lineStarts(data []rune) []int {
+	if data == nil {
+		return nil
+	}
+	starts := []int{0}
+	for i, c := range data {
+		if c == '\n' {
+			starts = append(starts, i+1)
+		}
+	}
+	return starts
+}
+
+// docSource computes the positions of offsets in the source of a document for decode
+// errors. The line offsets are computed on first use.
+type docSource struct {
+	data []rune
+	r    *positionRecorder
+}
+
+// position is like positionRecorder.position, but returns no column for offsets which
+// aren't on the given line.
+func (s *docSource) position(line, pos int) Position {
+	if s == nil || pos < 0 {
+		return Position{Line: line}
+	}
+	if s.r == nil {
+		s.r = &positionRecorder{lineStarts: lineStarts(s.data)}
+	}
+	if p := s.r.position(line, pos); p.Line == line {
+		return p
+	}
+	return Position{Line: line}
+}
+
+// position returns the position of offset pos. The line is used if the source is not
+// available.
+func (r *positionRecorder) position(line, pos int) Position {
+	if r.lineStarts == nil {
+		return Position{Line: line}
+	}
+	i := sort.Search(len(r.lineStarts), func(i int) bool { return r.lineStarts[i] > pos })
+	return Position{i, pos - r.lineStarts[i-1] + 1}
+}
+
+func (r *positionRecorder) field(path string, field interface{}) {
+	switch f := field.(type) {
+	case *ast.Table:
+		if path != "" && f.Position != (ast.Position{}) {
+			r.p[path] = r.position(f.Line, f.Position.Begin)
+		}
+		r.fields(path, f)
+	case []*ast.Table:
+		for i, t := range f {
+			r.field(path+"["+strconv.Itoa(i)+"]", t)
+		}
+	case *ast.KeyValue:
+		r.value(path, f.Line, f.Value)
+	}
+}
+
+func (r *positionRecorder) fields(path string, t *ast.Table) {
+	for key, child := range t.Fields {
+		if path == "" {
+			r.field(canonicalKey(key), child)
+		} else {
+			r.field(path+"."+canonicalKey(key), child)
+		}
+	}
+}
+
+func (r *positionRecorder) value(path string, line int, v ast.Value) {
+	r.p[path] = r.position(line, v.Pos())
+	switch v := v.(type) {
+	case *ast.Table:
+		r.fields(path, v)
+	case *ast.Array:
+		for i, elem := range v.Value {
+			r.value(path+"["+strconv.Itoa(i)+"]", line, elem)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package toml
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestUnmarshalPositions(t *testing.T) {
+	data := []byte(`name = "app"
+[server]
+  port = 99999
+  "bind addr" = { host = "localhost" }
+[[users]]
+name = "a"
+[[users]]
+name = "b"
+tags = [
+  "x",
+  "y",
+]
+`)
+	var v struct {
+		Name   string
+		Server struct {
+			Port     int
+			BindAddr struct{ Host string } `toml:"bind addr"`
+		}
+		Users []struct {
+			Name string
+			Tags []string
+		}
+	}
+	pos, err := UnmarshalPositions(data, &v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Positions{
+		"name":                    {1, 8},
+		"server":                  {2, 1},
+		"server.port":             {3, 10},
+		`server."bind addr"`:      {4, 17},
+		`server."bind addr".host`: {4, 26},
+		"users[0]":                {5, 1},
+		"users[0].name":           {6, 8},
+		"users[1]":                {7, 1},
+		"users[1].name":           {8, 8},
+		"users[1].tags":           {9, 8},
+		"users[1].tags[0]":        {10, 3},
+		"users[1].tags[1]":        {11, 3},
+	}
+	if !reflect.DeepEqual(pos, want) {
+		t.Errorf("got %v\nwant %v", pos, want)
+	}
+
+	err = pos.Error("server.port", errors.New("port out of range"))
+	if err.Error() != "line 3: server.port: port out of range" {
+		t.Errorf("wrong error: %v", err)
+	}
+
+	dpos := make(Positions)
+	dec := NewDecoder(bytes.NewReader(data))
+	dec.RecordPositions(dpos)
+	if err := dec.Decode(&v); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(dpos, want) {
+		t.Errorf("Decoder: got %v\nwant %v", dpos, want)
+	}
+}
@@ -0,0 +1,59 @@
+package toml
+
+import (
+	"reflect"
+)
+
+// Primitive holds a TOML value whose decoding is deferred. When a struct field or map
+// element of type Primitive is decoded, the value is captured as is and can be decoded
+// later with PrimitiveDecode, e.g. once the concrete type is known from another key:
+//
+//	var plugin struct {
+//		Type   string
+//		Config toml.Primitive
+//	}
+//	toml.Unmarshal(data, &plugin)
+//	cfg := newPluginConfig(plugin.Type)
+//	err := toml.PrimitiveDecode(plugin.Config, cfg)
+type Primitive struct {
+	cfg  *Config
+	av   interface{} // *ast.KeyValue, *ast.Table or []*ast.Table
+	path *keyPath
+}
+
+var primitiveType = reflect.TypeOf(Primitive{})
+
+// PrimitiveDecode decodes the value held by p into the value pointed to by v, using the
+// Config which captured it. It does nothing if p is the zero Primitive, e.g. because the
+// key wasn't defined. Errors have the location of the value in the original document.
+func PrimitiveDecode(p Primitive, v interface{}) error {
+	if p.av == nil {
+		return nil
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return &invalidUnmarshalError{reflect.TypeOf(v)}
+	}
+	cfg := p.cfg
+	if cfg.CollectUnknownFields || cfg.ContinueOnError {
+		c := *cfg
+		c.errs = newErrorList(cfg)
+		cfg = &c
+	}
+	err := cfg.valueError(p.path, p.av, "", unmarshalField(cfg, rv.Elem(), p.av, p.path))
+	if cfg.errs != nil {
+		return cfg.errs.finish(err)
+	}
+	return err
+}
+
+// setPrimitive stores fieldAst in rv if it is a Primitive or a pointer to one.
+func setPrimitive(cfg *Config, rv reflect.Value, fieldAst interface{}, path *keyPath) bool {
+	if typ := rv.Type(); typ != primitiveType && (typ.Kind() != reflect.Ptr || typ.Elem() != primitiveType) {
+		return false
+	}
+	c := *cfg
+	c.errs = nil // errors of PrimitiveDecode are reported there
+	indirect(rv).Set(reflect.ValueOf(Primitive{cfg: &c, av: fieldAst, path: path}))
+	return true
+}
@@ -0,0 +1,199 @@
+package toml
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/naoina/toml/ast"
+)
+
+// Query returns the values selected by a path expression in document t. The syntax
+// is a subset of jq:
+//
+//	.                  the document itself
+//	.servers           the value of key "servers"
+//	."key.with.dots"   keys which aren't bare keys must be quoted
+//	.servers[0]        an element of an array or array table; negative indexes count
+//	                   from the end
+//	.servers[]         all elements of an array or array table, or all values of a table
+//	.servers[].ip      steps after [] apply to every element
+//
+// Tables are returned as *ast.Table and array tables as *ast.Array containing
+// *ast.Table elements. A missing key or index yields no result, selecting a key of
+// a value which isn't a table is an error.
+func Query(t *ast.Table, path string) ([]ast.Value, error) {
+	steps, err := parseQuery(path)
+	if err != nil {
+		return nil, err
+	}
+	results := []ast.Value{t}
+	for _, step := range steps {
+		var next []ast.Value
+		for _, v := range results {
+			if next, err = step.apply(v, next); err != nil {
+				return nil, fmt.Errorf("toml: query %s: %v", path, err)
+			}
+		}
+		results = next
+	}
+	return results, nil
+}
+
+type queryStep struct {
+	key     string
+	index   int
+	isKey   bool
+	iterate bool
+}
+
+func (s queryStep) apply(v ast.Value, results []ast.Value) ([]ast.Value, error) {
+	switch {
+	case s.isKey:
+		t, ok := v.(*ast.Table)
+		if !ok {
+			return nil, fmt.Errorf("cannot select key `%s' of %s", s.key, queryType(v))
+		}
+		if field, ok := t.Fields[s.key]; ok {
+			results = append(results, fieldValue(field))
+		}
+		return results, nil
+	case s.iterate:
+		switch v := v.(type) {
+		case *ast.Array:
+			return append(results, v.Value...), nil
+		case *ast.Table:
+			for _, key := range sortedKeys(v) {
+				results = append(results, fieldValue(v.Fields[key]))
+			}
+			return results, nil
+		}
+		return nil, fmt.Errorf("cannot iterate over %s", queryType(v))
+	default:
+		a, ok := v.(*ast.Array)
+		if !ok {
+			return nil, fmt.Errorf("cannot index %s", queryType(v))
+		}
+		i := s.index
+		if i < 0 {
+			i += len(a.Value)
+		}
+		if i >= 0 && i < len(a.Value) {
+			results = append(results, a.Value[i])
+		}
+		return results, nil
+	}
+}
+
+// fieldValue returns a table field as a value.
+func fieldValue(field interface{}) ast.Value {
+	switch f := field.(type) {
+	case *ast.KeyValue:
+		return f.Value
+	case *ast.Table:
+		return f
+	case []*ast.Table:
+		a := &ast.Array{Value: make([]ast.Value, len(f))}
+		for i, t := range f {
+			a.Value[i] = t
+		}
+		return a
+	default:
+		panic(fmt.Sprintf("BUG: unhandled node type %T", field))
+	}
+}
+
+func queryType(v ast.Value) string {
+	switch v.(type) {
+	case *ast.String:
+		return "string"
+	case *ast.Integer:
+		return "integer"
+	case *ast.Float:
+		return "float"
+	case *ast.Boolean:
+		return "boolean"
+	case *ast.Datetime:
+		return "datetime"
+	case *ast.Array:
+		return "array"
+	default:
+		return "table"
+	}
+}
+
+func parseQuery(path string) ([]queryStep, error) {
+	rest := strings.TrimSpace(path)
+	if !strings.HasPrefix(rest, ".") {
+		return nil, fmt.Errorf("toml: invalid query %q: must start with '.'", path)
+	}
+	var steps []queryStep
+	for rest != "" {
+		switch rest[0] {
+		case '.':
+			rest = rest[1:]
+			if rest == "" || rest[0] == '[' {
+				continue
+			}
+			var key string
+			var err error
+			if key, rest, err = parseQueryKey(rest); err != nil {
+				return nil, fmt.Errorf("toml: invalid query %q: %v", path, err)
+			}
+			steps = append(steps, queryStep{key: key, isKey: true})
+		case '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("toml: invalid query %q: missing ']'", path)
+			}
+			if index := strings.TrimSpace(rest[1:end]); index == "" {
+				steps = append(steps, queryStep{iterate: true})
+			} else {
+				i, err := strconv.Atoi(index)
+				if err != nil {
+					return nil, fmt.Errorf("toml: invalid query %q: invalid index %q", path, index)
+				}
+				steps = append(steps, queryStep{index: i})
+			}
+			rest = rest[end+1:]
+		default:
+			return nil, fmt.Errorf("toml: invalid query %q: unexpected %q", path, rest[0])
+		}
+	}
+	return steps, nil
+}
+
+// parseQueryKey parses a bare or quoted key at the start of s.
+func parseQueryKey(s string) (key, rest string, err error) {
+	switch s[0] {
+	case '"':
+		end := 1
+		for end < len(s) && s[end] != '"' {
+			if s[end] == '\\' {
+				end++
+			}
+			end++
+		}
+		if end >= len(s) {
+			return "", "", fmt.Errorf("unterminated key %s", s)
+		}
+		if key, err = strconv.Unquote(s[:end+1]); err != nil {
+			return "", "", fmt.Errorf("invalid key %s", s[:end+1])
+		}
+		return key, s[end+1:], nil
+	case '\'':
+		end := strings.IndexByte(s[1:], '\'')
+		if end < 0 {
+			return "", "", fmt.Errorf("unterminated key %s", s)
+		}
+		return s[1 : end+1], s[end+2:], nil
+	}
+	end := strings.IndexAny(s, ".[")
+	if end < 0 {
+		end = len(s)
+	}
+	if key = s[:end]; canonicalKey(key) != key {
+		return "", "", fmt.Errorf("invalid key %q", key)
+	}
+	return key, s[end:], nil
+}
@@ -0,0 +1,65 @@
+package toml
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestQuery(t *testing.T) {
+	doc, err := Parse([]byte(`
+name = "x"
+ports = [1, 2, 3]
+"a.b" = true
+
+[[servers]]
+ip = "10.0.0.1"
+[[servers]]
+ip = "10.0.0.2"
+tags = { role = "db" }
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		query string
+		want  string // results in canonical inline notation, one per line
+	}{
+		{".name", "\"x\"\n"},
+		{".ports[1]", "2\n"},
+		{".ports[-1]", "3\n"},
+		{".ports[5]", ""},
+		{".ports[]", "1\n2\n3\n"},
+		{`."a.b"`, "true\n"},
+		{".missing", ""},
+		{".servers[].ip", "\"10.0.0.1\"\n\"10.0.0.2\"\n"},
+		{".servers[1].tags.role", "\"db\"\n"},
+		{".servers[0]", "{ ip = \"10.0.0.1\" }\n"},
+		{".servers[].tags", "{ role = \"db\" }\n"},
+	}
+	for _, test := range tests {
+		results, err := Query(doc, test.query)
+		if err != nil {
+			t.Errorf("Query(%s): %v", test.query, err)
+			continue
+		}
+		var buf bytes.Buffer
+		for _, v := range results {
+			if err := canonicalInline(&buf, v); err != nil {
+				t.Fatal(err)
+			}
+			buf.WriteByte('\n')
+		}
+		if buf.String() != test.want {
+			t.Errorf("Query(%s) = %q, want %q", test.query, buf.String(), test.want)
+		}
+	}
+
+	if results, err := Query(doc, "."); err != nil || len(results) != 1 || results[0] != doc {
+		t.Errorf("Query(.) = %v, %v", results, err)
+	}
+	for _, query := range []string{"name", ".name[0]", ".name.x", ".ports[x]", ".ports[", `."a`, ".a b"} {
+		if _, err := Query(doc, query); err == nil {
+			t.Errorf("Query(%s): expected error", query)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package toml
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+
+	"github.com/naoina/toml/ast"
+)
+
+// RawMessage is a raw TOML value. It can be used to pass a part of a document through
+// decoding and encoding unchanged, e.g. the configuration of a plugin.
+//
+// Values other than tables are stored as their source text, e.g. [1, 2]. Tables are
+// stored as a document containing the keys of the table, so the message can be decoded
+// with Unmarshal. The lines of a table are copied verbatim, comments included, unless
+// the table has sub-tables or the source text isn't available. The canonical form (see
+// Canonicalize) is stored in these cases.
+//
+// When encoding, a RawMessage holding a value is written as is. Documents are written as
+// a table, verbatim if they contain only key/value pairs.
+type RawMessage []byte
+
+var rawMessageType = reflect.TypeOf(RawMessage(nil))
+
+// UnmarshalTOML implements Unmarshaler.
+func (m *RawMessage) UnmarshalTOML(data []byte) error {
+	*m = append((*m)[:0], data...)
+	return nil
+}
+
+// setRawMessage stores the tables in fieldAst as documents if rv is a RawMessage or a
+// pointer to one. Other values are handled by UnmarshalTOML.
+func setRawMessage(cfg *Config, rv reflect.Value, fieldAst interface{}) (bool, error) {
+	if typ := rv.Type(); typ != rawMessageType && (typ.Kind() != reflect.Ptr || typ.Elem() != rawMessageType) {
+		return false, nil
+	}
+	var t *ast.Table
+	switch av := fieldAst.(type) {
+	case *ast.Table:
+		t = av
+	case *ast.KeyValue:
+		if t, _ = av.Value.(*ast.Table); t == nil {
+			return false, nil
+		}
+	case []*ast.Table:
+		return true, &UnmarshalTypeError{"array table", "slice", rv.Type()}
+	}
+	raw, err := rawTable(cfg, t)
+	if err != nil {
+		return true, err
+	}
+	indirect(rv).SetBytes(raw)
+	return true, nil
+}
+
+// rawTable returns the content of t as a document.
+func rawTable(cfg *Config, t *ast.Table) ([]byte, error) {
+	if cfg.source == nil || cfg.source.data == nil || t.Type != ast.TableTypeNormal || t.Position == (ast.Position{}) {
+		return CanonicalizeTable(t)
+	}
+	src := cfg.source.data
+	// The body starts below the header and ends with the line of the last value.
+	start := t.Position.Begin
+	for start < len(src) && src[start] != '\n' {
+		start++
+	}
+	end := start
+	for _, f := range t.Fields {
+		kv, ok := f.(*ast.KeyValue)
+		if !ok {
+			return CanonicalizeTable(t)
+		}
+		if kv.Value.End() > end {
+			end = kv.Value.End()
+		}
+	}
+	if end == start {
+		return nil, nil
+	}
+	for end < len(src) && src[end] != '\n' {
+		end++
+	}
+	if end < len(src) {
+		end++
+	}
+	return []byte(string(src[start+1 : end])), nil
+}
+
+// rawMessage writes a RawMessage.
+func (b *tableBuf) rawMessage(cfg *Config, raw []byte, name string) ([]*tableBuf, error) {
+	if t, err := Parse(append([]byte("v = "), raw...)); err == nil && len(t.Fields) == 1 {
+		b.body = append(b.body, bytes.TrimSpace(raw)...)
+		return nil, nil
+	}
+	t, err := Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("toml: invalid RawMessage: %v", err)
+	}
+	child := b.newChild(cfg, name)
+	var tables []*tableBuf
+	if child.typ != ast.TableTypeInline && !hasSubTables(t) {
+		child.body = append(child.body, raw...)
+		if len(raw) > 0 && raw[len(raw)-1] != '\n' {
+			child.body = append(child.body, '\n')
+		}
+	} else {
+		var kvs []KeyValue
+		if err := cfg.UnmarshalTable(t, &kvs); err != nil {
+			return nil, err
+		}
+		tables, err = child.keyValueFields(cfg, reflect.ValueOf(kvs))
+	}
+	b.addChild(cfg, child)
+	if child.typ == ast.TableTypeInline {
+		return nil, err
+	}
+	return append(tables, child), err
+}
+
+// hasSubTables reports whether t contains tables or array tables which aren't inline.
+func hasSubTables(t *ast.Table) bool {
+	for _, f := range t.Fields {
+		if _, ok := f.(*ast.KeyValue); !ok {
+			return true
+		}
+	}
+	return false
+}
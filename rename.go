@@ -0,0 +1,144 @@
+package toml
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/naoina/toml/ast"
+)
+
+// renamedKeyError is the warning for a key found at the old path of Config.RenamedKeys.
+type renamedKeyError struct {
+	old, to string
+}
+
+func (err *renamedKeyError) Error() string {
+	return fmt.Sprintf("key `%s' is deprecated, use `%s' instead", err.old, err.to)
+}
+
+// renameKeys returns t with the keys of cfg.RenamedKeys moved to their new paths. Tables
+// along the paths are copied, the original AST is never modified.
+func renameKeys(cfg *Config, t *ast.Table) (*ast.Table, error) {
+	olds := make([]string, 0, len(cfg.RenamedKeys))
+	for old := range cfg.RenamedKeys {
+		olds = append(olds, old)
+	}
+	sort.Strings(olds)
+	r := &renamer{copied: make(map[*ast.Table]bool)}
+	root := r.clone(t)
+	for _, old := range olds {
+		to := cfg.RenamedKeys[old]
+		oldPath, newPath := strings.Split(old, "."), strings.Split(to, ".")
+		parent := r.table(root, oldPath[:len(oldPath)-1], false)
+		if parent == nil {
+			continue
+		}
+		key := oldPath[len(oldPath)-1]
+		field, ok := parent.Fields[key]
+		if !ok {
+			continue
+		}
+		line := fieldLineNumber(field)
+		dst := r.table(root, newPath[:len(newPath)-1], true)
+		newKey := newPath[len(newPath)-1]
+		if dst == nil {
+			return nil, lineError(line, fmt.Errorf("key `%s' cannot be renamed to `%s' because `%s' is not a table", old, to, strings.Join(newPath[:len(newPath)-1], ".")))
+		}
+		if other, ok := dst.Fields[newKey]; ok {
+			return nil, lineError(line, fmt.Errorf("key `%s' is in conflict with `%s' in line %d, which replaces it", old, to, fieldLineNumber(other)))
+		}
+		delete(parent.Fields, key)
+		dst.Fields[newKey] = renamedField(field, newKey)
+		r.removeEmpty(root, oldPath[:len(oldPath)-1])
+		if cfg.OnRenamedKey != nil {
+			cfg.OnRenamedKey(old, to, line)
+		}
+		if cfg.Warning != nil {
+			var path *keyPath
+			for _, k := range oldPath {
+				path = path.child(k)
+			}
+			cfg.Warning(&LineError{Line: line, Path: path.String(), Err: &renamedKeyError{old, to}})
+		}
+	}
+	return root, nil
+}
+
+// renamer tracks the tables copied by renameKeys.
+type renamer struct {
+	copied map[*ast.Table]bool
+}
+
+// clone returns a copy of t which may be modified.
+func (r *renamer) clone(t *ast.Table) *ast.Table {
+	if r.copied[t] {
+		return t
+	}
+	nt := *t
+	nt.Fields = make(map[string]interface{}, len(t.Fields))
+	for k, v := range t.Fields {
+		nt.Fields[k] = v
+	}
+	r.copied[&nt] = true
+	return &nt
+}
+
+// table returns a modifiable copy of the table at path below t. Missing tables are
+// created if create is true. It returns nil if the path doesn't lead to a table, e.g.
+// because it goes through an array table or inline table.
+func (r *renamer) table(t *ast.Table, path []string, create bool) *ast.Table {
+	for _, key := range path {
+		switch child := t.Fields[key].(type) {
+		case *ast.Table:
+			t.Fields[key] = r.clone(child)
+		case nil:
+			if !create {
+				return nil
+			}
+			nt := &ast.Table{Name: key, Type: ast.TableTypeNormal, Fields: make(map[string]interface{})}
+			r.copied[nt] = true
+			t.Fields[key] = nt
+		default:
+			return nil
+		}
+		t = t.Fields[key].(*ast.Table)
+	}
+	return t
+}
+
+// removeEmpty deletes the tables along path below t which are empty after a key was
+// renamed, starting with the innermost one. The tables have already been copied.
+func (r *renamer) removeEmpty(t *ast.Table, path []string) {
+	if len(path) == 0 {
+		return
+	}
+	child := t.Fields[path[0]].(*ast.Table)
+	r.removeEmpty(child, path[1:])
+	if len(child.Fields) == 0 {
+		delete(t.Fields, path[0])
+	}
+}
+
+// renamedField returns a copy of table field f with the given key.
+func renamedField(f interface{}, key string) interface{} {
+	switch f := f.(type) {
+	case *ast.KeyValue:
+		kv := *f
+		kv.Key = key
+		return &kv
+	case *ast.Table:
+		t := *f
+		t.Name = key
+		return &t
+	case []*ast.Table:
+		tables := make([]*ast.Table, len(f))
+		for i, t := range f {
+			nt := *t
+			nt.Name = key
+			tables[i] = &nt
+		}
+		return tables
+	}
+	return f
+}
@@ -0,0 +1,343 @@
+package toml
+
+import (
+	"bytes"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/naoina/toml/ast"
+)
+
+// KeyOrder sorts the keys of the table at path in place. It is used by Document.Sort.
+type KeyOrder func(path []string, keys []string)
+
+// AlphabeticalOrder sorts keys in lexical order.
+func AlphabeticalOrder(path []string, keys []string) {
+	sort.Strings(keys)
+}
+
+// StructOrder returns a KeyOrder which sorts keys in the order Marshal would write the
+// fields of v. Keys are matched to struct fields like Unmarshal does with DefaultConfig.
+// Keys which don't belong to a struct field follow in lexical order.
+func StructOrder(v interface{}) KeyOrder {
+	return DefaultConfig.StructOrder(v)
+}
+
+// StructOrder is like the package-level StructOrder, but matches keys to struct fields
+// and orders them like cfg does.
+func (cfg *Config) StructOrder(v interface{}) KeyOrder {
+	root := reflect.TypeOf(v)
+	return func(path []string, keys []string) {
+		rt := root
+		for _, key := range path {
+			if rt = cfg.structKeyType(rt, key); rt == nil {
+				break
+			}
+		}
+		var rank map[string]int
+		if rt = indirectType(rt); rt != nil && rt.Kind() == reflect.Struct {
+			rank = cfg.structKeyRank(rt, keys)
+		}
+		sortByRank(keys, rank)
+	}
+}
+
+// DocumentOrder returns a KeyOrder which sorts keys in the order they appear in
+// document t. Keys which don't appear in t follow in lexical order.
+func DocumentOrder(t *ast.Table) KeyOrder {
+	return func(path []string, keys []string) {
+		ref := t
+		for _, key := range path {
+			if array, ok := ref.Fields[key].([]*ast.Table); ok {
+				ref = array[0]
+			} else if ref = mergeableTable(ref.Fields[key]); ref == nil {
+				break
+			}
+		}
+		var rank map[string]int
+		if ref != nil {
+			rank = make(map[string]int, len(ref.Fields))
+			for key, field := range ref.Fields {
+				rank[key] = fieldPos(field)
+			}
+		}
+		sortByRank(keys, rank)
+	}
+}
+
+func sortByRank(keys []string, rank map[string]int) {
+	sort.Slice(keys, func(i, j int) bool {
+		ri, iok := rank[keys[i]]
+		rj, jok := rank[keys[j]]
+		switch {
+		case iok && jok && ri != rj:
+			return ri < rj
+		case iok != jok:
+			return iok
+		default:
+			return keys[i] < keys[j]
+		}
+	})
+}
+
+func indirectType(rt reflect.Type) reflect.Type {
+	for rt != nil && rt.Kind() == reflect.Ptr {
+		rt = rt.Elem()
+	}
+	return rt
+}
+
+// structKeyType returns the type of the value stored under key in a value of type rt.
+func (cfg *Config) structKeyType(rt reflect.Type, key string) reflect.Type {
+	rt = indirectType(rt)
+	if rt == nil {
+		return nil
+	}
+	switch rt.Kind() {
+	case reflect.Map:
+		return rt.Elem()
+	case reflect.Struct:
+		for i := 0; i < rt.NumField(); i++ {
+			if cfg.structFieldKey(rt, rt.Field(i), key) {
+				rt = indirectType(rt.Field(i).Type)
+				if rt.Kind() == reflect.Slice || rt.Kind() == reflect.Array {
+					rt = rt.Elem()
+				}
+				return rt
+			}
+		}
+	}
+	return nil
+}
+
+// structKeyRank returns the position of all keys matching a field of struct type rt.
+func (cfg *Config) structKeyRank(rt reflect.Type, keys []string) map[string]int {
+	order, err := fieldOrder(cfg, rt)
+	if err != nil {
+		order = nil
+	}
+	rank := make(map[string]int)
+	for pos, i := range order {
+		for _, key := range keys {
+			if _, ok := rank[key]; !ok && cfg.structFieldKey(rt, rt.Field(i), key) {
+				rank[key] = pos
+			}
+		}
+	}
+	return rank
+}
+
+// structFieldKey reports whether key is decoded into field ft of struct type rt.
+func (cfg *Config) structFieldKey(rt reflect.Type, ft reflect.StructField, key string) bool {
+	if ft.PkgPath != "" && !ft.Anonymous {
+		return false
+	}
+	name, _ := extractTag(ft.Tag.Get(cfg.tagName()))
+	switch name {
+	case tagSkip:
+		return false
+	case "":
+		return cfg.fieldKey(rt, ft.Name) == cfg.inputKey(rt, key)
+	default:
+		return name == key
+	}
+}
+
+// fieldPos returns the source position of a table field. Implicitly created tables
+// take the position of their first child.
+func fieldPos(field interface{}) int {
+	switch f := field.(type) {
+	case *ast.KeyValue:
+		return f.Value.Pos()
+	case []*ast.Table:
+		return f[0].Position.Begin
+	case *ast.Table:
+		if f.Position != (ast.Position{}) {
+			return f.Position.Begin
+		}
+		pos := -1
+		for _, child := range f.Fields {
+			if p := fieldPos(child); pos < 0 || p < pos {
+				pos = p
+			}
+		}
+		return pos
+	}
+	return -1
+}
+
+// Sort rewrites the document with the keys of all tables sorted by order. Key/value
+// pairs precede the sub-tables of their table. The elements of arrays and array tables
+// keep their order.
+//
+// Sort also normalizes spacing: indentation is removed, keys are separated from their
+// values by " = ", and tables are separated by a single blank line. Comments on their
+// own lines move with the key or table that follows them; comments at the end of a line
+// stay on that line. A comment block at the top of the document which is separated from
+// the first key by a blank line remains at the top.
+func (d *Document) Sort(order KeyOrder) error {
+	s := &docSorter{d: d, order: order, comments: make(map[int][]*ast.Comment)}
+	s.attachComments()
+	for i, c := range s.preamble {
+		if i > 0 && d.blankLineBetween(s.preamble[i-1].Position.End, c.Position.Begin) {
+			s.buf.WriteByte('\n')
+		}
+		s.writeComment(c)
+	}
+	if len(s.preamble) > 0 {
+		s.buf.WriteByte('\n')
+	}
+	s.table(d.table, nil)
+	if len(s.epilogue) > 0 {
+		s.buf.WriteByte('\n')
+	}
+	for _, c := range s.epilogue {
+		s.writeComment(c)
+	}
+	return d.replace([]rune(s.buf.String()))
+}
+
+type docSorter struct {
+	d        *Document
+	order    KeyOrder
+	buf      bytes.Buffer
+	comments map[int][]*ast.Comment // comments preceding the key or table at offset
+	preamble []*ast.Comment
+	epilogue []*ast.Comment
+}
+
+type docItem struct {
+	begin, end int // line range of the key/value pair or table header
+}
+
+// attachComments assigns all comments on their own lines to the following item.
+func (s *docSorter) attachComments() {
+	d := s.d
+	var items []docItem
+	var walk func(field interface{})
+	walk = func(field interface{}) {
+		switch f := field.(type) {
+		case *ast.KeyValue:
+			items = append(items, docItem{d.lineStart(f.Value.Pos()), d.lineEnd(f.Value.End())})
+		case *ast.Table:
+			if f != d.table && f.Position != (ast.Position{}) {
+				items = append(items, docItem{d.lineStart(f.Position.Begin), d.lineEnd(f.Position.Begin)})
+			}
+			for _, child := range f.Fields {
+				walk(child)
+			}
+		case []*ast.Table:
+			for _, t := range f {
+				walk(t)
+			}
+		}
+	}
+	walk(d.table)
+	sort.Slice(items, func(i, j int) bool { return items[i].begin < items[j].begin })
+
+	for _, c := range d.table.Comments {
+		i := sort.Search(len(items), func(i int) bool { return items[i].begin > c.Position.Begin })
+		if i > 0 && c.Position.Begin <= items[i-1].end {
+			continue // on the line of an item
+		}
+		if i == len(items) {
+			s.epilogue = append(s.epilogue, c)
+		} else {
+			s.comments[items[i].begin] = append(s.comments[items[i].begin], c)
+		}
+	}
+	if len(items) == 0 {
+		return
+	}
+	first := s.comments[items[0].begin]
+	for k := len(first); k > 0; k-- {
+		next := items[0].begin
+		if k < len(first) {
+			next = first[k].Position.Begin
+		}
+		if d.blankLineBetween(first[k-1].Position.End, next) {
+			s.preamble, s.comments[items[0].begin] = first[:k], first[k:]
+			break
+		}
+	}
+}
+
+// blankLineBetween reports whether there is an empty line between offsets begin and end.
+func (d *Document) blankLineBetween(begin, end int) bool {
+	lines := strings.Split(string(d.src[begin:end]), "\n")
+	for _, line := range lines[1 : len(lines)-1] {
+		if strings.TrimSpace(line) == "" {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *docSorter) writeComment(c *ast.Comment) {
+	s.buf.WriteString("#")
+	s.buf.WriteString(strings.TrimRight(c.Text, " \t\r"))
+	s.buf.WriteByte('\n')
+}
+
+func (s *docSorter) writeComments(pos int) {
+	for _, c := range s.comments[pos] {
+		s.writeComment(c)
+	}
+}
+
+// table writes the key/value pairs and sub-tables of t, which is at path.
+func (s *docSorter) table(t *ast.Table, path []string) {
+	keys := make([]string, 0, len(t.Fields))
+	for key := range t.Fields {
+		keys = append(keys, key)
+	}
+	s.order(path, keys)
+
+	var tables []string
+	for _, key := range keys {
+		kv, ok := t.Fields[key].(*ast.KeyValue)
+		if !ok {
+			tables = append(tables, key)
+			continue
+		}
+		v := kv.Value
+		s.writeComments(s.d.lineStart(v.Pos()))
+		s.buf.WriteString(canonicalKey(key))
+		s.buf.WriteString(" = ")
+		s.buf.WriteString(strings.TrimRight(string(s.d.src[v.Pos():s.d.lineEnd(v.End())]), " \t\r"))
+		s.buf.WriteByte('\n')
+	}
+	for _, key := range tables {
+		childPath := append(path[:len(path):len(path)], key)
+		switch f := t.Fields[key].(type) {
+		case *ast.Table:
+			if f.Position != (ast.Position{}) {
+				s.header(f, "["+canonicalPath(childPath)+"]")
+			}
+			s.table(f, childPath)
+		case []*ast.Table:
+			for _, elem := range f {
+				s.header(elem, "[["+canonicalPath(childPath)+"]]")
+				s.table(elem, childPath)
+			}
+		}
+	}
+}
+
+// header writes the header of table t, with the comments preceding and following it.
+func (s *docSorter) header(t *ast.Table, header string) {
+	begin, end := s.d.lineStart(t.Position.Begin), s.d.lineEnd(t.Position.Begin)
+	if s.buf.Len() > 0 {
+		s.buf.WriteByte('\n')
+	}
+	s.writeComments(begin)
+	s.buf.WriteString(header)
+	for _, c := range s.d.table.Comments {
+		if c.Position.Begin >= begin && c.Position.Begin < end {
+			s.buf.WriteString(" #")
+			s.buf.WriteString(strings.TrimRight(c.Text, " \t\r"))
+		}
+	}
+	s.buf.WriteByte('\n')
+}
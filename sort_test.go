@@ -0,0 +1,167 @@
+package toml
+
+import (
+	"testing"
+)
+
+const testUnsortedDocument = `# Configuration file.
+# Second line.
+
+  zeta = 1   # trailing
+# About alpha.
+alpha  =   [
+  1, # one
+  2,
+]
+
+[server]   # the server
+port = 80
+
+# Database settings.
+[db]
+name = "x"
+# Host comment.
+host = "h"
+
+[[rule]]
+z = 1
+a = 2
+
+[[rule]]
+b = 3
+
+# The end.
+`
+
+func TestDocumentSortAlphabetical(t *testing.T) {
+	doc, err := ParseDocument([]byte(testUnsortedDocument))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := doc.Sort(AlphabeticalOrder); err != nil {
+		t.Fatal(err)
+	}
+	want := `# Configuration file.
+# Second line.
+
+# About alpha.
+alpha = [
+  1, # one
+  2,
+]
+zeta = 1   # trailing
+
+# Database settings.
+[db]
+# Host comment.
+host = "h"
+name = "x"
+
+[[rule]]
+a = 2
+z = 1
+
+[[rule]]
+b = 3
+
+[server] # the server
+port = 80
+
+# The end.
+`
+	if d := checkOutput(doc.Bytes(), []byte(want)); d != "" {
+		t.Errorf("wrong output:\n%s", d)
+	}
+}
+
+func TestDocumentSortStructOrder(t *testing.T) {
+	type config struct {
+		Zeta   int
+		Server struct {
+			Port int
+		}
+		Rule []struct {
+			Z int
+			A int
+		}
+		Alpha []int `toml:",order=1"`
+	}
+	doc, err := ParseDocument([]byte(testUnsortedDocument))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := doc.Sort(StructOrder(&config{})); err != nil {
+		t.Fatal(err)
+	}
+	want := `# Configuration file.
+# Second line.
+
+zeta = 1   # trailing
+# About alpha.
+alpha = [
+  1, # one
+  2,
+]
+
+[server] # the server
+port = 80
+
+[[rule]]
+z = 1
+a = 2
+
+[[rule]]
+b = 3
+
+# Database settings.
+[db]
+# Host comment.
+host = "h"
+name = "x"
+
+# The end.
+`
+	if d := checkOutput(doc.Bytes(), []byte(want)); d != "" {
+		t.Errorf("wrong output:\n%s", d)
+	}
+}
+
+func TestDocumentSortStructOrderConfig(t *testing.T) {
+	type config struct {
+		First int `conf:"zz"`
+		Table struct {
+			Y int
+			X int
+		} `conf:"aa"`
+	}
+	doc, err := ParseDocument([]byte("[aa]\nx = 1\ny = 1\n\n[other]\n\n[zz]\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := DefaultConfig.With(TagName("conf"))
+	if err := doc.Sort(cfg.StructOrder(&config{})); err != nil {
+		t.Fatal(err)
+	}
+	want := "[zz]\n\n[aa]\ny = 1\nx = 1\n\n[other]\n"
+	if d := checkOutput(doc.Bytes(), []byte(want)); d != "" {
+		t.Errorf("wrong output:\n%s", d)
+	}
+}
+
+func TestDocumentSortDocumentOrder(t *testing.T) {
+	ref, err := Parse([]byte("[b]\ny = 1\nx = 1\n[a]\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err := ParseDocument([]byte("c = 1\n[a]\n[b]\nx = 2\nz = 2\ny = 2\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := doc.Sort(DocumentOrder(ref)); err != nil {
+		t.Fatal(err)
+	}
+	want := "c = 1\n\n[b]\ny = 2\nx = 2\nz = 2\n\n[a]\n"
+	if d := checkOutput(doc.Bytes(), []byte(want)); d != "" {
+		t.Errorf("wrong output:\n%s", d)
+	}
+}
@@ -0,0 +1,204 @@
+package toml
+
+import (
+	"io"
+	"sort"
+
+	"github.com/naoina/toml/ast"
+)
+
+// TokenKind is the kind of a Token.
+type TokenKind int
+
+const (
+	TokenTable            TokenKind = iota + 1 // a table header, e.g. [a.b]
+	TokenArrayTable                            // an array table header, e.g. [[a.b]]
+	TokenKey                                   // the key of a key/value pair
+	TokenValue                                 // a value other than an array or inline table
+	TokenArrayStart                            // the '[' of an array
+	TokenArrayEnd                              // the ']' of an array
+	TokenInlineTableStart                      // the '{' of an inline table
+	TokenInlineTableEnd                        // the '}' of an inline table
+)
+
+var tokenKindNames = map[TokenKind]string{
+	TokenTable:            "table header",
+	TokenArrayTable:       "array table header",
+	TokenKey:              "key",
+	TokenValue:            "value",
+	TokenArrayStart:       "array start",
+	TokenArrayEnd:         "array end",
+	TokenInlineTableStart: "inline table start",
+	TokenInlineTableEnd:   "inline table end",
+}
+
+func (k TokenKind) String() string {
+	if name, ok := tokenKindNames[k]; ok {
+		return name
+	}
+	return "invalid token"
+}
+
+// Token is an element of a TOML document returned by Decoder.Token.
+type Token struct {
+	Kind     TokenKind
+	Key      []string  // the keys of a table header, or the key of a TokenKey
+	Value    ast.Value // the value of a TokenValue
+	Position Position  // the location of the first character of the token
+}
+
+// Token returns the next token of the input, or io.EOF at the end of the input. Arrays
+// and inline tables are returned as their delimiters with the tokens of their content in
+// between.
+//
+// Unlike Decode, Token doesn't build the AST of the whole document. The input is read
+// and parsed one table at a time, so conflicts between tables, e.g. a table defined
+// twice, aren't reported. Token always uses the built-in parser, and it cannot be mixed
+// with Decode on the same Decoder.
+func (d *Decoder) Token() (Token, error) {
+	if d.tokens == nil {
+		d.tokens = &tokenReader{
+			pr:   newPartReader(decoderReader{d}, 1),
+			o:    d.cfg.parseOptions(),
+			d:    &parseState{p: getParser()},
+			line: 1,
+		}
+	}
+	return d.tokens.next()
+}
+
+// tokenReader parses the tables of a document one by one and returns their tokens.
+type tokenReader struct {
+	pr     *partReader
+	o      *parseOptions
+	d      *parseState
+	tokens []Token // the remaining tokens of the current table
+	base   int     // offset of the current table
+	line   int     // line of the current table
+	starts []int   // offsets of the lines of the current table
+	err    error   // returned once all tokens have been returned
+}
+
+func (tr *tokenReader) next() (Token, error) {
+	for len(tr.tokens) == 0 {
+		if tr.err != nil {
+			return Token{}, tr.err
+		}
+		tr.err = tr.readPart()
+	}
+	tok := tr.tokens[0]
+	tr.tokens = tr.tokens[1:]
+	return tok, nil
+}
+
+// readPart parses the next table of the input into a new AST and adds its tokens.
+func (tr *tokenReader) readPart() error {
+	data, rerr := tr.pr.part()
+	if rerr != nil && rerr != io.EOF {
+		return rerr
+	}
+	data, err := preparePart(data, tr.o, tr.base == 0, tr.line)
+	if err != nil {
+		return err
+	}
+	p := tr.d.p
+	tr.d.init(data)
+	p.toml.version = tr.o.version
+	p.toml.allowLeadingZeros = tr.o.leadingZeros
+	p.toml.warn = tr.o.warn
+	p.toml.base, p.toml.firstLine, p.toml.line = tr.base, tr.line, tr.line
+	p.toml.header = nil
+	if err := tr.d.parse(); err != nil {
+		return err
+	}
+	tr.starts = lineStarts(p.toml.src)
+	t := p.toml.topTable
+	if p.toml.header != nil {
+		kind := TokenTable
+		if p.toml.curTable.Type == ast.TableTypeArray {
+			kind = TokenArrayTable
+		}
+		i := p.toml.curTable.Position.Begin - tr.base
+		for p.toml.src[i] == ' ' || p.toml.src[i] == '\t' {
+			i++
+		}
+		tr.add(Token{Kind: kind, Key: p.toml.header}, tr.base+i)
+		t = p.toml.curTable
+	}
+	tr.fields(t)
+	tr.base += len(p.toml.src)
+	tr.line = p.toml.line
+	return rerr
+}
+
+// fields adds the tokens of the key/value pairs of t in document order.
+func (tr *tokenReader) fields(t *ast.Table) {
+	var kvs []*ast.KeyValue
+	for _, f := range t.Fields {
+		if kv, ok := f.(*ast.KeyValue); ok {
+			kvs = append(kvs, kv)
+		}
+	}
+	sort.Slice(kvs, func(i, j int) bool { return kvs[i].Value.Pos() < kvs[j].Value.Pos() })
+	for _, kv := range kvs {
+		tr.add(Token{Kind: TokenKey, Key: []string{kv.Key}}, tr.keyPos(kv.Value.Pos()))
+		tr.value(kv.Value)
+	}
+}
+
+func (tr *tokenReader) value(v ast.Value) {
+	switch v := v.(type) {
+	case *ast.Array:
+		tr.add(Token{Kind: TokenArrayStart}, v.Pos())
+		for _, elem := range v.Value {
+			tr.value(elem)
+		}
+		tr.add(Token{Kind: TokenArrayEnd}, v.End()-1)
+	case *ast.Table:
+		tr.add(Token{Kind: TokenInlineTableStart}, v.Pos())
+		tr.fields(v)
+		tr.add(Token{Kind: TokenInlineTableEnd}, v.End()-1)
+	default:
+		tr.add(Token{Kind: TokenValue, Value: v}, v.Pos())
+	}
+}
+
+// keyPos returns the offset of the key of the value at offset pos. The grammar only
+// allows spaces and '=' between them.
+func (tr *tokenReader) keyPos(pos int) int {
+	src := tr.d.p.toml.src
+	i := pos - tr.base - 1
+	for src[i] == ' ' || src[i] == '\t' {
+		i--
+	}
+	for i--; src[i] == ' ' || src[i] == '\t'; i-- {
+	}
+	if src[i] != '"' {
+		for i > 0 && isBareKeyChar(src[i-1]) {
+			i--
+		}
+		return tr.base + i
+	}
+	// Find the opening quote, which is the first one which isn't escaped.
+	for i--; i > 0; i-- {
+		if src[i] != '"' {
+			continue
+		}
+		n := 0
+		for i-n > 0 && src[i-n-1] == '\\' {
+			n++
+		}
+		if n%2 == 0 {
+			break
+		}
+	}
+	return tr.base + i
+}
+
+// add appends tok at offset pos.
+func (tr *tokenReader) add(tok Token, pos int) {
+	pos -= tr.base
+	i := sort.Search(len(tr.starts), func(i int) bool { return tr.starts[i] > pos })
+	tok.Position = Position{Line: tr.line + i - 1, Column: pos - tr.starts[i-1] + 1}
+	tr.tokens = append(tr.tokens, tok)
+}
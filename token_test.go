@@ -0,0 +1,89 @@
+package toml
+
+import (
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/naoina/toml/ast"
+)
+
+func TestDecoderToken(t *testing.T) {
+	input := `title = "x" # comment
+[servers.alpha]
+  "ip addr" = "10.0.0.1"
+  ports = [ 8001, [8002] ]
+[[fruit]]
+inline = { a = 1, "b\"" = true }
+`
+	type tok struct {
+		Kind   TokenKind
+		Key    []string
+		Source string
+		Pos    Position
+	}
+	want := []tok{
+		{TokenKey, []string{"title"}, "", Position{1, 1}},
+		{TokenValue, nil, `"x"`, Position{1, 9}},
+		{TokenTable, []string{"servers", "alpha"}, "", Position{2, 1}},
+		{TokenKey, []string{"ip addr"}, "", Position{3, 3}},
+		{TokenValue, nil, `"10.0.0.1"`, Position{3, 15}},
+		{TokenKey, []string{"ports"}, "", Position{4, 3}},
+		{TokenArrayStart, nil, "", Position{4, 11}},
+		{TokenValue, nil, "8001", Position{4, 13}},
+		{TokenArrayStart, nil, "", Position{4, 19}},
+		{TokenValue, nil, "8002", Position{4, 20}},
+		{TokenArrayEnd, nil, "", Position{4, 24}},
+		{TokenArrayEnd, nil, "", Position{4, 26}},
+		{TokenArrayTable, []string{"fruit"}, "", Position{5, 1}},
+		{TokenKey, []string{"inline"}, "", Position{6, 1}},
+		{TokenInlineTableStart, nil, "", Position{6, 10}},
+		{TokenKey, []string{"a"}, "", Position{6, 12}},
+		{TokenValue, nil, "1", Position{6, 16}},
+		{TokenKey, []string{`b"`}, "", Position{6, 19}},
+		{TokenValue, nil, "true", Position{6, 27}},
+		{TokenInlineTableEnd, nil, "", Position{6, 32}},
+	}
+
+	dec := NewDecoder(strings.NewReader(input))
+	var got []tok
+	for {
+		token, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		tk := tok{Kind: token.Kind, Key: token.Key, Pos: token.Position}
+		if token.Value != nil {
+			tk.Source = token.Value.Source()
+		}
+		got = append(got, tk)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("wrong tokens:\ngot  %v\nwant %v", got, want)
+	}
+}
+
+func TestDecoderTokenError(t *testing.T) {
+	dec := NewDecoder(strings.NewReader("a = 1\n[t]\nb = 2 c = 3\n"))
+	var values []ast.Value
+	var err error
+	for err == nil {
+		var token Token
+		if token, err = dec.Token(); token.Kind == TokenValue {
+			values = append(values, token.Value)
+		}
+	}
+	if len(values) != 1 {
+		t.Errorf("got %d values before the error, want 1", len(values))
+	}
+	if err == io.EOF || err.Error() != "line 3: newline required in table" {
+		t.Errorf("wrong error %v", err)
+	}
+	if _, err2 := dec.Token(); err2 != err {
+		t.Errorf("error not repeated: %v", err2)
+	}
+}
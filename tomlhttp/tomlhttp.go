@@ -0,0 +1,87 @@
+// Package tomlhttp decodes TOML request bodies and writes TOML responses. It is kept
+// separate from package toml, so programs which don't need it don't depend on net/http.
+package tomlhttp
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+
+	"github.com/naoina/toml"
+)
+
+// MediaType is the media type of TOML documents.
+const MediaType = "application/toml"
+
+// DefaultMaxRequestSize is the maximum size of request bodies accepted by DecodeRequest,
+// and by DecodeRequestConfig if maxSize is zero.
+const DefaultMaxRequestSize = 1 << 20
+
+// RequestError is returned by DecodeRequest. StatusCode is the HTTP status code that
+// should be sent in response to the request.
+type RequestError struct {
+	StatusCode int
+	Err        error
+}
+
+func (err *RequestError) Error() string {
+	return fmt.Sprintf("toml: bad request (%d %s): %v", err.StatusCode, http.StatusText(err.StatusCode), err.Err)
+}
+
+func (err *RequestError) Unwrap() error {
+	return err.Err
+}
+
+// DecodeRequest decodes the TOML body of an HTTP request into the value pointed to by v,
+// using toml.DefaultConfig. See DecodeRequestConfig for details.
+func DecodeRequest(r *http.Request, v interface{}) error {
+	return DecodeRequestConfig(&toml.DefaultConfig, 0, r, v)
+}
+
+// DecodeRequestConfig decodes the TOML body of an HTTP request into the value pointed
+// to by v using cfg. The Content-Type of the request must be MediaType, and the body
+// must not be larger than maxSize bytes, or DefaultMaxRequestSize if maxSize is zero or
+// negative. All errors are *RequestError values.
+func DecodeRequestConfig(cfg *toml.Config, maxSize int64, r *http.Request, v interface{}) error {
+	ct := r.Header.Get("Content-Type")
+	if typ, _, err := mime.ParseMediaType(ct); err != nil || typ != MediaType {
+		return &RequestError{http.StatusUnsupportedMediaType, fmt.Errorf("content type %q is not %s", ct, MediaType)}
+	}
+
+	limit := maxSize
+	if limit <= 0 {
+		limit = DefaultMaxRequestSize
+	}
+	data, err := ioutil.ReadAll(io.LimitReader(r.Body, limit+1))
+	if err != nil {
+		return &RequestError{http.StatusBadRequest, err}
+	}
+	if int64(len(data)) > limit {
+		return &RequestError{http.StatusRequestEntityTooLarge, fmt.Errorf("body exceeds the maximum size of %d bytes", limit)}
+	}
+	if err := cfg.Unmarshal(data, v); err != nil {
+		return &RequestError{http.StatusBadRequest, err}
+	}
+	return nil
+}
+
+// EncodeResponse writes the TOML encoding of v as the body of an HTTP response, using
+// toml.DefaultConfig. See EncodeResponseConfig for details.
+func EncodeResponse(w http.ResponseWriter, v interface{}) error {
+	return EncodeResponseConfig(&toml.DefaultConfig, w, v)
+}
+
+// EncodeResponseConfig writes the TOML encoding of v using cfg as the body of an HTTP
+// response with status 200. The Content-Type header is set to MediaType. If v cannot be
+// encoded, nothing is written and the error is returned.
+func EncodeResponseConfig(cfg *toml.Config, w http.ResponseWriter, v interface{}) error {
+	data, err := cfg.Marshal(v)
+	if err != nil {
+		return err
+	}
+	w.Header().Set("Content-Type", MediaType+"; charset=utf-8")
+	_, err = w.Write(data)
+	return err
+}
@@ -0,0 +1,59 @@
+package tomlhttp
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/naoina/toml"
+)
+
+func TestDecodeRequest(t *testing.T) {
+	type config struct{ Name string }
+	tests := []struct {
+		contentType, body string
+		status            int
+	}{
+		{"application/toml", `name = "x"`, 0},
+		{"application/toml; charset=utf-8", `name = "x"`, 0},
+		{"", `name = "x"`, http.StatusUnsupportedMediaType},
+		{"application/json", `{}`, http.StatusUnsupportedMediaType},
+		{"application/toml", `name = `, http.StatusBadRequest},
+		{"application/toml", `other = 1`, http.StatusBadRequest},
+		{"application/toml", `name = "` + strings.Repeat("x", 100) + `"`, http.StatusRequestEntityTooLarge},
+	}
+	for _, test := range tests {
+		r := httptest.NewRequest("POST", "/", strings.NewReader(test.body))
+		if test.contentType != "" {
+			r.Header.Set("Content-Type", test.contentType)
+		}
+		var v config
+		err := DecodeRequestConfig(&toml.DefaultConfig, 100, r, &v)
+		var rerr *RequestError
+		switch {
+		case test.status == 0 && err != nil:
+			t.Errorf("%q: unexpected error: %v", test.body, err)
+		case test.status == 0 && v.Name != "x":
+			t.Errorf("%q: wrong value %+v", test.body, v)
+		case test.status != 0 && !errors.As(err, &rerr):
+			t.Errorf("%q: expected *RequestError, got %v", test.body, err)
+		case test.status != 0 && rerr.StatusCode != test.status:
+			t.Errorf("%q: wrong status %d, want %d", test.body, rerr.StatusCode, test.status)
+		}
+	}
+}
+
+func TestEncodeResponse(t *testing.T) {
+	w := httptest.NewRecorder()
+	if err := EncodeResponse(w, struct{ Name string }{"x"}); err != nil {
+		t.Fatal(err)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/toml; charset=utf-8" {
+		t.Errorf("wrong Content-Type %q", ct)
+	}
+	if body := w.Body.String(); body != "name = \"x\"\n" {
+		t.Errorf("wrong body %q", body)
+	}
+}
@@ -0,0 +1,56 @@
+// Package tomlsql stores TOML documents in SQL databases. It is kept separate from
+// package toml, so programs which don't need it don't depend on database/sql/driver.
+package tomlsql
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+
+	"github.com/naoina/toml"
+)
+
+// Column stores a value as a TOML document in a text column of an SQL database.
+// It implements driver.Valuer, which encodes V using toml.Marshal, and sql.Scanner,
+// which decodes the column into V using toml.Unmarshal. For scanning, V must be a
+// pointer.
+//
+//	var settings Settings
+//	db.Exec("UPDATE users SET settings = ? WHERE id = ?", tomlsql.Column{settings}, id)
+//	db.QueryRow("SELECT settings FROM users WHERE id = ?", id).Scan(tomlsql.Column{&settings})
+//
+// NULL values are stored for nil V, and scanning NULL leaves V unchanged.
+type Column struct {
+	V interface{}
+}
+
+// Value implements driver.Valuer.
+func (c Column) Value() (driver.Value, error) {
+	if c.V == nil || isNilPtr(reflect.ValueOf(c.V)) {
+		return nil, nil
+	}
+	data, err := toml.Marshal(c.V)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// Scan implements sql.Scanner.
+func (c Column) Scan(src interface{}) error {
+	switch src := src.(type) {
+	case nil:
+		return nil
+	case string:
+		return toml.Unmarshal([]byte(src), c.V)
+	case []byte:
+		return toml.Unmarshal(src, c.V)
+	default:
+		return fmt.Errorf("toml: cannot scan %T into Column", src)
+	}
+}
+
+// isNilPtr reports whether rv is a nil pointer.
+func isNilPtr(rv reflect.Value) bool {
+	return rv.Kind() == reflect.Ptr && rv.IsNil()
+}
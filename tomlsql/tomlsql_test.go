@@ -0,0 +1,47 @@
+package tomlsql
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"reflect"
+	"testing"
+)
+
+var (
+	_ driver.Valuer = Column{}
+	_ sql.Scanner   = Column{}
+)
+
+func TestColumn(t *testing.T) {
+	type settings struct {
+		Theme string
+		Size  int
+	}
+	value, err := Column{settings{"dark", 12}}.Value()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "theme = \"dark\"\nsize = 12\n"; value != want {
+		t.Errorf("wrong value %q, want %q", value, want)
+	}
+
+	var got settings
+	for _, src := range []interface{}{value, []byte(value.(string))} {
+		got = settings{}
+		if err := (Column{&got}).Scan(src); err != nil {
+			t.Fatal(err)
+		}
+		if want := (settings{"dark", 12}); !reflect.DeepEqual(got, want) {
+			t.Errorf("wrong value after Scan(%T): %+v", src, got)
+		}
+	}
+	if err := (Column{&got}).Scan(nil); err != nil || got.Theme != "dark" {
+		t.Errorf("Scan(nil) changed value or failed: %+v, %v", got, err)
+	}
+	if err := (Column{&got}).Scan(1); err == nil {
+		t.Error("expected error for integer column")
+	}
+	if value, err := (Column{(*settings)(nil)}).Value(); value != nil || err != nil {
+		t.Errorf("wrong result for nil pointer: %v, %v", value, err)
+	}
+}
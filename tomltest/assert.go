@@ -0,0 +1,100 @@
+package tomltest
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/naoina/toml"
+)
+
+var updateGolden = flag.Bool("tomltest.update", false, "rewrite golden files checked by AssertGolden")
+
+// AssertRoundTrip checks that v survives encoding with toml.Marshal and decoding
+// with toml.Unmarshal into a new value of the same type.
+func AssertRoundTrip(t testing.TB, v interface{}) {
+	t.Helper()
+	AssertRoundTripConfig(t, &toml.DefaultConfig, v)
+}
+
+// AssertRoundTripConfig is like AssertRoundTrip, but uses cfg for encoding and decoding.
+func AssertRoundTripConfig(t testing.TB, cfg *toml.Config, v interface{}) {
+	t.Helper()
+	data, err := cfg.Marshal(v)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+		return
+	}
+	want := reflect.ValueOf(v)
+	for want.Kind() == reflect.Ptr {
+		want = want.Elem()
+	}
+	got := reflect.New(want.Type())
+	if err := cfg.Unmarshal(data, got.Interface()); err != nil {
+		t.Fatalf("Unmarshal of the encoded value failed: %v\nTOML:\n%s", err, data)
+		return
+	}
+	if !reflect.DeepEqual(got.Elem().Interface(), want.Interface()) {
+		t.Errorf("value changed in round trip:\ngot  %#v\nwant %#v\nTOML:\n%s", got.Elem().Interface(), want.Interface(), data)
+	}
+}
+
+// AssertEqualDocs checks that two TOML documents have the same content. Differences in
+// formatting, comments and key order are ignored. On failure, the differing keys
+// are reported in the format of toml.Change.
+func AssertEqualDocs(t testing.TB, want, got []byte) {
+	t.Helper()
+	if diff, err := diffDocs(want, got); err != nil {
+		t.Fatalf("%v", err)
+	} else if diff != "" {
+		t.Errorf("documents differ (- want, + got):\n%s", diff)
+	}
+}
+
+// AssertGolden checks that the TOML document got has the same content as the golden
+// file. When the test binary is run with the -tomltest.update flag, the golden file is
+// overwritten with got instead.
+func AssertGolden(t testing.TB, file string, got []byte) {
+	t.Helper()
+	if *updateGolden {
+		if err := ioutil.WriteFile(file, got, 0644); err != nil {
+			t.Fatalf("%v", err)
+		}
+		return
+	}
+	want, err := ioutil.ReadFile(file)
+	if err != nil {
+		t.Fatalf("%v", err)
+		return
+	}
+	if diff, err := diffDocs(want, got); err != nil {
+		t.Fatalf("%s: %v", file, err)
+	} else if diff != "" {
+		t.Errorf("output differs from %s (- want, + got):\n%s", file, diff)
+	}
+}
+
+// diffDocs returns the changes between two documents, one per line.
+func diffDocs(want, got []byte) (string, error) {
+	wantTable, err := toml.Parse(want)
+	if err != nil {
+		return "", fmt.Errorf("invalid expected document: %v", err)
+	}
+	gotTable, err := toml.Parse(got)
+	if err != nil {
+		return "", fmt.Errorf("invalid document: %v", err)
+	}
+	changes, err := toml.Diff(wantTable, gotTable)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	for _, c := range changes {
+		b.WriteString(c.String())
+		b.WriteByte('\n')
+	}
+	return b.String(), nil
+}
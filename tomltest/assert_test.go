@@ -0,0 +1,75 @@
+package tomltest
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+// recorder captures the failures reported by assertions.
+type recorder struct {
+	testing.TB
+	failures []string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...interface{}) {
+	r.failures = append(r.failures, fmt.Sprintf(format, args...))
+}
+
+func (r *recorder) Fatalf(format string, args ...interface{}) {
+	r.Errorf(format, args...)
+}
+
+func TestAssertRoundTrip(t *testing.T) {
+	type config struct {
+		Name  string
+		Ports []int
+	}
+	r := new(recorder)
+	AssertRoundTrip(r, &config{Name: "x", Ports: []int{1, 2}})
+	if len(r.failures) != 0 {
+		t.Errorf("unexpected failures: %q", r.failures)
+	}
+
+	// Unexported fields are not encoded.
+	type lossy struct {
+		A int
+		b int
+	}
+	r = new(recorder)
+	AssertRoundTrip(r, lossy{A: 1, b: 2})
+	if len(r.failures) != 1 {
+		t.Errorf("expected one failure, got %q", r.failures)
+	}
+}
+
+func TestAssertEqualDocs(t *testing.T) {
+	r := new(recorder)
+	AssertEqualDocs(r, []byte("a = 1\n[t]\nb = 'x'\n"), []byte("# comment\nt = { b = \"x\" }\na = 0x1\n"))
+	if len(r.failures) != 0 {
+		t.Errorf("unexpected failures: %q", r.failures)
+	}
+
+	r = new(recorder)
+	AssertEqualDocs(r, []byte("a = 1\nb = 2\n"), []byte("a = 2\nc = 3\n"))
+	want := "documents differ (- want, + got):\n~ a = 1 -> 2\n- b = 2\n+ c = 3\n"
+	if len(r.failures) != 1 || r.failures[0] != want {
+		t.Errorf("wrong failures: %q", r.failures)
+	}
+}
+
+func TestAssertGolden(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "golden.toml")
+	if err := ioutil.WriteFile(file, []byte("a = 1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	r := new(recorder)
+	AssertGolden(r, file, []byte("a = 1 # same\n"))
+	AssertGolden(r, file, []byte("a = 2\n"))
+	if len(r.failures) != 1 {
+		t.Errorf("expected one failure, got %q", r.failures)
+	}
+}
@@ -0,0 +1,249 @@
+package tomltest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"math"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/naoina/toml"
+)
+
+// Runner runs the test cases of the toml-test suite.
+type Runner struct {
+	// Files contains the test cases. This should be the "tests" directory of
+	// toml-test, i.e. the directory containing "valid" and "invalid".
+	Files fs.FS
+
+	// Config is the configuration under test. DefaultConfig is used if nil.
+	Config *toml.Config
+
+	// Encoder selects the encoder tests. By default, the decoder is tested.
+	Encoder bool
+
+	// Skip contains patterns of test names which are not run. Test names
+	// are paths without extension like "valid/string/escapes". The patterns
+	// use the syntax of path.Match.
+	Skip []string
+}
+
+// Test is the result of a single test case.
+type Test struct {
+	Name    string // e.g. "valid/string/escapes"
+	Skipped bool
+	Failure string // empty if the test passed
+}
+
+// Failed reports whether the test has failed.
+func (t Test) Failed() bool {
+	return t.Failure != ""
+}
+
+// Run runs all test cases and returns the results in name order.
+func (r *Runner) Run() ([]Test, error) {
+	names, err := r.testNames()
+	if err != nil {
+		return nil, err
+	}
+	tests := make([]Test, 0, len(names))
+	for _, name := range names {
+		test := Test{Name: name}
+		switch {
+		case r.skip(name):
+			test.Skipped = true
+		case strings.HasPrefix(name, "invalid/"):
+			test.Failure = r.runInvalid(name)
+		default:
+			test.Failure = r.runValid(name)
+		}
+		tests = append(tests, test)
+	}
+	return tests, nil
+}
+
+// testNames returns the names of all test cases in r.Files.
+func (r *Runner) testNames() ([]string, error) {
+	var names []string
+	err := fs.WalkDir(r.Files, ".", func(file string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		ext := path.Ext(file)
+		name := strings.TrimSuffix(file, ext)
+		switch {
+		case strings.HasPrefix(file, "valid/") && ext == ".toml":
+			names = append(names, name)
+		case strings.HasPrefix(file, "invalid/") && ext == ".toml" && !r.Encoder:
+			names = append(names, name)
+		}
+		return nil
+	})
+	sort.Strings(names)
+	return names, err
+}
+
+func (r *Runner) skip(name string) bool {
+	for _, pattern := range r.Skip {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Runner) config() *toml.Config {
+	if r.Config != nil {
+		return r.Config
+	}
+	return &DefaultConfig
+}
+
+func (r *Runner) runInvalid(name string) string {
+	input, err := fs.ReadFile(r.Files, name+".toml")
+	if err != nil {
+		return err.Error()
+	}
+	var out bytes.Buffer
+	if err := Decode(r.config(), bytes.NewReader(input), &out); err == nil {
+		return "expected an error, but decoding succeeded with output:\n" + out.String()
+	}
+	return ""
+}
+
+func (r *Runner) runValid(name string) string {
+	input, err := fs.ReadFile(r.Files, name+".toml")
+	if err != nil {
+		return err.Error()
+	}
+	want, err := fs.ReadFile(r.Files, name+".json")
+	if err != nil {
+		return err.Error()
+	}
+	cfg := r.config()
+	if r.Encoder {
+		// Encode the expected JSON and check that it decodes to the same value.
+		var enc bytes.Buffer
+		if err := Encode(cfg, bytes.NewReader(want), &enc); err != nil {
+			return "encoding error: " + err.Error()
+		}
+		input = enc.Bytes()
+	}
+	var got bytes.Buffer
+	if err := Decode(cfg, bytes.NewReader(input), &got); err != nil {
+		return "decoding error: " + err.Error()
+	}
+	var wantJSON, gotJSON interface{}
+	if err := json.Unmarshal(want, &wantJSON); err != nil {
+		return "invalid test JSON: " + err.Error()
+	}
+	if err := json.Unmarshal(got.Bytes(), &gotJSON); err != nil {
+		return "invalid output JSON: " + err.Error()
+	}
+	if err := compareJSON("", wantJSON, gotJSON); err != nil {
+		return err.Error()
+	}
+	return ""
+}
+
+// compareJSON compares values in the toml-test representation.
+func compareJSON(key string, want, got interface{}) error {
+	switch w := want.(type) {
+	case map[string]interface{}:
+		g, ok := got.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("key %q: want table, got %v", key, got)
+		}
+		if isPrim(w) {
+			return comparePrim(key, w, g)
+		}
+		if len(w) != len(g) {
+			return fmt.Errorf("key %q: want table with %d keys, got %d", key, len(w), len(g))
+		}
+		for k, wv := range w {
+			gv, ok := g[k]
+			if !ok {
+				return fmt.Errorf("key %q: missing key %q", key, k)
+			}
+			if err := compareJSON(joinKey(key, k), wv, gv); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		g, ok := got.([]interface{})
+		if !ok {
+			return fmt.Errorf("key %q: want array, got %v", key, got)
+		}
+		if len(w) != len(g) {
+			return fmt.Errorf("key %q: want array of length %d, got %d", key, len(w), len(g))
+		}
+		for i := range w {
+			if err := compareJSON(fmt.Sprintf("%s[%d]", key, i), w[i], g[i]); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("key %q: unexpected value %v in test JSON", key, want)
+	}
+	return nil
+}
+
+func joinKey(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
+
+func isPrim(m map[string]interface{}) bool {
+	if len(m) != 2 {
+		return false
+	}
+	_, hasType := m["type"].(string)
+	_, hasValue := m["value"].(string)
+	return hasType && hasValue
+}
+
+func comparePrim(key string, want, got map[string]interface{}) error {
+	wt, wv := want["type"].(string), want["value"].(string)
+	if !isPrim(got) {
+		return fmt.Errorf("key %q: want %s value, got %v", key, wt, got)
+	}
+	gt, gv := got["type"].(string), got["value"].(string)
+	if wt != gt {
+		return fmt.Errorf("key %q: want type %s, got %s", key, wt, gt)
+	}
+	if !primEqual(wt, wv, gv) {
+		return fmt.Errorf("key %q: want %s %q, got %q", key, wt, wv, gv)
+	}
+	return nil
+}
+
+func primEqual(typ, want, got string) bool {
+	switch typ {
+	case "integer":
+		w, err1 := strconv.ParseInt(want, 10, 64)
+		g, err2 := strconv.ParseInt(got, 10, 64)
+		return err1 == nil && err2 == nil && w == g
+	case "float":
+		w, err1 := strconv.ParseFloat(strings.TrimPrefix(want, "+"), 64)
+		g, err2 := strconv.ParseFloat(strings.TrimPrefix(got, "+"), 64)
+		if math.IsNaN(w) && math.IsNaN(g) {
+			return true
+		}
+		return err1 == nil && err2 == nil && w == g
+	case "datetime", "datetime-local", "date-local", "time-local":
+		p := prim{Type: typ, Value: want}
+		w, err1 := p.toInterface()
+		p.Value = got
+		g, err2 := p.toInterface()
+		return err1 == nil && err2 == nil && w.(time.Time).Equal(g.(time.Time))
+	default:
+		return want == got
+	}
+}
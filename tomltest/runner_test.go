@@ -0,0 +1,105 @@
+package tomltest
+
+import (
+	"os"
+	"reflect"
+	"testing"
+	"testing/fstest"
+
+	"github.com/naoina/toml"
+)
+
+var testFiles = fstest.MapFS{
+	"valid/integer.toml": {Data: []byte("a = 1\nb = -2\n")},
+	"valid/integer.json": {Data: []byte(`{"a": {"type": "integer", "value": "1"}, "b": {"type": "integer", "value": "-2"}}`)},
+	"valid/table.toml":   {Data: []byte("[t]\nf = 1.5\ns = \"x\"\n")},
+	"valid/table.json":   {Data: []byte(`{"t": {"f": {"type": "float", "value": "1.5"}, "s": {"type": "string", "value": "x"}}}`)},
+	"valid/wrong.toml":   {Data: []byte("a = 1\n")},
+	"valid/wrong.json":   {Data: []byte(`{"a": {"type": "integer", "value": "2"}}`)},
+	"invalid/key.toml":   {Data: []byte("a = \n")},
+	"invalid/dup.toml":   {Data: []byte("a = 1\na = 2\n")},
+	"invalid/skip.toml":  {Data: []byte("a = 1\n")},
+}
+
+func TestRunner(t *testing.T) {
+	r := Runner{Files: testFiles, Skip: []string{"invalid/skip"}}
+	tests, err := r.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Test{
+		{Name: "invalid/dup"},
+		{Name: "invalid/key"},
+		{Name: "invalid/skip", Skipped: true},
+		{Name: "valid/integer"},
+		{Name: "valid/table"},
+		{Name: "valid/wrong", Failure: `key "a": want integer "2", got "1"`},
+	}
+	if !reflect.DeepEqual(tests, want) {
+		t.Errorf("wrong results:\ngot  %+v\nwant %+v", tests, want)
+	}
+}
+
+func TestRunnerEncoder(t *testing.T) {
+	r := Runner{Files: testFiles, Encoder: true, Skip: []string{"valid/wrong"}}
+	tests, err := r.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range tests {
+		if test.Failed() {
+			t.Errorf("%s: %s", test.Name, test.Failure)
+		}
+	}
+	if len(tests) != 3 {
+		t.Errorf("ran %d tests, want 3", len(tests))
+	}
+}
+
+// strictGaps are the test cases of testdata which Strict is known to fail.
+var strictGaps = []string{
+	// Dotted keys in key/value pairs are not supported.
+	"valid/key/dotted",
+	"valid/inline-table/dotted",
+	// Literal strings cannot be used as keys.
+	"valid/key/literal",
+	// Local date-times, dates and times decode to time.Time in UTC and are reported
+	// as offset date-times.
+	"valid/datetime/local",
+}
+
+// TestStrictSuite runs a subset of the toml-test suite, in the same layout, with the
+// configuration used by toml-test-adapter.go.
+func TestStrictSuite(t *testing.T) {
+	for _, encoder := range []bool{false, true} {
+		r := Runner{
+			Files:   os.DirFS("testdata"),
+			Config:  DefaultConfig.With(toml.Strict()),
+			Encoder: encoder,
+			Skip:    strictGaps,
+		}
+		tests, err := r.Run()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, test := range tests {
+			if test.Failed() {
+				t.Errorf("%s (encoder: %t): %s", test.Name, encoder, test.Failure)
+			}
+		}
+	}
+
+	// The known gaps still fail. Remove them from strictGaps once they pass.
+	r := Runner{Files: os.DirFS("testdata"), Config: DefaultConfig.With(toml.Strict())}
+	tests, err := r.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range tests {
+		for _, gap := range strictGaps {
+			if test.Name == gap && !test.Failed() {
+				t.Errorf("%s passes, remove it from strictGaps", test.Name)
+			}
+		}
+	}
+}
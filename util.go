@@ -1,65 +1,389 @@
 package toml
 
 import (
+	"encoding"
 	"fmt"
 	"reflect"
+	"sort"
 	"strings"
 )
 
-const fieldTagName = "toml"
+const (
+	fieldTagName      = "toml"
+	deprecatedTagName = "deprecated"
+	protobufTagName   = "protobuf"
+)
 
 // fieldCache maps normalized field names to their position in a struct.
 type fieldCache struct {
-	named map[string]fieldInfo // fields with an explicit name in tag
-	auto  map[string]fieldInfo // fields with auto-assigned normalized names
+	named  map[string]fieldInfo // fields with an explicit name in tag
+	auto   map[string]fieldInfo // fields with auto-assigned normalized names
+	tables map[string]string    // tables of dotted keys in tags -> first field name
 }
 
 type fieldInfo struct {
-	index   []int
-	name    string
-	ignored bool
+	index    []int
+	name     string
+	key      string // the key given in the tag
+	ignored  bool
+	opts     tagOptions
+	depth    int  // number of embedded structs containing the field
+	embedded bool // an embedded struct whose fields are promoted
+
+	deprecated    bool
+	deprecatedMsg string
 }
 
-func makeFieldCache(cfg *Config, rt reflect.Type) fieldCache {
+// makeFieldCache returns the fields of struct type rt. Like encoding/json, the fields of
+// embedded structs without a key in their tag are promoted to rt. Promoted fields are
+// shadowed by fields of a lower depth, whether their keys come from tags or field names,
+// and promoted fields of the same depth with the same key cancel each other out. Keys
+// given by the alias tag option must not be used by other fields. An exported embedded
+// struct can still be set through a table named after its type if no field has that key.
+func makeFieldCache(cfg *Config, rt reflect.Type) (fieldCache, error) {
 	named, auto := make(map[string]fieldInfo), make(map[string]fieldInfo)
-	for i := 0; i < rt.NumField(); i++ {
-		ft := rt.Field(i)
-		// skip unexported fields
-		if ft.PkgPath != "" && !ft.Anonymous {
-			continue
-		}
-		col, _ := extractTag(ft.Tag.Get(fieldTagName))
-		info := fieldInfo{index: ft.Index, name: ft.Name, ignored: col == "-"}
-		if col == "" || col == "-" {
-			auto[cfg.NormFieldName(rt, ft.Name)] = info
+	ambiguous := make(map[string]bool) // "n" or "a" followed by the key
+	var structs []fieldInfo            // embedded structs of rt
+	var aliases []fieldAlias
+	level := []fieldInfo{{}}
+	visited := map[reflect.Type]bool{rt: true}
+	for depth := 0; len(level) > 0; depth++ {
+		var next []fieldInfo
+		for _, parent := range level {
+			st := rt
+			if depth > 0 {
+				st = structType(rt.FieldByIndex(parent.index).Type)
+			}
+			for i := 0; i < st.NumField(); i++ {
+				ft := st.Field(i)
+				// skip unexported fields
+				if ft.PkgPath != "" && !isPromoted(ft) {
+					continue
+				}
+				col, opts := extractTag(ft.Tag.Get(cfg.tagName()))
+				var alias string
+				if cfg.Protobuf {
+					if isProtobufInternal(ft) {
+						continue
+					}
+					if name, jsonName, ok := protobufNames(ft); ok && col == "" {
+						col, alias = name, jsonName
+					}
+				}
+				index := append(append([]int(nil), parent.index...), i)
+				info := fieldInfo{index: index, name: ft.Name, ignored: col == "-", opts: opts, depth: depth}
+				if col != "-" {
+					info.key = col
+				}
+				info.deprecatedMsg, info.deprecated = ft.Tag.Lookup(deprecatedTagName)
+				if col == "" && isPromoted(ft) {
+					if !visited[structType(ft.Type)] {
+						next = append(next, info)
+					}
+					if depth == 0 && ft.PkgPath == "" {
+						info.embedded = true
+						structs = append(structs, info)
+					}
+					continue
+				}
+				m, key, id := named, col, "n"+col
+				if col == "" || col == "-" {
+					m, key = auto, cfg.fieldKey(rt, ft.Name)
+					id = "a" + key
+				}
+				if prev, ok := m[key]; ok {
+					switch {
+					case prev.depth < depth:
+						continue
+					case info.ignored:
+						continue
+					case prev.ignored:
+					case depth > 0:
+						ambiguous[id] = true
+						continue
+					default:
+						return fieldCache{}, &fieldConflictError{rt, prev.name, ft.Name, key}
+					}
+				}
+				m[key] = info
+				if _, ok := named[alias]; alias != "" && alias != col && !ok {
+					named[alias] = info
+				}
+				for _, alias := range opts.values(tagAlias) {
+					aliases = append(aliases, fieldAlias{alias, info})
+				}
+			}
+		}
+		for _, info := range next {
+			visited[structType(rt.FieldByIndex(info.index).Type)] = true
+		}
+		level = next
+	}
+	shadowTagged(cfg, rt, named, auto)
+	for id := range ambiguous {
+		if id[0] == 'n' {
+			delete(named, id[1:])
 		} else {
-			named[col] = info
+			delete(auto, id[1:])
+		}
+	}
+	for _, a := range aliases {
+		prev, ok := named[a.key]
+		if !ok {
+			prev, ok = auto[cfg.inputKey(rt, a.key)]
+		}
+		if ok && !prev.ignored && !reflect.DeepEqual(prev.index, a.info.index) {
+			return fieldCache{}, &fieldConflictError{rt, prev.name, a.info.name, a.key}
+		}
+		named[a.key] = a.info
+	}
+	for _, info := range structs {
+		key := cfg.fieldKey(rt, info.name)
+		if _, ok := auto[key]; !ok {
+			auto[key] = info
+		}
+	}
+	tables, err := dottedTables(cfg, rt, named, auto)
+	if err != nil {
+		return fieldCache{}, err
+	}
+	return fieldCache{named, auto, tables}, nil
+}
+
+// shadowTagged removes promoted fields which are shadowed by a field of a lower depth
+// with the same key in the other map, so a field's depth decides before its tag does.
+func shadowTagged(cfg *Config, rt reflect.Type, named, auto map[string]fieldInfo) {
+	namedDepth := make(map[string]int, len(named))
+	for key, info := range named {
+		k := cfg.inputKey(rt, key)
+		if d, ok := namedDepth[k]; !ok || info.depth < d {
+			namedDepth[k] = info.depth
+		}
+	}
+	for key, info := range named {
+		if a, ok := auto[cfg.inputKey(rt, key)]; ok && !a.ignored && a.depth < info.depth {
+			delete(named, key)
+		}
+	}
+	for key, info := range auto {
+		if d, ok := namedDepth[key]; ok && d < info.depth {
+			delete(auto, key)
+		}
+	}
+}
+
+// fieldAlias is an additional key of a field given by the alias tag option.
+type fieldAlias struct {
+	key  string
+	info fieldInfo
+}
+
+// dottedTables returns the tables containing the fields with dotted keys in their
+// tag, e.g. "server" and "server.tls" for "server.tls.cert". The tables cannot be
+// fields themselves.
+func dottedTables(cfg *Config, rt reflect.Type, named, auto map[string]fieldInfo) (map[string]string, error) {
+	var tables map[string]string
+	for key, info := range named {
+		for i := strings.LastIndexByte(key, '.'); i > 0; i = strings.LastIndexByte(key[:i], '.') {
+			if tables == nil {
+				tables = make(map[string]string)
+			}
+			if prev, ok := tables[key[:i]]; !ok || info.index[0] < named[prev].index[0] {
+				tables[key[:i]] = key
+			}
+		}
+	}
+	for table, key := range tables {
+		prev, ok := named[table]
+		if !ok && !strings.Contains(table, ".") {
+			prev, ok = auto[cfg.inputKey(rt, table)]
+		}
+		if ok && !prev.ignored && !prev.embedded {
+			return nil, &fieldConflictError{rt, prev.name, named[key].name, table}
+		}
+	}
+	return tables, nil
+}
+
+// isPromoted reports whether the fields of embedded struct field ft are promoted to the
+// containing struct. Structs which decode themselves are set as a whole. Unexported
+// embedded structs are promoted unless they are pointers, which cannot be allocated.
+func isPromoted(ft reflect.StructField) bool {
+	if !ft.Anonymous || ft.PkgPath != "" && ft.Type.Kind() == reflect.Ptr {
+		return false
+	}
+	typ := structType(ft.Type)
+	if typ == nil || typ == timeType || hasUnmarshaler(typ) {
+		return false
+	}
+	return !reflect.PtrTo(typ).Implements(textUnmarshalerType)
+}
+
+var textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+// structType returns the struct type of typ or of the type it points to, or nil.
+func structType(typ reflect.Type) reflect.Type {
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if typ.Kind() != reflect.Struct {
+		return nil
+	}
+	return typ
+}
+
+// fieldByIndex returns the field of struct rv at index. Nil pointers to embedded
+// structs are allocated if alloc is true, otherwise the result is invalid.
+func fieldByIndex(rv reflect.Value, index []int, alloc bool) reflect.Value {
+	for i, x := range index {
+		if i > 0 && rv.Kind() == reflect.Ptr {
+			if rv.IsNil() {
+				if !alloc {
+					return reflect.Value{}
+				}
+				rv.Set(reflect.New(rv.Type().Elem()))
+			}
+			rv = rv.Elem()
+		}
+		rv = rv.Field(x)
+	}
+	return rv
+}
+
+// isProtobufInternal reports whether ft is one of the XXX_ fields of structs generated
+// by older versions of protoc-gen-go. The internal fields of current versions are
+// unexported and skipped anyway.
+func isProtobufInternal(ft reflect.StructField) bool {
+	return strings.HasPrefix(ft.Name, "XXX_")
+}
+
+// protobufNames returns the field name and the JSON name declared in the protobuf tag
+// of a generated struct field, e.g.
+//
+//	`protobuf:"bytes,1,opt,name=server_name,json=serverName,proto3"`
+func protobufNames(ft reflect.StructField) (name, jsonName string, ok bool) {
+	tag, ok := ft.Tag.Lookup(protobufTagName)
+	if !ok {
+		return "", "", false
+	}
+	for _, opt := range strings.Split(tag, ",") {
+		switch {
+		case strings.HasPrefix(opt, "name="):
+			name = opt[len("name="):]
+		case strings.HasPrefix(opt, "json="):
+			jsonName = opt[len("json="):]
+		}
+	}
+	return name, jsonName, name != ""
+}
+
+// fields returns all fields that can be set through TOML in struct order.
+func (fc fieldCache) fields() []fieldInfo {
+	var fields []fieldInfo
+	for _, m := range []map[string]fieldInfo{fc.named, fc.auto} {
+		for _, info := range m {
+			if !info.ignored && !info.embedded {
+				fields = append(fields, info)
+			}
+		}
+	}
+	sort.Slice(fields, func(i, j int) bool {
+		a, b := fields[i].index, fields[j].index
+		for k := 0; k < len(a) && k < len(b); k++ {
+			if a[k] != b[k] {
+				return a[k] < b[k]
+			}
 		}
+		return len(a) < len(b)
+	})
+	return fields
+}
+
+// fieldKey returns the key of struct field name of rt in fieldCache.auto.
+func (cfg *Config) fieldKey(rt reflect.Type, name string) string {
+	switch {
+	case !cfg.ExactKeys:
+		return cfg.NormFieldName(rt, name)
+	case cfg.FieldToKey != nil:
+		return cfg.FieldToKey(rt, name)
+	default:
+		return name
+	}
+}
+
+// inputKey returns the key under which TOML key key is looked up in fieldCache.auto.
+func (cfg *Config) inputKey(rt reflect.Type, key string) string {
+	if cfg.ExactKeys {
+		return key
 	}
-	return fieldCache{named, auto}
+	return cfg.NormFieldName(rt, key)
 }
 
-func (fc fieldCache) findField(cfg *Config, rv reflect.Value, name string) (reflect.Value, string, error) {
+// findField returns the field of struct rv for key name, which is defined in the given
+// line.
+func (fc fieldCache) findField(cfg *Config, rv reflect.Value, name string, line int) (reflect.Value, fieldInfo, error) {
 	info, found := fc.named[name]
 	if !found {
-		info, found = fc.auto[cfg.NormFieldName(rv.Type(), name)]
+		info, found = fc.auto[cfg.inputKey(rv.Type(), name)]
 	}
 	if !found {
+		if cfg.OnUnknownField != nil {
+			cfg.OnUnknownField(rv.Type(), name, line)
+		}
 		if cfg.MissingField == nil {
-			return reflect.Value{}, "", fmt.Errorf("field corresponding to `%s' is not defined in %v", name, rv.Type())
+			return reflect.Value{}, info, fmt.Errorf("field corresponding to `%s' is not defined in %v", name, rv.Type())
 		} else {
-			return reflect.Value{}, "", cfg.MissingField(rv.Type(), name)
+			return reflect.Value{}, info, cfg.MissingField(rv.Type(), name)
 		}
 	} else if info.ignored {
-		return reflect.Value{}, "", fmt.Errorf("field corresponding to `%s' in %v cannot be set through TOML", name, rv.Type())
+		return reflect.Value{}, info, fmt.Errorf("field corresponding to `%s' in %v cannot be set through TOML", name, rv.Type())
 	}
-	return rv.FieldByIndex(info.index), info.name, nil
+	return fieldByIndex(rv, info.index, true), info, nil
 }
 
-func extractTag(tag string) (col, rest string) {
+func extractTag(tag string) (col string, opts tagOptions) {
 	tags := strings.SplitN(tag, ",", 2)
 	if len(tags) == 2 {
-		return strings.TrimSpace(tags[0]), strings.TrimSpace(tags[1])
+		return strings.TrimSpace(tags[0]), tagOptions(strings.TrimSpace(tags[1]))
 	}
 	return strings.TrimSpace(tags[0]), ""
 }
+
+// tagOptions is the comma-separated list of options following the key name
+// in a struct tag. Options are either flags like "omitempty" or have a value
+// like "layout=2006-01-02".
+type tagOptions string
+
+// has reports whether the option list contains the named option.
+func (o tagOptions) has(name string) bool {
+	_, ok := o.lookup(name)
+	return ok
+}
+
+// lookup returns the value of the named option.
+func (o tagOptions) lookup(name string) (string, bool) {
+	values := o.values(name)
+	if len(values) == 0 {
+		return "", false
+	}
+	return values[0], true
+}
+
+// values returns the values of all occurrences of the named option, e.g. the names
+// given by "alias=a,alias=b".
+func (o tagOptions) values(name string) []string {
+	if o == "" {
+		return nil
+	}
+	var values []string
+	for _, opt := range strings.Split(string(o), ",") {
+		key, value := strings.TrimSpace(opt), ""
+		if i := strings.IndexByte(key, '='); i >= 0 {
+			key, value = strings.TrimSpace(key[:i]), strings.TrimSpace(key[i+1:])
+		}
+		if key == name {
+			values = append(values, value)
+		}
+	}
+	return values
+}
@@ -0,0 +1,43 @@
+package toml
+
+import (
+	"math/big"
+	"reflect"
+
+	"github.com/naoina/toml/ast"
+)
+
+// setWeak applies the conversions of Config.WeaklyTypedInput. It reports false for
+// values which decode without conversion.
+func setWeak(cfg *Config, rv reflect.Value, val ast.Value, path *keyPath) (bool, error) {
+	switch k := rv.Kind(); {
+	case k == reflect.Slice:
+		if _, ok := val.(*ast.Array); ok {
+			return false, nil
+		}
+		arr := &ast.Array{Position: ast.Position{Begin: val.Pos(), End: val.End()}, Value: []ast.Value{val}}
+		return true, setArray(cfg, rv, arr, path)
+	case k == reflect.Float32 || k == reflect.Float64:
+		if v, ok := val.(*ast.Integer); ok {
+			return true, setIntFloat(rv, v)
+		}
+	}
+	if v, ok := val.(*ast.String); ok {
+		return setQuoted(rv, v)
+	}
+	return false, nil
+}
+
+// setIntFloat stores integer v in float fv.
+func setIntFloat(fv reflect.Value, v *ast.Integer) error {
+	f, _, err := big.ParseFloat(v.Value, 0, 53, big.ToNearestEven)
+	if err != nil {
+		return err
+	}
+	f64, _ := f.Float64()
+	if fv.OverflowFloat(f64) {
+		return &OverflowError{fv.Kind(), v.Value}
+	}
+	fv.SetFloat(f64)
+	return nil
+}